@@ -8,6 +8,7 @@ import (
 	_ "github.com/bigstack-oss/plane-go/examples/oneway/plugin/output"
 	_ "github.com/bigstack-oss/plane-go/examples/oneway/plugin/process"
 	_ "github.com/bigstack-oss/plane-go/examples/oneway/plugin/transit"
+	_ "github.com/bigstack-oss/plane-go/pkg/frame/oneway/plugin/process/router"
 )
 
 func main() {
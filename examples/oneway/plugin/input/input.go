@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/bigstack-oss/plane-go/pkg/base/log"
+	"github.com/bigstack-oss/plane-go/pkg/frame/oneway/plugin"
 	"github.com/bigstack-oss/plane-go/pkg/frame/oneway/plugin/input"
 	"github.com/goinggo/mapstructure"
 	"go.uber.org/zap"
@@ -30,8 +31,8 @@ type DummyInputter struct {
 
 type config struct {
 	Name          string `validate:"required"`
-	Group         string `validate:"required"`
-	FetchInterval int    `validate:"required"`
+	plugin.Part   `mapstructure:",squash"`
+	FetchInterval int `validate:"required"`
 }
 
 func init() {
@@ -43,7 +44,7 @@ func (d *DummyInputter) SetConfig(conf interface{}) {
 
 	d.wg = &sync.WaitGroup{}
 	d.ctx, d.cancel = context.WithCancel(context.Background())
-	d.input = input.WrapWithSingleMsgLoop(d.ctx, d.wg, d.Group, d.coreFunc, time.Duration(d.FetchInterval))
+	d.input = input.WrapWithSingleMsgLoop(d.ctx, d.wg, d.Part, d.coreFunc, time.Duration(d.FetchInterval))
 
 	d.log = log.GetLogger(module)
 	d.logf = d.log.Sugar()
@@ -6,6 +6,7 @@ import (
 
 	"github.com/bigstack-oss/plane-go/pkg/base/log"
 	"github.com/bigstack-oss/plane-go/pkg/base/protocol"
+	"github.com/bigstack-oss/plane-go/pkg/frame/oneway/plugin"
 	"github.com/bigstack-oss/plane-go/pkg/frame/oneway/plugin/output"
 	"github.com/goinggo/mapstructure"
 	"go.uber.org/zap"
@@ -29,8 +30,8 @@ type DummyOutputer struct {
 }
 
 type config struct {
-	Name  string `validate:"required"`
-	Group string `validate:"required"`
+	Name        string `validate:"required"`
+	plugin.Part `mapstructure:",squash"`
 }
 
 func init() {
@@ -42,7 +43,7 @@ func (d *DummyOutputer) SetConfig(conf interface{}) {
 
 	d.wg = &sync.WaitGroup{}
 	d.ctx, d.cancel = context.WithCancel(context.Background())
-	d.output = output.WrapWithSingleMsgLoop(d.ctx, d.wg, d.Group, d.coreFunc)
+	d.output = output.WrapWithSingleMsgLoop(d.ctx, d.wg, d.Part, d.coreFunc)
 
 	d.log = log.GetLogger(module)
 	d.logf = d.log.Sugar()
@@ -7,6 +7,7 @@ import (
 	"github.com/bigstack-oss/plane-go/pkg/base/log"
 	"github.com/bigstack-oss/plane-go/pkg/base/protocol"
 	"github.com/bigstack-oss/plane-go/pkg/frame/oneway/metric"
+	"github.com/bigstack-oss/plane-go/pkg/frame/oneway/plugin"
 	"github.com/bigstack-oss/plane-go/pkg/frame/oneway/plugin/process"
 	"github.com/goinggo/mapstructure"
 	"github.com/prometheus/client_golang/prometheus"
@@ -44,9 +45,9 @@ type DummyProcessor struct {
 }
 
 type config struct {
-	Name  string `validate:"required"`
-	Group string `validate:"required"`
-	ID    string
+	Name        string `validate:"required"`
+	plugin.Part `mapstructure:",squash"`
+	ID          string
 }
 
 func init() {
@@ -59,7 +60,7 @@ func (d *DummyProcessor) SetConfig(conf interface{}) {
 
 	d.wg = &sync.WaitGroup{}
 	d.ctx, d.cancel = context.WithCancel(context.Background())
-	d.process = process.WrapWithSingleMsgLoop(d.ctx, d.wg, d.Group, d.coreFunc)
+	d.process = process.WrapWithSingleMsgLoop(d.ctx, d.wg, d.Part, d.coreFunc)
 
 	d.log = log.GetLogger(module)
 	d.logf = d.log.Sugar()
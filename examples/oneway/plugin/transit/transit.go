@@ -7,6 +7,7 @@ import (
 
 	"github.com/bigstack-oss/plane-go/pkg/base/log"
 	"github.com/bigstack-oss/plane-go/pkg/base/protocol"
+	"github.com/bigstack-oss/plane-go/pkg/frame/oneway/plugin"
 	"github.com/bigstack-oss/plane-go/pkg/frame/oneway/plugin/transit"
 	"github.com/goinggo/mapstructure"
 	"go.uber.org/zap"
@@ -30,8 +31,8 @@ type DummyTransitter struct {
 }
 
 type config struct {
-	Name  string `validate:"required"`
-	Group string `validate:"required"`
+	Name        string `validate:"required"`
+	plugin.Part `mapstructure:",squash"`
 }
 
 func init() {
@@ -43,7 +44,7 @@ func (d *DummyTransitter) SetConfig(conf interface{}) {
 
 	d.wg = &sync.WaitGroup{}
 	d.ctx, d.cancel = context.WithCancel(context.Background())
-	d.transit = transit.WrapWithSingleMsgLoop(d.ctx, d.wg, d.Group, d.coreFunc)
+	d.transit = transit.WrapWithSingleMsgLoop(d.ctx, d.wg, d.Part, d.coreFunc)
 
 	d.log = log.GetLogger(module)
 	d.logf = d.log.Sugar()
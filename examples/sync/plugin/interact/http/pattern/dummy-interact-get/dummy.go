@@ -1,13 +1,12 @@
 package dummy
 
 import (
-	"errors"
 	"net/http"
 
 	"github.com/bigstack-oss/plane-go/pkg/base/log"
 	"github.com/bigstack-oss/plane-go/pkg/base/protocol"
 	"github.com/bigstack-oss/plane-go/pkg/frame/sync/plugin/interact/http/interfacehttp"
-	"github.com/bigstack-oss/plane-go/pkg/frame/sync/plugin/plug"
+	"github.com/bigstack-oss/plane-go/pkg/frame/sync/plugin/interact/http/openapi"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 )
@@ -17,12 +16,10 @@ const (
 	response = "dummy get response"
 )
 
-var (
-	get = "GET"
-)
-
+// DummyHandler runs the stages by the executor, and replaces the rendering
+// with its own response
 type DummyHandler struct {
-	stages []plug.Stager
+	interfacehttp.Executor
 
 	log  *zap.Logger
 	logf *zap.SugaredLogger
@@ -35,28 +32,20 @@ func init() {
 func (m *DummyHandler) SetConfig() {
 	m.log = log.GetLogger(module)
 	m.logf = m.log.Sugar()
+	m.Render = m.render
 }
 
-func (m *DummyHandler) RegisterRouter(router *gin.Engine, method string, path string) error {
-	switch method {
-	case get:
-		router.GET(path, m.get)
-	default:
-		m.logf.Errorf("unsupported method(%s) detected in pattern(%s)", method, path)
-		return errors.New("register unsupported REST method")
+func (m *DummyHandler) render(g *gin.Context, job *protocol.Job, err error) {
+	if err != nil {
+		m.logf.Errorf("failed to execute stages. error: %s", err.Error())
+		interfacehttp.RenderJSON(g, job, err)
+		return
 	}
 
-	return nil
-}
-
-func (m *DummyHandler) AppendStage(stage string) {
-	m.stages = append(m.stages, plug.Stagers[stage])
+	g.JSON(http.StatusOK, response)
 }
 
-func (m *DummyHandler) get(g *gin.Context) {
-	for _, stage := range m.stages {
-		stage.Execute(&protocol.Job{})
-	}
-
-	g.JSON(http.StatusOK, response)
+// Schema describes the fixed response of the handler
+func (m *DummyHandler) Schema() openapi.Schema {
+	return openapi.Schema{Summary: "get the dummy response", Response: openapi.Raw{"type": "string"}}
 }
@@ -1,57 +1,47 @@
 package dummy
 
 import (
-	"errors"
-	"net/http"
-
 	"github.com/bigstack-oss/plane-go/pkg/base/log"
 	"github.com/bigstack-oss/plane-go/pkg/frame/sync/plugin/interact/http/interfacehttp"
-	"github.com/bigstack-oss/plane-go/pkg/frame/sync/plugin/plug"
-	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 )
 
 const (
-	module   = "dummy-interact-post"
-	response = "dummy post response"
-)
-
-var (
-	post = "POST"
+	module = "dummy-interact-post"
 )
 
+// DummyHandler binds the request into a job, runs the stages and responds
+// with the result of the job by the executor
 type DummyHandler struct {
-	stages []plug.Stager
+	interfacehttp.Executor
 
 	log  *zap.Logger
 	logf *zap.SugaredLogger
 }
 
-func init() {
-	interfacehttp.Plugins[module] = &DummyHandler{}
+// DummyRequest is declared as the request of the interface in config, the
+// invalid bodies are rejected before the stages
+type DummyRequest struct {
+	ID      string        `json:"id" validate:"omitempty,max=64"`
+	Desired *DummyDesired `json:"desired" validate:"required"`
 }
 
-func (m *DummyHandler) SetConfig() {
-	m.log = log.GetLogger(module)
-	m.logf = m.log.Sugar()
+type DummyDesired struct {
+	Operation string         `json:"operation" validate:"oneof=create update delete"`
+	Resource  *DummyResource `json:"resource" validate:"required"`
 }
 
-func (m *DummyHandler) RegisterRouter(router *gin.Engine, method string, path string) error {
-	switch method {
-	case post:
-		router.POST(path, m.post)
-	default:
-		m.logf.Errorf("unsupported method(%s) detected in pattern(%s)", method, path)
-		return errors.New("register unsupported REST methog")
-	}
-
-	return nil
+type DummyResource struct {
+	Type string `json:"type" validate:"required"`
+	Name string `json:"name" validate:"required"`
 }
 
-func (m *DummyHandler) AppendStage(stage string) {
-	m.stages = append(m.stages, plug.Stagers[stage])
+func init() {
+	interfacehttp.Plugins[module] = &DummyHandler{}
+	interfacehttp.Requests[module] = DummyRequest{}
 }
 
-func (m *DummyHandler) post(g *gin.Context) {
-	g.JSON(http.StatusOK, response)
+func (m *DummyHandler) SetConfig() {
+	m.log = log.GetLogger(module)
+	m.logf = m.log.Sugar()
 }
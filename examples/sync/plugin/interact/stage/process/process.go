@@ -1,6 +1,8 @@
 package process
 
 import (
+	"context"
+
 	"github.com/bigstack-oss/plane-go/pkg/base/log"
 	"github.com/bigstack-oss/plane-go/pkg/base/protocol"
 	"github.com/bigstack-oss/plane-go/pkg/frame/sync/plugin/interact/stage"
@@ -47,3 +49,9 @@ func (d *DummyProcessor) Execute(task *protocol.Job) (bool, error) {
 	d.logf.Info(d.Name)
 	return true, nil
 }
+
+// Compensate undoes the process when a later stage failed
+func (d *DummyProcessor) Compensate(ctx context.Context, task *protocol.Job) error {
+	d.logf.Infof("compensate %s", d.Name)
+	return nil
+}
@@ -40,7 +40,9 @@ func (d *DummyRequester) CheckConfig() error {
 	return validate.Struct(d.config)
 }
 
+// Execute logs the request decoded from the body, it is nil if the
+// interface declares no request
 func (d *DummyRequester) Execute(task *protocol.Job) (bool, error) {
-	d.logf.Info(d.Name)
+	d.logf.Infof("%s: %+v", d.Name, task.GetRequest())
 	return true, nil
 }
@@ -10,6 +10,7 @@ import (
 	_ "github.com/bigstack-oss/plane-go/examples/sync/plugin/interact/stage/request"
 	_ "github.com/bigstack-oss/plane-go/examples/sync/plugin/interact/stage/transit"
 
+	_ "github.com/bigstack-oss/plane-go/pkg/frame/sync/plugin/interact/grpc"
 	_ "github.com/bigstack-oss/plane-go/pkg/frame/sync/plugin/interact/http"
 )
 
@@ -18,6 +18,12 @@ func GetConfiger() Configer {
 	return instance
 }
 
+// NewConfiger returns a configer apart from the shared one, which is used to
+// check a new conf before it replaces the running one
+func NewConfiger() Configer {
+	return viper.New()
+}
+
 type Configer interface {
 	SetConfigType(string)
 	ReadConfig(io.Reader) error
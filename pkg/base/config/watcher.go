@@ -0,0 +1,87 @@
+package config
+
+import (
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+const (
+	debounce = 500 * time.Millisecond
+)
+
+// Watcher calls onChange once the conf file is written, created or replaced.
+// the directory of the file is watched, since editors and configmaps replace
+// the file instead of writing it in place
+type Watcher struct {
+	watcher *fsnotify.Watcher
+	done    chan struct{}
+}
+
+func isConfEvent(event fsnotify.Event, conf string) bool {
+	if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) == 0 {
+		return false
+	}
+
+	// configmaps swap the `..data` symlink of the directory
+	name := filepath.Clean(event.Name)
+	return name == conf || filepath.Base(name) == "..data"
+}
+
+func WatchFile(path string, onChange func()) (*Watcher, error) {
+	conf, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	err = watcher.Add(filepath.Dir(conf))
+	if err != nil {
+		watcher.Close()
+		return nil, err
+	}
+
+	w := &Watcher{watcher: watcher, done: make(chan struct{})}
+	go w.watch(conf, onChange)
+	return w, nil
+}
+
+func (w *Watcher) watch(conf string, onChange func()) {
+	var timer *time.Timer
+	for {
+		select {
+		case <-w.done:
+			if timer != nil {
+				timer.Stop()
+			}
+			return
+		case event, isChnOpen := <-w.watcher.Events:
+			if !isChnOpen {
+				return
+			}
+			if !isConfEvent(event, conf) {
+				continue
+			}
+
+			// a save usually comes with several events
+			if timer != nil {
+				timer.Stop()
+			}
+			timer = time.AfterFunc(debounce, onChange)
+		case _, isChnOpen := <-w.watcher.Errors:
+			if !isChnOpen {
+				return
+			}
+		}
+	}
+}
+
+func (w *Watcher) Close() error {
+	close(w.done)
+	return w.watcher.Close()
+}
@@ -1,6 +1,8 @@
 package protocol
 
 import (
+	"time"
+
 	json "github.com/json-iterator/go"
 )
 
@@ -10,16 +12,23 @@ type Job struct {
 	*Applicant `json:"applicant"`
 	*Desired   `json:"desired"`
 	*Result    `json:"result"`
+
+	// when the job was put into its current hop, it is not serialized
+	enqueuedAt time.Time
+
+	// the request decoded from the body of the interface, it is not serialized
+	request interface{}
 }
 
 type Applicant struct {
-	ID       string `json:"id"`
-	Name     string `json:"name"`
-	Project  string `json:"project"`
-	Email    string `json:"email,omitempty"`
-	Country  string `json:"country,omitempty"`
-	Company  string `json:"company,omitempty"`
-	Industry string `json:"industry,omitempty"`
+	ID       string   `json:"id"`
+	Name     string   `json:"name"`
+	Project  string   `json:"project"`
+	Email    string   `json:"email,omitempty"`
+	Country  string   `json:"country,omitempty"`
+	Company  string   `json:"company,omitempty"`
+	Industry string   `json:"industry,omitempty"`
+	Roles    []string `json:"roles,omitempty"`
 }
 
 type Desired struct {
@@ -47,6 +56,26 @@ func (j *Job) Reset() {
 	j.Result = nil
 }
 
+// SetEnqueuedAt records when the job was put into a hop
+func (j *Job) SetEnqueuedAt(enqueuedAt time.Time) {
+	j.enqueuedAt = enqueuedAt
+}
+
+func (j *Job) GetEnqueuedAt() time.Time {
+	return j.enqueuedAt
+}
+
+// SetRequest keeps the request decoded from the body along with the job
+func (j *Job) SetRequest(request interface{}) {
+	j.request = request
+}
+
+// GetRequest returns the request decoded from the body, it is nil if the
+// interface declares no request
+func (j *Job) GetRequest() interface{} {
+	return j.request
+}
+
 func (j *Job) String() string {
 	b, err := json.Marshal(j)
 	if err != nil {
@@ -0,0 +1,425 @@
+package queue
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	segmentSuffix = ".log"
+	ackFile       = "ack"
+	headerSize    = 8
+
+	DefaultSegmentSize = 64 * 1024 * 1024
+)
+
+var (
+	ErrClosed = errors.New("queue was closed for writing")
+)
+
+type segment struct {
+	base  int64
+	count int64
+	size  int64
+	path  string
+}
+
+// Log is an append-only queue persisted as segment files in a directory.
+// every record gets an offset, and the offset of the first record which has
+// not been acknowledged is persisted, so the records are delivered again
+// after restart unless they were acknowledged
+type Log struct {
+	mutex  sync.Mutex
+	notify chan struct{}
+
+	dir         string
+	segmentSize int64
+	isSync      bool
+
+	segments []*segment
+	writer   *os.File
+
+	reader       *os.File
+	readerBase   int64
+	readerCursor int64
+
+	nextOffset int64
+	ackOffset  int64
+	acked      map[int64]bool
+
+	isWriteClosed bool
+}
+
+func segmentPath(dir string, base int64) string {
+	return filepath.Join(dir, fmt.Sprintf("%020d%s", base, segmentSuffix))
+}
+
+// Open loads the log in the directory, and truncates the record which was
+// partially written before the last crash
+func Open(dir string, segmentSize int64, isSync bool) (*Log, error) {
+	if segmentSize <= 0 {
+		segmentSize = DefaultSegmentSize
+	}
+
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &Log{
+		notify:      make(chan struct{}),
+		dir:         dir,
+		segmentSize: segmentSize,
+		isSync:      isSync,
+		acked:       make(map[int64]bool),
+	}
+
+	err = l.loadAckOffset()
+	if err != nil {
+		return nil, err
+	}
+
+	err = l.loadSegments()
+	if err != nil {
+		return nil, err
+	}
+
+	l.readerCursor = l.ackOffset
+	l.removeAckedSegments()
+	return l, l.openWriter()
+}
+
+func (l *Log) loadAckOffset() error {
+	content, err := os.ReadFile(filepath.Join(l.dir, ackFile))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	l.ackOffset, err = strconv.ParseInt(strings.TrimSpace(string(content)), 10, 64)
+	return err
+}
+
+func (l *Log) loadSegments() error {
+	paths, err := filepath.Glob(filepath.Join(l.dir, "*"+segmentSuffix))
+	if err != nil {
+		return err
+	}
+
+	sort.Strings(paths)
+	for _, path := range paths {
+		base, err := strconv.ParseInt(strings.TrimSuffix(filepath.Base(path), segmentSuffix), 10, 64)
+		if err != nil {
+			continue
+		}
+
+		s := &segment{base: base, path: path}
+		err = s.scan()
+		if err != nil {
+			return err
+		}
+
+		l.segments = append(l.segments, s)
+	}
+
+	if len(l.segments) == 0 {
+		l.segments = append(l.segments, &segment{base: l.ackOffset, path: segmentPath(l.dir, l.ackOffset)})
+	}
+
+	last := l.segments[len(l.segments)-1]
+	l.nextOffset = last.base + last.count
+	if l.ackOffset > l.nextOffset {
+		l.ackOffset = l.nextOffset
+	}
+
+	return nil
+}
+
+// scan counts the complete records of the segment and drops the broken tail
+func (s *segment) scan() error {
+	file, err := os.OpenFile(s.path, os.O_RDWR, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	for {
+		_, err := readRecord(file)
+		if err != nil {
+			break
+		}
+
+		s.count++
+	}
+
+	s.size, err = file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+
+	return file.Truncate(s.size)
+}
+
+func readRecord(reader io.ReadSeeker) ([]byte, error) {
+	header := make([]byte, headerSize)
+	_, err := io.ReadFull(reader, header)
+	if err != nil {
+		return nil, err
+	}
+
+	payload := make([]byte, binary.BigEndian.Uint32(header[:4]))
+	_, err = io.ReadFull(reader, payload)
+	if err == nil && crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(header[4:]) {
+		err = errors.New("checksum mismatched")
+	}
+	if err != nil {
+		_, _ = reader.Seek(-int64(headerSize+len(payload)), io.SeekCurrent)
+		return nil, err
+	}
+
+	return payload, nil
+}
+
+func (l *Log) openWriter() error {
+	last := l.segments[len(l.segments)-1]
+	writer, err := os.OpenFile(last.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	l.writer = writer
+	return nil
+}
+
+func (l *Log) roll() error {
+	err := l.writer.Close()
+	if err != nil {
+		return err
+	}
+
+	l.segments = append(l.segments, &segment{base: l.nextOffset, path: segmentPath(l.dir, l.nextOffset)})
+	return l.openWriter()
+}
+
+// Append persists the payload and returns its offset
+func (l *Log) Append(payload []byte) (int64, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.isWriteClosed {
+		return 0, ErrClosed
+	}
+
+	last := l.segments[len(l.segments)-1]
+	if last.size >= l.segmentSize && last.count > 0 {
+		err := l.roll()
+		if err != nil {
+			return 0, err
+		}
+
+		last = l.segments[len(l.segments)-1]
+	}
+
+	record := make([]byte, headerSize+len(payload))
+	binary.BigEndian.PutUint32(record[:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(record[4:headerSize], crc32.ChecksumIEEE(payload))
+	copy(record[headerSize:], payload)
+
+	_, err := l.writer.Write(record)
+	if err != nil {
+		return 0, err
+	}
+	if l.isSync {
+		err = l.writer.Sync()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	offset := l.nextOffset
+	last.count++
+	last.size += int64(len(record))
+	l.nextOffset++
+
+	close(l.notify)
+	l.notify = make(chan struct{})
+	return offset, nil
+}
+
+func (l *Log) findSegment(offset int64) *segment {
+	for _, s := range l.segments {
+		if offset >= s.base && offset < s.base+s.count {
+			return s
+		}
+	}
+
+	return nil
+}
+
+func (l *Log) read() ([]byte, error) {
+	s := l.findSegment(l.readerCursor)
+	if s == nil {
+		return nil, fmt.Errorf("offset %d was not found in queue %s", l.readerCursor, l.dir)
+	}
+
+	if l.reader == nil || l.readerBase != s.base {
+		if l.reader != nil {
+			l.reader.Close()
+		}
+
+		reader, err := os.Open(s.path)
+		if err != nil {
+			return nil, err
+		}
+
+		l.reader, l.readerBase = reader, s.base
+		for i := s.base; i < l.readerCursor; i++ {
+			_, err = readRecord(l.reader)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return readRecord(l.reader)
+}
+
+// Next blocks until the next record is available, it returns false when the
+// context is done, or the log is closed for writing and all records are read
+func (l *Log) Next(ctx context.Context) (int64, []byte, bool) {
+	for {
+		l.mutex.Lock()
+		if l.readerCursor < l.nextOffset {
+			payload, err := l.read()
+			if err != nil {
+				l.mutex.Unlock()
+				return 0, nil, false
+			}
+
+			offset := l.readerCursor
+			l.readerCursor++
+			l.mutex.Unlock()
+			return offset, payload, true
+		}
+
+		if l.isWriteClosed {
+			l.mutex.Unlock()
+			return 0, nil, false
+		}
+
+		notify := l.notify
+		l.mutex.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return 0, nil, false
+		case <-notify:
+		}
+	}
+}
+
+// Ack marks the record as done. the persisted ack offset only moves forward
+// when all the records before it were acknowledged
+func (l *Log) Ack(offset int64) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if offset < l.ackOffset {
+		return nil
+	}
+
+	l.acked[offset] = true
+	if offset != l.ackOffset {
+		return nil
+	}
+
+	for l.acked[l.ackOffset] {
+		delete(l.acked, l.ackOffset)
+		l.ackOffset++
+	}
+
+	err := l.saveAckOffset()
+	if err != nil {
+		return err
+	}
+
+	l.removeAckedSegments()
+	return nil
+}
+
+func (l *Log) saveAckOffset() error {
+	path := filepath.Join(l.dir, ackFile)
+	err := os.WriteFile(path+".tmp", []byte(strconv.FormatInt(l.ackOffset, 10)), 0644)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(path+".tmp", path)
+}
+
+func (l *Log) removeAckedSegments() {
+	for len(l.segments) > 1 && l.segments[0].base+l.segments[0].count <= l.ackOffset {
+		_ = os.Remove(l.segments[0].path)
+		l.segments = l.segments[1:]
+	}
+}
+
+// CloseWrite rejects the following appends, and Next returns false once the
+// remaining records are read
+func (l *Log) CloseWrite() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.isWriteClosed {
+		return
+	}
+
+	l.isWriteClosed = true
+	close(l.notify)
+	l.notify = make(chan struct{})
+}
+
+func (l *Log) Close() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.reader != nil {
+		l.reader.Close()
+		l.reader = nil
+	}
+
+	return l.writer.Close()
+}
+
+// Depth returns the number of records which have not been acknowledged
+func (l *Log) Depth() int64 {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return l.nextOffset - l.ackOffset
+}
+
+// Size returns the bytes of the segment files on disk
+func (l *Log) Size() int64 {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	var size int64
+	for _, s := range l.segments {
+		size += s.size
+	}
+
+	return size
+}
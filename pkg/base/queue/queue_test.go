@@ -0,0 +1,110 @@
+package queue
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func appendRecords(t *testing.T, l *Log, from int, to int) {
+	for i := from; i < to; i++ {
+		_, err := l.Append([]byte(strconv.Itoa(i)))
+		assert.Equal(t, nil, err, "failed to append record")
+	}
+}
+
+func TestResumeFromUnackedRecord(t *testing.T) {
+	dir := t.TempDir()
+	l, err := Open(dir, 0, false)
+	assert.Equal(t, nil, err, "failed to open queue")
+	appendRecords(t, l, 0, 3)
+
+	offset, payload, isOpen := l.Next(context.Background())
+	assert.Equal(t, true, isOpen, "failed to read record")
+	assert.Equal(t, "0", string(payload), "failed to read the first record")
+	assert.Equal(t, nil, l.Ack(offset), "failed to ack record")
+
+	// the second record is read but not acknowledged before the crash
+	_, payload, _ = l.Next(context.Background())
+	assert.Equal(t, "1", string(payload), "failed to read the second record")
+	assert.Equal(t, int64(2), l.Depth(), "failed to count unacked records")
+	assert.Equal(t, nil, l.Close(), "failed to close queue")
+
+	l, err = Open(dir, 0, false)
+	assert.Equal(t, nil, err, "failed to reopen queue")
+	_, payload, _ = l.Next(context.Background())
+	assert.Equal(t, "1", string(payload), "failed to resume from the unacked record")
+	_, payload, _ = l.Next(context.Background())
+	assert.Equal(t, "2", string(payload), "failed to read the record after the unacked one")
+	_ = l.Close()
+}
+
+func TestAckOutOfOrder(t *testing.T) {
+	l, _ := Open(t.TempDir(), 0, false)
+	appendRecords(t, l, 0, 3)
+
+	_ = l.Ack(2)
+	_ = l.Ack(1)
+	assert.Equal(t, int64(3), l.Depth(), "failed to hold the ack offset for the missing ack")
+
+	_ = l.Ack(0)
+	assert.Equal(t, int64(0), l.Depth(), "failed to move the ack offset over the acked records")
+	_ = l.Close()
+}
+
+func TestRollAndRemoveSegments(t *testing.T) {
+	dir := t.TempDir()
+	l, _ := Open(dir, 1, false)
+	appendRecords(t, l, 0, 3)
+
+	segments, _ := filepath.Glob(filepath.Join(dir, "*"+segmentSuffix))
+	assert.Equal(t, 3, len(segments), "failed to roll segments")
+
+	for i := 0; i < 3; i++ {
+		offset, _, _ := l.Next(context.Background())
+		_ = l.Ack(offset)
+	}
+
+	segments, _ = filepath.Glob(filepath.Join(dir, "*"+segmentSuffix))
+	assert.Equal(t, 1, len(segments), "failed to remove acked segments")
+	assert.Equal(t, int64(headerSize+1), l.Size(), "failed to count the size of the active segment")
+	_ = l.Close()
+}
+
+func TestTruncatePartialRecord(t *testing.T) {
+	dir := t.TempDir()
+	l, _ := Open(dir, 0, false)
+	appendRecords(t, l, 0, 2)
+	_ = l.Close()
+
+	file, _ := os.OpenFile(segmentPath(dir, 0), os.O_APPEND|os.O_WRONLY, 0644)
+	_, _ = file.Write([]byte{0, 0, 0, 9, 1})
+	_ = file.Close()
+
+	l, err := Open(dir, 0, false)
+	assert.Equal(t, nil, err, "failed to open queue with partial record")
+	assert.Equal(t, int64(2), l.Depth(), "failed to drop partial record")
+
+	offset, _ := l.Append([]byte("2"))
+	assert.Equal(t, int64(2), offset, "failed to append after the truncated record")
+	_ = l.Close()
+}
+
+func TestDrainAfterCloseWrite(t *testing.T) {
+	l, _ := Open(t.TempDir(), 0, false)
+	appendRecords(t, l, 0, 1)
+	l.CloseWrite()
+
+	_, err := l.Append([]byte("1"))
+	assert.Equal(t, ErrClosed, err, "failed to reject append after close")
+
+	_, _, isOpen := l.Next(context.Background())
+	assert.Equal(t, true, isOpen, "failed to read the remaining record")
+	_, _, isOpen = l.Next(context.Background())
+	assert.Equal(t, false, isOpen, "failed to stop after the remaining records")
+	_ = l.Close()
+}
@@ -29,8 +29,10 @@ import (
 const (
 	module   = "controller"
 	runMode  = "oneTimeExec"
-	chanSize = "channelSize"
+	drainTTL = "drainTimeout"
 	yamlConf = "yaml"
+
+	defaultDrainTimeout = 30 * time.Second
 )
 
 var (
@@ -39,8 +41,9 @@ var (
 	wg   sync.WaitGroup = sync.WaitGroup{}
 	once sync.Once
 
-	configer = config.GetConfiger()
-	conf     string
+	configer  = config.GetConfiger()
+	conf      string
+	watchConf bool
 
 	logLevel int
 
@@ -56,7 +59,9 @@ type controller struct {
 
 	isWorkerCompleted bool
 	isOneTimeExec     bool
+	drainTimeout      time.Duration
 	signalChan        chan os.Signal
+	watcher           *config.Watcher
 
 	log  *zap.Logger
 	logf *zap.SugaredLogger
@@ -66,6 +71,7 @@ type controller struct {
 
 func init() {
 	flag.StringVar(&conf, "conf", "", "")
+	flag.BoolVar(&watchConf, "watch-conf", false, "")
 	flag.IntVar(&logLevel, "log-level", 2, "")
 	flag.Parse()
 
@@ -82,39 +88,54 @@ func GetInstance() *controller {
 	return instance
 }
 
-func (c *controller) loadConfig(conf string) error {
+func readConfigFile(conf string) ([]byte, error) {
 	if conf == "" {
-		return errors.New("conf file is required, please specify the path of conf file")
+		return nil, errors.New("conf file is required, please specify the path of conf file")
 	}
 
-	content, err := ioutil.ReadFile(conf)
+	return ioutil.ReadFile(conf)
+}
+
+func parseConfig(cfg config.Configer, content []byte) error {
+	cfg.SetConfigType(yamlConf)
+	return cfg.ReadConfig(bytes.NewBuffer(content))
+}
+
+func (c *controller) loadConfig(conf string) error {
+	content, err := readConfigFile(conf)
 	if err != nil {
 		return err
 	}
 
-	configer.SetConfigType(yamlConf)
-	err = configer.ReadConfig(bytes.NewBuffer(content))
-	if err != nil {
-		return err
+	return parseConfig(configer, content)
+}
+
+func getDrainTimeout(cfg config.Configer) time.Duration {
+	if cfg.Get(drainTTL) == nil {
+		return defaultDrainTimeout
 	}
 
-	return nil
+	return time.Duration(cfg.GetInt32(drainTTL)) * time.Second
 }
 
 func (c *controller) initControllerParams() {
 	c.wg = &wg
 	c.ctx, c.cancel = context.WithCancel(context.Background())
 
-	c.signalChan = make(chan os.Signal, 1)
+	// the signals are still trapped on the channel after a restart
+	if c.signalChan == nil {
+		c.signalChan = make(chan os.Signal, 1)
+	}
+
 	c.isOneTimeExec = configer.Get(runMode).(bool)
 	c.isWorkerCompleted = false
+	c.drainTimeout = getDrainTimeout(configer)
 }
 
 func (c *controller) initPluginParams() {
 	plugin.Service = strings.TrimSuffix(conf, filepath.Ext(conf))
 
 	plugin.IsOneTimeExec = c.isOneTimeExec
-	plugin.ChanSize = configer.GetInt32(chanSize)
 
 	plugin.Metrics = &plugin.Metric{}
 	plugin.Records = &plugin.Record{}
@@ -139,6 +160,7 @@ func (c *controller) ActivateService() {
 	c.Worker.SetParter(plugin.Transit)
 	c.Worker.SetParter(plugin.Process)
 	c.Worker.SetParter(plugin.Output)
+	c.Worker.SetDeadLetter(plugin.DeadLetter)
 	c.Worker.SetCronner(plugin.CronJob)
 }
 
@@ -151,26 +173,80 @@ func (c *controller) Start() {
 
 func (c *controller) Stop() {
 	c.Worker.StopParters()
+	c.Worker.StopDeadLetters()
 	c.Worker.StopCronners()
 	c.wg.Done()
 	c.log.Info("worker has been stopped")
 }
 
-func (c *controller) Restart() {
-	c.log.Info("restarting worker")
-	defer c.wg.Done()
-	c.wg.Add(1)
+// Drain stops the inputs and waits for the rest of the pipeline to consume
+// the messages in flight before stopping the worker, the worker is stopped
+// right away if the drain timeout is set to 0
+func (c *controller) Drain() {
+	if c.drainTimeout <= 0 {
+		c.Stop()
+		return
+	}
 
-	c.Stop()
+	c.log.Info("draining worker")
+	c.Worker.DrainParters(c.drainTimeout)
+	c.Worker.StopDeadLetters()
+	c.Worker.StopCronners()
+	c.wg.Done()
+	c.log.Info("worker has been drained and stopped")
+}
+
+// Reload applies the changes of the conf file to the running worker, only
+// the plugins whose config changed are restarted after they are drained. the
+// worker keeps running with the previous conf if the new one is invalid. the
+// conf file is read once, so that the conf applied is the one checked
+func (c *controller) Reload() error {
+	content, err := readConfigFile(conf)
+	if err != nil {
+		return err
+	}
 
-	c = GetInstance()
-	c.InitService()
-	c.ActivateService()
-	c.Start()
-	c.TrapSignals()
+	cfg := config.NewConfiger()
+	err = parseConfig(cfg, content)
+	if err != nil {
+		return err
+	}
+
+	drainTimeout := getDrainTimeout(cfg)
+	err = c.Worker.Reload(cfg, drainTimeout)
+	if err != nil {
+		return err
+	}
+
+	err = parseConfig(configer, content)
+	if err != nil {
+		return err
+	}
+
+	c.drainTimeout = drainTimeout
+	return nil
+}
+
+// watchConfig reloads the service once the conf file is changed, the reload
+// is passed through the signal channel so that it never runs concurrently
+func (c *controller) watchConfig() {
+	if !watchConf || c.watcher != nil {
+		return
+	}
+
+	watcher, err := config.WatchFile(conf, func() { c.signalChan <- syscall.SIGHUP })
+	if err != nil {
+		c.logf.Errorf("failed to watch conf file '%s'. error details: %s", conf, err.Error())
+		return
+	}
+
+	c.watcher = watcher
+	c.logf.Infof("watching conf file '%s'", conf)
 }
 
 func (c *controller) TrapSignals() {
+	c.watchConfig()
+
 	go func() {
 		signal.Notify(c.signalChan, syscall.SIGHUP, syscall.SIGTERM)
 		c.log.Info("signal registered: SIGTERM, SIGUSR1")
@@ -178,11 +254,17 @@ func (c *controller) TrapSignals() {
 		for sig := range c.signalChan {
 			switch sig {
 			case syscall.SIGHUP:
-				c.log.Info("Signal SIGHUP received, restarting service...")
-				c.Restart()
+				c.log.Info("Signal SIGHUP received, reloading service...")
+				err := c.Reload()
+				if err != nil {
+					c.logf.Errorf("failed to reload conf from '%s', keep running with the previous conf. error details: %s", conf, err.Error())
+					continue
+				}
+
+				c.log.Info("service has been reloaded")
 			case syscall.SIGTERM:
-				c.log.Info("Signal SIGTERM received, stopping service ...")
-				c.Stop()
+				c.log.Info("Signal SIGTERM received, draining service ...")
+				c.Drain()
 			}
 		}
 	}()
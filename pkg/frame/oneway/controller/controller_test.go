@@ -1,16 +1,16 @@
 package controller
 
 import (
-	"context"
 	"errors"
 	"io"
 	"os"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"testing"
 	"time"
 
 	"github.com/bigstack-oss/plane-go/pkg/base/config"
-	"github.com/bigstack-oss/plane-go/pkg/base/log"
 	"github.com/bigstack-oss/plane-go/pkg/base/monitoring"
 	"github.com/bigstack-oss/plane-go/pkg/frame/oneway/plugin"
 	"github.com/prometheus/client_golang/prometheus"
@@ -23,32 +23,71 @@ const (
 	process = "testProcess"
 	output  = "testOutput"
 	cronJob = "cronJob"
+	letter  = "deadLetter"
 
-	successConf            = "../../../example/oneway/oneway-conf.yaml"
+	successConf            = "../../../../examples/oneway/oneway-conf.yaml"
 	failureNotFoundConf    = "can_not_find_this_file.yaml"
-	failureReadConfContent = "../../../example/oneway/oneway-conf.yaml"
+	failureReadConfContent = "../../../../examples/oneway/oneway-conf.yaml"
 
-	start = "start"
-	stop  = "stop"
+	start  = "start"
+	stop   = "stop"
+	drain  = "drain"
+	reload = "reload"
 )
 
 var (
-	isTestWorkerCompleted bool
+	isTestWorkerCompleted atomic.Bool
 	_                     = func() bool {
 		testing.Init()
 		return true
 	}()
 )
 
+// testWorker is driven by the signals trapped in another goroutine, so its
+// statuses are guarded by the mutex
 type testWorker struct {
+	sync.Mutex
+
 	Input    string
 	Transit  string
 	Process  string
 	Output   string
 	CronJobs []string
+	Letters  []string
 
 	testWokrerStatus string
 	testCronJbStatus string
+	testLetterStatus string
+	testDrainTimeout time.Duration
+	testStatusPolls  int
+}
+
+func (t *testWorker) getWorkerStatus() string {
+	t.Lock()
+	defer t.Unlock()
+
+	return t.testWokrerStatus
+}
+
+func (t *testWorker) getCronJobStatus() string {
+	t.Lock()
+	defer t.Unlock()
+
+	return t.testCronJbStatus
+}
+
+func (t *testWorker) getDrainTimeout() time.Duration {
+	t.Lock()
+	defer t.Unlock()
+
+	return t.testDrainTimeout
+}
+
+func (t *testWorker) getStatusPolls() int {
+	t.Lock()
+	defer t.Unlock()
+
+	return t.testStatusPolls
 }
 
 var tester *testWorker
@@ -66,20 +105,72 @@ func (t *testWorker) SetParter(pluginType string) {
 	}
 }
 
-func (t *testWorker) StartParters() { t.testWokrerStatus = start }
+func (t *testWorker) StartParters() {
+	t.Lock()
+	defer t.Unlock()
 
-func (t *testWorker) StopParters() { t.testWokrerStatus = stop }
+	t.testWokrerStatus = start
+}
+
+func (t *testWorker) StopParters() {
+	t.Lock()
+	defer t.Unlock()
+
+	t.testWokrerStatus = stop
+}
+
+func (t *testWorker) DrainParters(timeout time.Duration) {
+	t.Lock()
+	defer t.Unlock()
+
+	t.testWokrerStatus = drain
+	t.testDrainTimeout = timeout
+}
+
+func (t *testWorker) Reload(cfg config.Configer, timeout time.Duration) error {
+	t.Lock()
+	defer t.Unlock()
+
+	t.testWokrerStatus = reload
+	t.testDrainTimeout = timeout
+	return nil
+}
 
 func (t *testWorker) SetCronner(pluginType string) {
 	t.CronJobs = append(t.CronJobs, cronJob)
 }
 
-func (t *testWorker) StartCronners() { t.testCronJbStatus = start }
+func (t *testWorker) StartCronners() {
+	t.Lock()
+	defer t.Unlock()
+
+	t.testCronJbStatus = start
+}
+
+func (t *testWorker) StopCronners() {
+	t.Lock()
+	defer t.Unlock()
+
+	t.testCronJbStatus = stop
+}
+
+func (t *testWorker) SetDeadLetter(pluginType string) {
+	t.Letters = append(t.Letters, letter)
+}
+
+func (t *testWorker) StopDeadLetters() {
+	t.Lock()
+	defer t.Unlock()
 
-func (t *testWorker) StopCronners() { t.testCronJbStatus = stop }
+	t.testLetterStatus = stop
+}
 
 func (t *testWorker) GetStatus() bool {
-	return isTestWorkerCompleted
+	t.Lock()
+	defer t.Unlock()
+
+	t.testStatusPolls++
+	return isTestWorkerCompleted.Load()
 }
 
 type testConfiger struct{}
@@ -92,6 +183,18 @@ func (tcfgr *testConfiger) Get(confKey string) interface{} { return confKey }
 
 func (tcfgr *testConfiger) GetInt32(confKey string) int32 { return 0 }
 
+// sendSignal traps the signals with a new channel and sends sig, it returns
+// once sig has been handled. the signals are handled one by one, so the one
+// sent last is only taken after sig is done
+func sendSignal(sig os.Signal) {
+	instance.signalChan = make(chan os.Signal, 1)
+	instance.TrapSignals()
+
+	instance.signalChan <- sig
+	instance.signalChan <- syscall.SIGUSR1
+	instance.signalChan <- syscall.SIGUSR1
+}
+
 func TestController(t *testing.T) {
 	GetInstance()
 
@@ -148,6 +251,7 @@ func TestActivateService(t *testing.T) {
 	assert.Equal(t, process, tester.Process, "failed to set process plugin")
 	assert.Equal(t, output, tester.Output, "failed to set output plugin")
 	assert.Equal(t, cronJob, tester.CronJobs[0], "failed to set cronjob plugin")
+	assert.Equal(t, letter, tester.Letters[0], "failed to set dead letter sink")
 }
 
 func TestStartService(t *testing.T) {
@@ -160,23 +264,6 @@ func TestStartService(t *testing.T) {
 	instance.wg.Done()
 }
 
-func TestRestartService(t *testing.T) {
-	tester = &testWorker{}
-	instance.Worker = tester
-	instance.wg.Add(1)
-
-	instance.log = log.GetLogger(module)
-	instance.ctx, instance.cancel = context.WithCancel(context.Background())
-	instance.wg = &wg
-	instance.signalChan = make(chan os.Signal, 1)
-	tester.testWokrerStatus = stop
-	tester.testCronJbStatus = stop
-
-	instance.Restart()
-	assert.Equal(t, start, tester.testWokrerStatus, "failed to restart worker")
-	assert.Equal(t, start, tester.testCronJbStatus, "failed to restart cronjob")
-}
-
 func TestStopService(t *testing.T) {
 	tester = &testWorker{}
 	instance.Worker = tester
@@ -187,6 +274,7 @@ func TestStopService(t *testing.T) {
 	instance.Stop()
 	assert.Equal(t, stop, tester.testWokrerStatus, "failed to stop worker")
 	assert.Equal(t, stop, tester.testCronJbStatus, "failed to stop cronjob")
+	assert.Equal(t, stop, tester.testLetterStatus, "failed to stop dead letter sink")
 }
 
 func TestTrapSignalsSIGTERM(t *testing.T) {
@@ -195,46 +283,65 @@ func TestTrapSignalsSIGTERM(t *testing.T) {
 	tester.testWokrerStatus = start
 	tester.testCronJbStatus = start
 
-	instance.TrapSignals()
-	instance.signalChan <- syscall.SIGTERM
+	instance.drainTimeout = defaultDrainTimeout
+	instance.wg.Add(1)
 
-	time.Sleep(2 * time.Second)
-	assert.Equal(t, stop, tester.testWokrerStatus, "failed to stop worker by SIGTERM")
-	assert.Equal(t, stop, tester.testCronJbStatus, "failed to stop cronjob by SIGTERM")
+	sendSignal(syscall.SIGTERM)
+	assert.Equal(t, drain, tester.getWorkerStatus(), "failed to drain worker by SIGTERM")
+	assert.Equal(t, defaultDrainTimeout, tester.getDrainTimeout(), "failed to drain worker with timeout")
+	assert.Equal(t, stop, tester.getCronJobStatus(), "failed to stop cronjob by SIGTERM")
+}
+
+func TestDrainServiceWithoutTimeout(t *testing.T) {
+	tester = &testWorker{}
+	instance.Worker = tester
+	instance.drainTimeout = 0
+	instance.wg.Add(1)
+
+	instance.Drain()
+	assert.Equal(t, stop, tester.testWokrerStatus, "failed to stop worker right away")
+	assert.Equal(t, stop, tester.testLetterStatus, "failed to stop dead letter sink")
 }
 
 func TestTrapSignalsSIGHUP(t *testing.T) {
 	tester = &testWorker{}
 	instance.Worker = tester
-	tester.testWokrerStatus = stop
-	tester.testCronJbStatus = stop
+	tester.testWokrerStatus = start
+	tester.testCronJbStatus = start
+	conf = successConf
 
-	instance.TrapSignals()
-	instance.signalChan <- syscall.SIGHUP
+	sendSignal(syscall.SIGHUP)
+	assert.Equal(t, reload, tester.getWorkerStatus(), "failed to reload worker by SIGHUP")
+	assert.Equal(t, 30*time.Second, tester.getDrainTimeout(), "failed to drain the reloaded groups with the timeout of the conf")
+	assert.Equal(t, start, tester.getCronJobStatus(), "failed to keep cronjob running by SIGHUP")
+}
+
+func TestReloadServiceWithInvalidConf(t *testing.T) {
+	tester = &testWorker{}
+	instance.Worker = tester
+	tester.testWokrerStatus = start
+	conf = failureNotFoundConf
+	defer func() { conf = successConf }()
 
-	time.Sleep(2 * time.Second)
-	assert.Equal(t, start, tester.testWokrerStatus, "failed to stop worker by SIGHUP")
-	assert.Equal(t, start, tester.testCronJbStatus, "failed to stop cronjob by SIGHUP")
+	err := instance.Reload()
+	assert.NotEqual(t, nil, err, "failed to reject the invalid conf")
+	assert.Equal(t, start, tester.testWokrerStatus, "failed to keep worker running with the previous conf")
 }
 
 func TestTraceStatusIsOneTimeExec(t *testing.T) {
 	tester = &testWorker{}
 	instance.Worker = tester
 	instance.isOneTimeExec = true
-	isTestWorkerCompleted = false
+	isTestWorkerCompleted.Store(false)
 	instance.wg.Add(1)
 
 	go func() {
 		time.Sleep(2 * time.Second)
-		assert.Equal(t, false, instance.isWorkerCompleted, "failed to sync worker status to false")
-	}()
-
-	go func() {
-		time.Sleep(3 * time.Second)
-		isTestWorkerCompleted = true
+		isTestWorkerCompleted.Store(true)
 	}()
 
 	instance.TraceStatus()
+	assert.Equal(t, true, tester.getStatusPolls() > 1, "failed to keep tracing the worker which is not completed")
 	assert.Equal(t, true, instance.isWorkerCompleted, "failed to sync worker status to true")
 }
 
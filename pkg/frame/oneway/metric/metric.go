@@ -48,6 +48,13 @@ var (
 		},
 	)
 
+	transitRetry = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "transit_retry",
+			Help: "",
+		},
+	)
+
 	processOK = promauto.NewGauge(
 		prometheus.GaugeOpts{
 			Name: "process_ok",
@@ -62,6 +69,13 @@ var (
 		},
 	)
 
+	processRetry = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "process_retry",
+			Help: "",
+		},
+	)
+
 	outputOK = promauto.NewGauge(
 		prometheus.GaugeOpts{
 			Name: "output_ok",
@@ -76,13 +90,82 @@ var (
 		},
 	)
 
+	outputRetry = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "output_retry",
+			Help: "",
+		},
+	)
+
+	deadLetter = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "dead_letter",
+			Help: "",
+		},
+	)
+
+	deadLetterErr = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "dead_letter_err",
+			Help: "",
+		},
+	)
+
+	routeCount = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "router_routed_total",
+			Help: "number of jobs matched by each route of the routers",
+		},
+		[]string{
+			"group",
+			"route",
+		},
+	)
+
+	queueDepth = prometheus.NewDesc(
+		"durable_queue_depth",
+		"number of jobs in the durable queue of each hop which were not acknowledged",
+		[]string{"hop", "group"},
+		nil,
+	)
+
+	queueSize = prometheus.NewDesc(
+		"durable_queue_size_bytes",
+		"bytes of the segment files of the durable queue of each hop",
+		[]string{"hop", "group"},
+		nil,
+	)
+
 	metricLogger = log.GetLogger(module)
 )
 
+// queueCollector reads the durable queues when the metrics are collected
+type queueCollector struct{}
+
+func (c queueCollector) Describe(descs chan<- *prometheus.Desc) {
+	descs <- queueDepth
+	descs <- queueSize
+}
+
+func (c queueCollector) Collect(metrics chan<- prometheus.Metric) {
+	for _, stat := range plugin.GetQueueStats() {
+		metrics <- prometheus.MustNewConstMetric(queueDepth, prometheus.GaugeValue, float64(stat.Depth), stat.Hop, stat.Group)
+		metrics <- prometheus.MustNewConstMetric(queueSize, prometheus.GaugeValue, float64(stat.Size), stat.Hop, stat.Group)
+	}
+}
+
+func init() {
+	prometheus.MustRegister(queueCollector{})
+}
+
 func RegisterGaugeMetric(gauge *prometheus.GaugeVec) {
 	monitoring.MetricRegistry.MustRegister(gauge)
 }
 
+func CountRoute(group string, route string) {
+	routeCount.WithLabelValues(group, route).Inc()
+}
+
 func showMetrics(metrics *plugin.Metric) {
 	jsonMetrics, err := json.Marshal(metrics)
 	if err != nil {
@@ -111,10 +194,16 @@ func setMetrics(metrics *plugin.Metric) {
 
 	transitOK.Set(float64(metrics.TransitOK))
 	transitErr.Set(float64(metrics.TransitErr))
+	transitRetry.Set(float64(metrics.TransitRetry))
 
 	processOK.Set(float64(metrics.ProcessOK))
 	processErr.Set(float64(metrics.ProcessErr))
+	processRetry.Set(float64(metrics.ProcessRetry))
 
 	outputOK.Set(float64(metrics.OutputOK))
 	outputErr.Set(float64(metrics.OutputErr))
+	outputRetry.Set(float64(metrics.OutputRetry))
+
+	deadLetter.Set(float64(metrics.DeadLetter))
+	deadLetterErr.Set(float64(metrics.DeadLetterErr))
 }
@@ -0,0 +1,169 @@
+package metric
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bigstack-oss/plane-go/pkg/base/protocol"
+	"github.com/bigstack-oss/plane-go/pkg/frame/oneway/plugin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	statusOK  = "ok"
+	statusErr = "err"
+)
+
+var (
+	handledCount = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "plugin_handled_total",
+			Help: "number of jobs handled by each plugin",
+		},
+		[]string{"service", "stage", "plugin", "parter", "group", "status"},
+	)
+
+	handleDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "plugin_handle_duration_seconds",
+			Help:    "seconds each plugin took to handle a job or a batch, retries included",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"service", "stage", "plugin", "parter", "group"},
+	)
+
+	queueWait = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "plugin_queue_wait_seconds",
+			Help:    "seconds a job waited in the hop before each plugin picked it up",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"service", "stage", "plugin", "parter", "group"},
+	)
+
+	inFlight = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "plugin_in_flight",
+			Help: "number of jobs each plugin is handling",
+		},
+		[]string{"service", "stage", "plugin", "parter", "group"},
+	)
+
+	hopDepth = prometheus.NewDesc(
+		"hop_depth",
+		"number of jobs left in each hop",
+		[]string{"service", "hop", "group"},
+		nil,
+	)
+
+	parters     = make(map[string]parter)
+	parterMutex = sync.RWMutex{}
+)
+
+// parter is the node, the plugin name and the index in the conf of a parter
+type parter struct {
+	node  plugin.Node
+	name  string
+	index string
+}
+
+// Handle measures one job or one batch handled by a plugin
+type Handle struct {
+	labels    []string
+	startedAt time.Time
+}
+
+// hopCollector reads the depth of the hops when the metrics are collected
+type hopCollector struct{}
+
+func (c hopCollector) Describe(descs chan<- *prometheus.Desc) {
+	descs <- hopDepth
+}
+
+func (c hopCollector) Collect(metrics chan<- prometheus.Metric) {
+	for _, stat := range plugin.GetHopStats() {
+		metrics <- prometheus.MustNewConstMetric(hopDepth, prometheus.GaugeValue, float64(stat.Depth), plugin.Service, stat.Hop, stat.Group)
+	}
+}
+
+func init() {
+	prometheus.MustRegister(hopCollector{})
+}
+
+// SetParter labels the metrics of the parter with the plugin name and the
+// index of the plugin in the conf
+func SetParter(parterName string, node plugin.Node, pluginName string, index int) {
+	parterMutex.Lock()
+	defer parterMutex.Unlock()
+
+	parters[parterName] = parter{node: node, name: pluginName, index: strconv.Itoa(index)}
+}
+
+// RemoveParter drops the metrics of the parter, so that a reloaded plugin
+// does not leave stale series behind. the other plugins of the same node
+// keep their metrics
+func RemoveParter(parterName string) {
+	parterMutex.Lock()
+	p, isExisted := parters[parterName]
+	delete(parters, parterName)
+	parterMutex.Unlock()
+
+	if !isExisted {
+		return
+	}
+
+	labels := prometheus.Labels{"stage": p.node.Stage, "group": p.node.Group, "plugin": p.name, "parter": p.index}
+	handledCount.DeletePartialMatch(labels)
+	handleDuration.DeletePartialMatch(labels)
+	queueWait.DeletePartialMatch(labels)
+	inFlight.DeletePartialMatch(labels)
+}
+
+func getParterLabels(stage string, part plugin.Part) []string {
+	parterMutex.RLock()
+	defer parterMutex.RUnlock()
+
+	p := parters[part.Parter]
+	return []string{plugin.Service, stage, p.name, p.index, part.Group}
+}
+
+// StartHandle is called by the wrappers before the jobs are handed over to
+// the plugin, it records how long the jobs waited in the hop
+func StartHandle(stage string, part plugin.Part, jobs ...protocol.Job) *Handle {
+	h := &Handle{
+		labels:    getParterLabels(stage, part),
+		startedAt: time.Now(),
+	}
+
+	for _, job := range jobs {
+		enqueuedAt := job.GetEnqueuedAt()
+		if enqueuedAt.IsZero() {
+			continue
+		}
+
+		queueWait.WithLabelValues(h.labels...).Observe(h.startedAt.Sub(enqueuedAt).Seconds())
+	}
+
+	inFlight.WithLabelValues(h.labels...).Inc()
+	return h
+}
+
+// Done records the result of a single job
+func (h *Handle) Done(err error) {
+	if err != nil {
+		h.DoneBatch(0, 1)
+		return
+	}
+
+	h.DoneBatch(1, 0)
+}
+
+// DoneBatch records the result of a batch
+func (h *Handle) DoneBatch(succeeded int, failed int) {
+	inFlight.WithLabelValues(h.labels...).Dec()
+	handleDuration.WithLabelValues(h.labels...).Observe(time.Since(h.startedAt).Seconds())
+	handledCount.WithLabelValues(append(h.labels, statusOK)...).Add(float64(succeeded))
+	handledCount.WithLabelValues(append(h.labels, statusErr)...).Add(float64(failed))
+}
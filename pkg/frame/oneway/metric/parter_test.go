@@ -0,0 +1,51 @@
+package metric
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bigstack-oss/plane-go/pkg/base/protocol"
+	"github.com/bigstack-oss/plane-go/pkg/frame/oneway/plugin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandle(t *testing.T) {
+	plugin.Service = "test"
+	part := plugin.Part{Group: "handle", Parter: "output-dummy-out-handle"}
+	SetParter(part.Parter, plugin.Node{Stage: plugin.Output, Group: part.Group}, "dummy-out", 1)
+	defer RemoveParter(part.Parter)
+
+	labels := []string{"test", plugin.Output, "dummy-out", "1", "handle"}
+	job := protocol.Job{ID: "0"}
+	job.SetEnqueuedAt(time.Now().Add(-time.Second))
+
+	handle := StartHandle(plugin.Output, part, job)
+	assert.Equal(t, float64(1), testutil.ToFloat64(inFlight.WithLabelValues(labels...)), "failed to count the job in flight")
+
+	handle.Done(nil)
+	handle = StartHandle(plugin.Output, part, job, job)
+	handle.DoneBatch(1, 1)
+
+	assert.Equal(t, float64(0), testutil.ToFloat64(inFlight.WithLabelValues(labels...)), "failed to count the finished jobs")
+	assert.Equal(t, float64(2), testutil.ToFloat64(handledCount.WithLabelValues(append(labels, statusOK)...)), "failed to count the succeeded jobs")
+	assert.Equal(t, float64(1), testutil.ToFloat64(handledCount.WithLabelValues(append(labels, statusErr)...)), "failed to count the failed jobs")
+	assert.Equal(t, 1, testutil.CollectAndCount(queueWait), "failed to observe the queue wait time")
+}
+
+func TestRemoveParter(t *testing.T) {
+	node := plugin.Node{Stage: plugin.Process, Group: "removed"}
+	first := plugin.Part{Group: node.Group, Parter: "process-dummy-proc-removed"}
+	second := plugin.Part{Group: node.Group, Parter: "process-dummy-proc-removed-2"}
+	SetParter(first.Parter, node, "dummy-proc", 0)
+	SetParter(second.Parter, node, "dummy-proc", 1)
+	defer RemoveParter(second.Parter)
+
+	StartHandle(plugin.Process, first).Done(errors.New("failed"))
+	StartHandle(plugin.Process, second).Done(errors.New("failed"))
+	assert.Equal(t, 4, testutil.CollectAndCount(handledCount), "failed to record the plugins of the node apart")
+
+	RemoveParter(first.Parter)
+	assert.Equal(t, 2, testutil.CollectAndCount(handledCount), "failed to drop the series of the removed plugin only")
+}
@@ -0,0 +1,171 @@
+package deadletter
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bigstack-oss/plane-go/pkg/base/log"
+	"github.com/bigstack-oss/plane-go/pkg/base/protocol"
+	"github.com/bigstack-oss/plane-go/pkg/frame/oneway/plugin"
+	"github.com/bigstack-oss/plane-go/pkg/frame/oneway/plugin/plug"
+)
+
+const (
+	module = "deadLetter"
+	Status = "deadLetter"
+)
+
+var (
+	Plugin = make(map[string]Sink)
+	Sinks  = make(map[string]Sink)
+
+	sinkMutex    = sync.RWMutex{}
+	letterLogger = log.GetLogger(module).Sugar()
+)
+
+// Sink takes the letters of a group, Send may block until ctx is done
+type Sink interface {
+	plug.Parter
+	Send(context.Context, Letter) error
+}
+
+// Targeter is implemented by the sinks which hand the letters over to the
+// output plugins of another group
+type Targeter interface {
+	GetTargetGroup() string
+}
+
+// Letter wraps a message which was dropped by a stage, so that it can be
+// inspected or replayed later on
+type Letter struct {
+	Group     string    `json:"group"`
+	Stage     string    `json:"stage"`
+	Error     string    `json:"error"`
+	Timestamp time.Time `json:"timestamp"`
+	Payload   []byte    `json:"payload"`
+}
+
+func (l Letter) Bytes() []byte {
+	b, err := json.Marshal(l)
+	if err != nil {
+		return nil
+	}
+
+	return b
+}
+
+// Job converts the letter into a job for the sinks which hand letters over
+// to output plugins. the original job is kept if the letter was dropped
+// after the transit stage
+func (l Letter) Job() protocol.Job {
+	job := protocol.Job{}
+	if l.Stage != plugin.Transit {
+		_ = json.Unmarshal(l.Payload, &job)
+	}
+
+	job.Result = &protocol.Result{
+		Status: Status,
+		Desc:   l.Error,
+		Data:   l.Bytes(),
+	}
+
+	return job
+}
+
+// SetSink replaces the sink of the group while the workers may be sending,
+// and stops the replaced one
+func SetSink(group string, sink Sink) {
+	sinkMutex.Lock()
+	replaced, isConfigured := Sinks[group]
+	Sinks[group] = sink
+	sinkMutex.Unlock()
+
+	if isConfigured {
+		replaced.Stop()
+	}
+}
+
+// RemoveSink stops and removes the sink of the group
+func RemoveSink(group string) {
+	sinkMutex.Lock()
+	sink, isConfigured := Sinks[group]
+	delete(Sinks, group)
+	sinkMutex.Unlock()
+
+	if isConfigured {
+		sink.Stop()
+	}
+}
+
+// Send hands the failed message over to the dead-letter sink of the group,
+// it does nothing if there is no sink configured for the group. it returns
+// false only if the sink failed to take the letter before ctx is done
+func Send(ctx context.Context, group string, stage string, payload []byte, cause error) bool {
+	sinkMutex.RLock()
+	sink, isConfigured := Sinks[group]
+	sinkMutex.RUnlock()
+	if !isConfigured {
+		return true
+	}
+
+	letter := Letter{
+		Group:     group,
+		Stage:     stage,
+		Error:     cause.Error(),
+		Timestamp: time.Now(),
+		Payload:   payload,
+	}
+
+	err := sink.Send(ctx, letter)
+	if err != nil {
+		atomic.AddInt64(&plugin.Metrics.DeadLetterErr, 1)
+		letterLogger.Errorf("failed to send dead letter of group(%s) stage(%s). error: %s", group, stage, err.Error())
+		return false
+	}
+
+	atomic.AddInt64(&plugin.Metrics.DeadLetter, 1)
+	return true
+}
+
+// ReadFile reads the letters written by the file sink from the given offset,
+// and returns the offset right after the last complete letter
+func ReadFile(path string, offset int64) ([]Letter, int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, offset, err
+	}
+	defer file.Close()
+
+	_, err = file.Seek(offset, io.SeekStart)
+	if err != nil {
+		return nil, offset, err
+	}
+
+	letters := []Letter{}
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			return letters, offset, nil
+		}
+		if err != nil {
+			return letters, offset, err
+		}
+
+		offset += int64(len(line))
+		letter := Letter{}
+		err = json.Unmarshal(line, &letter)
+		if err != nil {
+			letterLogger.Errorf("skip malformed dead letter in %s. error: %s", path, err.Error())
+			continue
+		}
+
+		letters = append(letters, letter)
+	}
+}
@@ -0,0 +1,86 @@
+package deadletter
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bigstack-oss/plane-go/pkg/base/protocol"
+	"github.com/bigstack-oss/plane-go/pkg/frame/oneway/plugin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSendWithoutSink(t *testing.T) {
+	plugin.Metrics = &plugin.Metric{}
+
+	Send(context.Background(), "no-sink", plugin.Transit, []byte("msg"), errors.New("dropped"))
+	assert.Equal(t, int64(0), plugin.Metrics.DeadLetter, "failed to skip the group without sink")
+}
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dead-letter.log")
+	plugin.Metrics = &plugin.Metric{}
+
+	Sinks["1"] = &File{}
+	Sinks["1"].SetConfig(map[string]interface{}{"group": "1", "sink": fileSink, "path": path})
+	assert.Equal(t, nil, Sinks["1"].CheckConfig(), "failed to check file sink config")
+	defer delete(Sinks, "1")
+
+	job := protocol.Job{ID: "job-1"}
+	Send(context.Background(), "1", plugin.Transit, []byte("raw msg"), errors.New("transit failed"))
+	Send(context.Background(), "1", plugin.Process, job.Bytes(), errors.New("process failed"))
+	Sinks["1"].Stop()
+	assert.Equal(t, int64(2), plugin.Metrics.DeadLetter, "failed to count dead letters")
+
+	letters, offset, err := ReadFile(path, 0)
+	assert.Equal(t, nil, err, "failed to read dead letters")
+	assert.Equal(t, 2, len(letters), "failed to read all dead letters")
+	assert.Equal(t, []byte("raw msg"), letters[0].Payload, "failed to keep the original payload")
+	assert.Equal(t, "transit failed", letters[0].Error, "failed to keep the error")
+	assert.Equal(t, plugin.Process, letters[1].Stage, "failed to keep the stage")
+	assert.Equal(t, "job-1", letters[1].Job().ID, "failed to restore the original job")
+	assert.Equal(t, Status, letters[1].Job().Result.Status, "failed to mark the job as dead letter")
+
+	letters, _, err = ReadFile(path, offset)
+	assert.Equal(t, nil, err, "failed to read dead letters from offset")
+	assert.Equal(t, 0, len(letters), "failed to resume from offset")
+}
+
+func TestSendFailure(t *testing.T) {
+	plugin.Metrics = &plugin.Metric{}
+
+	Sinks["1"] = &File{}
+	Sinks["1"].SetConfig(map[string]interface{}{"group": "1", "sink": fileSink, "path": filepath.Join(t.TempDir(), "missing", "dead-letter.log")})
+	defer delete(Sinks, "1")
+
+	isPassed := Send(context.Background(), "1", plugin.Transit, []byte("msg"), errors.New("dropped"))
+	assert.Equal(t, false, isPassed, "failed to report the letter the sink failed to take")
+	assert.Equal(t, int64(0), plugin.Metrics.DeadLetter, "failed to count the dead letters sent only")
+	assert.Equal(t, int64(1), plugin.Metrics.DeadLetterErr, "failed to count the dead letters failed to be sent")
+}
+
+func TestOutputSink(t *testing.T) {
+	plugin.P2OChan["dead-letter"] = make(chan protocol.Job, 1)
+	defer delete(plugin.P2OChan, "dead-letter")
+
+	sink := &Output{}
+	sink.SetConfig(map[string]interface{}{"group": "1", "sink": outputSink, "to": "1"})
+	assert.NotEqual(t, nil, sink.CheckConfig(), "failed to detect the letters sent back to the group")
+
+	sink.SetConfig(map[string]interface{}{"group": "1", "sink": outputSink, "to": "dead-letter"})
+	assert.Equal(t, nil, sink.CheckConfig(), "failed to check output sink config")
+	assert.Equal(t, "dead-letter", sink.GetTargetGroup(), "failed to return the target group")
+
+	err := sink.Send(context.Background(), Letter{Group: "1", Stage: plugin.Transit, Error: "failed"})
+	assert.Equal(t, nil, err, "failed to send letter to output group")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err = sink.Send(ctx, Letter{Group: "1", Stage: plugin.Transit, Error: "busy"})
+	assert.NotEqual(t, nil, err, "failed to give up the letter once the context is done")
+
+	job := <-plugin.P2OChan["dead-letter"]
+	assert.Equal(t, "failed", job.Result.Desc, "failed to carry the error to output group")
+}
@@ -0,0 +1,72 @@
+package deadletter
+
+import (
+	"context"
+	"os"
+	"sync"
+
+	"github.com/goinggo/mapstructure"
+	"gopkg.in/go-playground/validator.v9"
+)
+
+const (
+	fileSink = "file"
+)
+
+type File struct {
+	mutex sync.Mutex
+	file  *os.File
+
+	config fileConfig
+}
+
+type fileConfig struct {
+	Group string `validate:"required"`
+	Sink  string `validate:"required"`
+	Path  string `validate:"required"`
+}
+
+func init() {
+	Plugin[fileSink] = &File{}
+}
+
+func (f *File) SetConfig(conf interface{}) {
+	_ = mapstructure.Decode(conf, &f.config)
+}
+
+func (f *File) CheckConfig() error {
+	return validator.New().Struct(f.config)
+}
+
+func (f *File) Send(ctx context.Context, letter Letter) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.file == nil {
+		file, err := os.OpenFile(f.config.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return err
+		}
+
+		f.file = file
+	}
+
+	_, err := f.file.Write(append(letter.Bytes(), '\n'))
+	return err
+}
+
+func (f *File) Stop() {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.file == nil {
+		return
+	}
+
+	err := f.file.Close()
+	if err != nil {
+		letterLogger.Errorf("failed to close dead letter file(%s). error: %s", f.config.Path, err.Error())
+	}
+
+	f.file = nil
+}
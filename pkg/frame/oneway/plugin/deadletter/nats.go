@@ -0,0 +1,79 @@
+package deadletter
+
+import (
+	"context"
+	"sync"
+
+	"github.com/bigstack-oss/plane-go/pkg/sdk-inject/nats"
+	"github.com/goinggo/mapstructure"
+	"gopkg.in/go-playground/validator.v9"
+)
+
+const (
+	natsSink = "nats"
+)
+
+// Nats publishes the letters to the subject. it connects on the first letter,
+// so that checking the conf never dials the sockets
+type Nats struct {
+	mutex       sync.Mutex
+	isConnected bool
+	helper      *nats.Helper
+
+	config natsConfig
+}
+
+type natsConfig struct {
+	Group         string   `validate:"required"`
+	Sink          string   `validate:"required"`
+	Sockets       []string `validate:"required"`
+	Subject       string   `validate:"required"`
+	IsHeadlessSvc bool
+	Retry         int
+}
+
+func init() {
+	Plugin[natsSink] = &Nats{}
+}
+
+func (n *Nats) SetConfig(conf interface{}) {
+	_ = mapstructure.Decode(conf, &n.config)
+
+	n.helper = &nats.Helper{}
+	n.helper.Sockets = n.config.Sockets
+	n.helper.IsHeadlessSvc = n.config.IsHeadlessSvc
+	n.helper.Subject = n.config.Subject
+	n.helper.Retry = n.config.Retry
+}
+
+func (n *Nats) CheckConfig() error {
+	return validator.New().Struct(n.config)
+}
+
+func (n *Nats) connect() error {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	if n.isConnected {
+		return nil
+	}
+
+	err := n.helper.ConnectJetStream()
+	if err != nil {
+		return err
+	}
+
+	n.isConnected = true
+	return nil
+}
+
+func (n *Nats) Send(ctx context.Context, letter Letter) error {
+	err := n.connect()
+	if err != nil {
+		return err
+	}
+
+	return n.helper.Publish(n.config.Subject, letter.Bytes())
+}
+
+func (n *Nats) Stop() {}
@@ -0,0 +1,64 @@
+package deadletter
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bigstack-oss/plane-go/pkg/frame/oneway/plugin"
+	"github.com/goinggo/mapstructure"
+	"gopkg.in/go-playground/validator.v9"
+)
+
+const (
+	outputSink = "output"
+)
+
+// Output hands the letters over to the output plugins of another group,
+// so any output plugin can be used as the dead-letter sink. the workers wait
+// for the group to take the letter until their context is done
+type Output struct {
+	config outputConfig
+}
+
+type outputConfig struct {
+	Group string `validate:"required"`
+	Sink  string `validate:"required"`
+	To    string `validate:"required"`
+}
+
+func init() {
+	Plugin[outputSink] = &Output{}
+}
+
+func (o *Output) SetConfig(conf interface{}) {
+	_ = mapstructure.Decode(conf, &o.config)
+}
+
+func (o *Output) CheckConfig() error {
+	err := validator.New().Struct(o.config)
+	if err != nil {
+		return err
+	}
+
+	if o.config.To == o.config.Group {
+		return fmt.Errorf("dead letters of group(%s) can not be sent back to itself", o.config.Group)
+	}
+
+	return nil
+}
+
+// GetTargetGroup returns the group whose output plugins take the letters
+func (o *Output) GetTargetGroup() string {
+	return o.config.To
+}
+
+func (o *Output) Send(ctx context.Context, letter Letter) error {
+	target := plugin.Node{Stage: plugin.Output, Group: o.config.To}
+	if !plugin.SendJobToNode(ctx, target, letter.Job()) {
+		return fmt.Errorf("output group(%s) did not take the letter", o.config.To)
+	}
+
+	return nil
+}
+
+func (o *Output) Stop() {}
@@ -0,0 +1,281 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"hash/fnv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bigstack-oss/plane-go/pkg/base/log"
+	"github.com/bigstack-oss/plane-go/pkg/base/protocol"
+	"github.com/bigstack-oss/plane-go/pkg/base/queue"
+)
+
+const (
+	Durable = "durables"
+)
+
+var (
+	Queues   = make(map[string]*queue.Log)
+	MsgLanes = make(map[string][](chan []byte))
+
+	// the records delivered to every lane, in the order of delivery
+	laneOffsets = make(map[string][]*offsetQueue)
+	queueMutex  = sync.RWMutex{}
+
+	queueLoggerf = log.GetLogger(Durable).Sugar()
+)
+
+// delivery is a record of the queue which was delivered to a lane
+type delivery struct {
+	offset int64
+	record []byte
+}
+
+type offsetQueue struct {
+	mutex      sync.Mutex
+	deliveries []delivery
+}
+
+func (q *offsetQueue) push(offset int64, record []byte) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	q.deliveries = append(q.deliveries, delivery{offset: offset, record: record})
+}
+
+func (q *offsetQueue) pop() (delivery, bool) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if len(q.deliveries) == 0 {
+		return delivery{}, false
+	}
+
+	d := q.deliveries[0]
+	q.deliveries = q.deliveries[1:]
+	return d, true
+}
+
+// QueueStat is the state of the durable queue of a hop
+type QueueStat struct {
+	Hop   string
+	Group string
+	Depth int64
+	Size  int64
+}
+
+func getQueue(hop string, group string) (*queue.Log, bool) {
+	queueMutex.RLock()
+	defer queueMutex.RUnlock()
+
+	log, isDurable := Queues[HopKey(hop, group)]
+	return log, isDurable
+}
+
+// SetDurable backs the hop of the group with the queue. the records of the
+// queue are dispatched to one lane for each consumer worker, and the ordered
+// hops dispatch jobs with the same id to the same lane
+func SetDurable(ctx context.Context, hop string, group string, log *queue.Log, number int, isOrdered bool) {
+	key := HopKey(hop, group)
+	size := getChanSize(hop, group)
+	offsets := make([]*offsetQueue, number)
+	for i := range offsets {
+		offsets[i] = &offsetQueue{}
+	}
+
+	queueMutex.Lock()
+	Queues[key] = log
+	laneOffsets[key] = offsets
+	queueMutex.Unlock()
+
+	if hop == I2T {
+		lanes := make([](chan []byte), number)
+		for i := range lanes {
+			lanes[i] = make(chan []byte, size)
+		}
+
+		hopMutex.Lock()
+		MsgLanes[key] = lanes
+		hopMutex.Unlock()
+
+		go pumpMsgs(ctx, log, lanes, offsets)
+		return
+	}
+
+	lanes := make([](chan protocol.Job), number)
+	for i := range lanes {
+		lanes[i] = make(chan protocol.Job, size)
+	}
+
+	hopMutex.Lock()
+	Lanes[key] = lanes
+	hopMutex.Unlock()
+
+	go pumpJobs(ctx, log, lanes, offsets, isOrdered)
+}
+
+func pumpMsgs(ctx context.Context, log *queue.Log, lanes [](chan []byte), offsets []*offsetQueue) {
+	defer func() {
+		for _, lane := range lanes {
+			close(lane)
+		}
+	}()
+
+	for {
+		offset, msg, isOpen := log.Next(ctx)
+		if !isOpen {
+			return
+		}
+
+		lane := int(offset % int64(len(lanes)))
+		offsets[lane].push(offset, msg)
+		select {
+		case <-ctx.Done():
+			return
+		case lanes[lane] <- msg:
+		}
+	}
+}
+
+func pumpJobs(ctx context.Context, log *queue.Log, lanes [](chan protocol.Job), offsets []*offsetQueue, isOrdered bool) {
+	defer func() {
+		for _, lane := range lanes {
+			close(lane)
+		}
+	}()
+
+	for {
+		offset, record, isOpen := log.Next(ctx)
+		if !isOpen {
+			return
+		}
+
+		job := protocol.Job{}
+		err := json.Unmarshal(record, &job)
+		if err != nil {
+			queueLoggerf.Errorf("failed to decode record(%d) of queue, it is dropped. record: %s, error: %s", offset, string(record), err.Error())
+			_ = log.Ack(offset)
+			continue
+		}
+
+		lane := int(offset % int64(len(lanes)))
+		if isOrdered {
+			hash := fnv.New32a()
+			_, _ = hash.Write([]byte(job.ID))
+			lane = int(hash.Sum32() % uint32(len(lanes)))
+		}
+
+		offsets[lane].push(offset, record)
+		job.SetEnqueuedAt(time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case lanes[lane] <- job:
+		}
+	}
+}
+
+// popOffset takes the record the worker received last from the durable
+// queue of the hop
+func popOffset(hop string, group string, worker int) (*queue.Log, delivery, bool) {
+	queueMutex.RLock()
+	log, isDurable := Queues[HopKey(hop, group)]
+	offsets := laneOffsets[HopKey(hop, group)]
+	queueMutex.RUnlock()
+
+	if !isDurable {
+		return nil, delivery{}, false
+	}
+
+	d, isDelivered := offsets[worker%len(offsets)].pop()
+	return log, d, isDelivered
+}
+
+// Ack tells the durable queue of the hop that the worker is done with the
+// message it received last. it does nothing if the hop is not durable
+func Ack(hop string, group string, worker int) {
+	log, d, isDelivered := popOffset(hop, group, worker)
+	if isDelivered {
+		_ = log.Ack(d.offset)
+	}
+}
+
+// Nack tells the durable queue of the hop that the worker gave up the
+// message it received last. the message is appended to the queue again and
+// acked, so that the acked offset keeps moving. it is left unacknowledged
+// and delivered again after restart if the queue no longer takes records
+func Nack(hop string, group string, worker int) {
+	log, d, isDelivered := popOffset(hop, group, worker)
+	if !isDelivered {
+		return
+	}
+
+	_, err := log.Append(d.record)
+	if err != nil {
+		queueLoggerf.Warnf("failed to requeue record(%d) of %s, it is delivered again after restart. error: %s", d.offset, HopKey(hop, group), err.Error())
+		return
+	}
+
+	_ = log.Ack(d.offset)
+}
+
+// Settle acks the message the worker received last if it was passed on,
+// and nacks it otherwise
+func Settle(hop string, group string, worker int, isPassed bool) {
+	if isPassed {
+		Ack(hop, group, worker)
+		return
+	}
+
+	Nack(hop, group, worker)
+}
+
+// CloseQueues closes the files of all durable queues, the records which were
+// not acknowledged are delivered again once the queues are opened next time
+func CloseQueues() {
+	queueMutex.Lock()
+	defer queueMutex.Unlock()
+
+	for _, log := range Queues {
+		_ = log.Close()
+	}
+
+	Queues = make(map[string]*queue.Log)
+	laneOffsets = make(map[string][]*offsetQueue)
+}
+
+func closeQueue(key string) {
+	queueMutex.Lock()
+	defer queueMutex.Unlock()
+
+	log, isDurable := Queues[key]
+	if !isDurable {
+		return
+	}
+
+	_ = log.Close()
+	delete(Queues, key)
+	delete(laneOffsets, key)
+}
+
+// GetQueueStats returns the depth and the disk usage of the durable queues
+func GetQueueStats() []QueueStat {
+	queueMutex.RLock()
+	defer queueMutex.RUnlock()
+
+	stats := []QueueStat{}
+	for key, log := range Queues {
+		parts := strings.SplitN(key, "-", 2)
+		stats = append(stats, QueueStat{
+			Hop:   parts[0],
+			Group: parts[1],
+			Depth: log.Depth(),
+			Size:  log.Size(),
+		})
+	}
+
+	return stats
+}
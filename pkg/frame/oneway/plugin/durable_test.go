@@ -0,0 +1,107 @@
+package plugin
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"github.com/bigstack-oss/plane-go/pkg/base/protocol"
+	"github.com/bigstack-oss/plane-go/pkg/base/queue"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDurableHop(t *testing.T) {
+	ResetHops()
+	defer CloseQueues()
+	AddHop(P2O, "durable", 10)
+	dir := t.TempDir()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	log, err := queue.Open(dir, 0, false)
+	assert.Equal(t, nil, err, "failed to open queue")
+	SetDurable(ctx, P2O, "durable", log, 1, false)
+	Edges[Node{Stage: Process, Group: "durable"}] = []Node{{Stage: Output, Group: "durable"}}
+	AddProducers(P2O, "durable", 1)
+
+	SendJob(ctx, Process, "durable", protocol.Job{ID: "job-1"})
+	SendJob(ctx, Process, "durable", protocol.Job{ID: "job-2"})
+	ReleaseTargets(Process, "durable")
+
+	job := <-GetJobChan(P2O, "durable", 0)
+	assert.Equal(t, "job-1", job.ID, "failed to deliver job from queue")
+	Ack(P2O, "durable", 0)
+	assert.Equal(t, int64(1), log.Depth(), "failed to ack the delivered job")
+
+	// the second job is delivered but not acknowledged before the restart
+	job = <-GetJobChan(P2O, "durable", 0)
+	assert.Equal(t, "job-2", job.ID, "failed to deliver job from queue")
+	_, isChnOpen := <-GetJobChan(P2O, "durable", 0)
+	assert.Equal(t, false, isChnOpen, "failed to close the lane after the queue is drained")
+	CloseQueues()
+
+	log, _ = queue.Open(dir, 0, false)
+	SetDurable(ctx, P2O, "durable", log, 1, false)
+	job = <-GetJobChan(P2O, "durable", 0)
+	assert.Equal(t, "job-2", job.ID, "failed to deliver the unacked job again")
+	assert.Equal(t, []QueueStat{{Hop: P2O, Group: "durable", Depth: 1, Size: log.Size()}}, GetQueueStats(), "failed to get queue stats")
+}
+
+func TestSettle(t *testing.T) {
+	ResetHops()
+	defer CloseQueues()
+	AddHop(P2O, "settled", 10)
+	dir := t.TempDir()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	log, err := queue.Open(dir, 0, false)
+	assert.Equal(t, nil, err, "failed to open queue")
+	SetDurable(ctx, P2O, "settled", log, 1, false)
+	Edges[Node{Stage: Process, Group: "settled"}] = []Node{{Stage: Output, Group: "settled"}, {Stage: Output, Group: "missing"}}
+
+	isDelivered := SendJob(ctx, Process, "settled", protocol.Job{ID: "job-1"})
+	assert.Equal(t, false, isDelivered, "failed to report the node which did not get the job")
+
+	Edges[Node{Stage: Process, Group: "settled"}] = []Node{{Stage: Output, Group: "settled"}}
+	isDelivered = SendJob(ctx, Process, "settled", protocol.Job{ID: "job-2"})
+	assert.Equal(t, true, isDelivered, "failed to report the delivered job")
+
+	<-GetJobChan(P2O, "settled", 0)
+	Settle(P2O, "settled", 0, false)
+	<-GetJobChan(P2O, "settled", 0)
+	Settle(P2O, "settled", 0, true)
+	assert.Equal(t, int64(1), log.Depth(), "failed to move the acked offset past the nacked job")
+
+	job := <-GetJobChan(P2O, "settled", 0)
+	assert.Equal(t, "job-1", job.ID, "failed to requeue the nacked job")
+
+	// the queue no longer takes records while it is drained
+	log.CloseWrite()
+	Settle(P2O, "settled", 0, false)
+	assert.Equal(t, int64(1), log.Depth(), "failed to keep the nacked job which could not be requeued")
+	CloseQueues()
+
+	log, _ = queue.Open(dir, 0, false)
+	SetDurable(ctx, P2O, "settled", log, 1, false)
+	job = <-GetJobChan(P2O, "settled", 0)
+	assert.Equal(t, "job-1", job.ID, "failed to deliver the nacked job again after restart")
+}
+
+func TestSendToClosedQueue(t *testing.T) {
+	ResetHops()
+	defer CloseQueues()
+	AddHop(I2T, "closed", 10)
+
+	log, err := queue.Open(t.TempDir(), 0, false)
+	assert.Equal(t, nil, err, "failed to open queue")
+	SetDurable(context.Background(), I2T, "closed", log, 1, false)
+	Edges[Node{Stage: Input, Group: "closed"}] = []Node{{Stage: Transit, Group: "closed"}}
+
+	log.CloseWrite()
+	failed := atomic.LoadInt64(&Metrics.DurableErr)
+	SendMsg(context.Background(), Input, "closed", []byte("msg"))
+	assert.Equal(t, failed+1, atomic.LoadInt64(&Metrics.DurableErr), "failed to count the message which was not written")
+}
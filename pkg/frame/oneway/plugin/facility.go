@@ -8,39 +8,54 @@ const (
 	Process = "process"
 	Output  = "output"
 	CronJob = "cronjobs"
+
+	DeadLetter = "deadLetters"
+
+	// ParterKey is where the worker puts the parter name into the conf of
+	// every plugin
+	ParterKey = "parter"
 )
 
 var (
 	Service string
 
-	ChanSize int32
-	I2TChan  = make(map[string](chan []byte))
-	T2PChan  = make(map[string](chan protocol.Job))
-	P2OChan  = make(map[string](chan protocol.Job))
+	I2TChan = make(map[string](chan []byte))
+	T2PChan = make(map[string](chan protocol.Job))
+	P2OChan = make(map[string](chan protocol.Job))
 
 	Metrics = &Metric{}
 	Records = &Record{}
 
-	InputDone   *int64 = new(int64)
-	TransitDone *int64 = new(int64)
-	ProcessDone *int64 = new(int64)
-	OutputDone  *int64 = new(int64)
-
 	IsOneTimeExec = false
 )
 
+// Part is squashed into the configs of the plugins and handed over to the
+// wrappers. Parter is the name given by the worker, which tells the plugins
+// of the same node apart in the metrics and the retry policies
+type Part struct {
+	Group  string `validate:"required"`
+	Parter string
+}
+
 type Metric struct {
 	InputOK  int64 `json:"inputOK"`
 	InputErr int64 `json:"inputErr"`
 
-	TransitOK  int64 `json:"transitOK"`
-	TransitErr int64 `json:"transitErr"`
+	TransitOK    int64 `json:"transitOK"`
+	TransitErr   int64 `json:"transitErr"`
+	TransitRetry int64 `json:"transitRetry"`
+
+	ProcessOK    int64 `json:"processOK"`
+	ProcessErr   int64 `json:"processErr"`
+	ProcessRetry int64 `json:"processRetry"`
 
-	ProcessOK  int64 `json:"processOK"`
-	ProcessErr int64 `json:"processErr"`
+	OutputOK    int64 `json:"outputOK"`
+	OutputErr   int64 `json:"outputErr"`
+	OutputRetry int64 `json:"outputRetry"`
 
-	OutputOK  int64 `json:"outputOK"`
-	OutputErr int64 `json:"outputErr"`
+	DeadLetter    int64 `json:"deadLetter"`
+	DeadLetterErr int64 `json:"deadLetterErr"`
+	DurableErr    int64 `json:"durableErr"`
 }
 
 type Record struct {
@@ -0,0 +1,366 @@
+package plugin
+
+import (
+	"context"
+	"hash/fnv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/bigstack-oss/plane-go/pkg/base/protocol"
+)
+
+const (
+	I2T = "i2t"
+	T2P = "t2p"
+	P2O = "p2o"
+)
+
+var (
+	Lanes = make(map[string][](chan protocol.Job))
+
+	producers = make(map[string]*int64)
+
+	// the number of the consumer workers started on every hop, which
+	// numbers the workers of all the plugins consuming the hop
+	consumers = make(map[string]*int32)
+
+	// the size of the channels of every hop
+	chanSizes = make(map[string]int32)
+
+	// the number of the workers of every node which are done
+	dones = make(map[Node]*int64)
+
+	// guards the hops and the edges, which are read by the running workers
+	// while the groups are reloaded
+	hopMutex = sync.RWMutex{}
+)
+
+func HopKey(hop string, group string) string {
+	return strings.Join([]string{hop, group}, "-")
+}
+
+func ResetHops() {
+	hopMutex.Lock()
+	defer hopMutex.Unlock()
+
+	producers = make(map[string]*int64)
+	consumers = make(map[string]*int32)
+	chanSizes = make(map[string]int32)
+	dones = make(map[Node]*int64)
+
+	Lanes = make(map[string][](chan protocol.Job))
+	MsgLanes = make(map[string][](chan []byte))
+	Edges = make(map[Node][]Node)
+	References = make(map[Node]map[string]Node)
+}
+
+// AddHop creates the channel of the hop which the plugins of the group
+// consume from, the lanes of the hop are created with the same size
+func AddHop(hop string, group string, size int32) {
+	hopMutex.Lock()
+	defer hopMutex.Unlock()
+
+	delete(consumers, HopKey(hop, group))
+	chanSizes[HopKey(hop, group)] = size
+	switch hop {
+	case I2T:
+		I2TChan[group] = make(chan []byte, size)
+	case T2P:
+		T2PChan[group] = make(chan protocol.Job, size)
+	case P2O:
+		P2OChan[group] = make(chan protocol.Job, size)
+	}
+}
+
+func getChanSize(hop string, group string) int32 {
+	hopMutex.RLock()
+	defer hopMutex.RUnlock()
+
+	return chanSizes[HopKey(hop, group)]
+}
+
+// RemoveGroup drops the hops, the lanes and the edges of the group, and
+// closes the durable queues of the group. the workers of the group and their
+// upstream workers have to be stopped before
+func RemoveGroup(group string) {
+	hopMutex.Lock()
+	defer hopMutex.Unlock()
+
+	for _, hop := range []string{I2T, T2P, P2O} {
+		key := HopKey(hop, group)
+		delete(producers, key)
+		delete(consumers, key)
+		delete(chanSizes, key)
+		delete(Lanes, key)
+		delete(MsgLanes, key)
+		closeQueue(key)
+	}
+
+	delete(I2TChan, group)
+	delete(T2PChan, group)
+	delete(P2OChan, group)
+
+	for node := range Edges {
+		if node.Group == group {
+			delete(Edges, node)
+		}
+	}
+
+	for node := range References {
+		if node.Group == group {
+			delete(References, node)
+		}
+	}
+
+	for node := range dones {
+		if node.Group == group {
+			delete(dones, node)
+		}
+	}
+}
+
+// CompleteWorker is called by a worker of the node which is done, since its
+// upstream hop was closed and consumed, or its input was executed once
+func CompleteWorker(stage string, group string) {
+	hopMutex.Lock()
+	node := Node{Stage: stage, Group: group}
+	if _, isExisted := dones[node]; !isExisted {
+		dones[node] = new(int64)
+	}
+	counter := dones[node]
+	hopMutex.Unlock()
+
+	atomic.AddInt64(counter, 1)
+}
+
+// CountDone returns the number of the workers of the node which are done
+func CountDone(stage string, group string) int64 {
+	hopMutex.RLock()
+	defer hopMutex.RUnlock()
+
+	counter, isExisted := dones[Node{Stage: stage, Group: group}]
+	if !isExisted {
+		return 0
+	}
+
+	return atomic.LoadInt64(counter)
+}
+
+// AddProducers records how many workers are writing into the hop of the group,
+// the channel of the hop is closed once all of them are released
+func AddProducers(hop string, group string, number int) {
+	hopMutex.Lock()
+	defer hopMutex.Unlock()
+
+	key := HopKey(hop, group)
+	if _, isExisted := producers[key]; !isExisted {
+		producers[key] = new(int64)
+	}
+
+	atomic.AddInt64(producers[key], int64(number))
+}
+
+// NextWorker numbers a consumer worker of the hop of the group, the workers
+// of all the plugins of a node take different numbers, so that every lane of
+// the hop has its own worker
+func NextWorker(hop string, group string) int {
+	hopMutex.Lock()
+	key := HopKey(hop, group)
+	if _, isExisted := consumers[key]; !isExisted {
+		consumers[key] = new(int32)
+	}
+	counter := consumers[key]
+	hopMutex.Unlock()
+
+	return int(atomic.AddInt32(counter, 1) - 1)
+}
+
+// HasProducers tells whether any worker writes into the hop of the group
+func HasProducers(hop string, group string) bool {
+	hopMutex.RLock()
+	defer hopMutex.RUnlock()
+
+	_, isExisted := producers[HopKey(hop, group)]
+	return isExisted
+}
+
+// ReleaseProducer is called by a worker which will never write into the hop
+// of the group again, and the last one closes the channel of the hop
+func ReleaseProducer(hop string, group string) {
+	hopMutex.RLock()
+	counter, isExisted := producers[HopKey(hop, group)]
+	msgChan, jobChan := I2TChan[group], getJobChan(hop, group)
+	hopMutex.RUnlock()
+
+	if !isExisted || atomic.AddInt64(counter, -1) != 0 {
+		return
+	}
+
+	log, isDurable := getQueue(hop, group)
+	if isDurable {
+		log.CloseWrite()
+		return
+	}
+
+	switch hop {
+	case I2T:
+		close(msgChan)
+	case T2P, P2O:
+		close(jobChan)
+	}
+}
+
+func getJobChan(hop string, group string) chan protocol.Job {
+	switch hop {
+	case T2P:
+		return T2PChan[group]
+	case P2O:
+		return P2OChan[group]
+	default:
+		return nil
+	}
+}
+
+func getMsgChan(group string) chan []byte {
+	hopMutex.RLock()
+	defer hopMutex.RUnlock()
+
+	return I2TChan[group]
+}
+
+// GetMsgChan returns the channel which the transit worker should consume from.
+// the lane of the worker is returned if the hop is durable
+func GetMsgChan(group string, worker int) chan []byte {
+	hopMutex.RLock()
+	defer hopMutex.RUnlock()
+
+	lanes, isDurable := MsgLanes[HopKey(I2T, group)]
+	if isDurable {
+		return lanes[worker%len(lanes)]
+	}
+
+	return I2TChan[group]
+}
+
+// GetJobChan returns the channel which the worker should consume from. the
+// lane of the worker is returned if the consumers of the hop keep the order,
+// or the hop is durable
+func GetJobChan(hop string, group string, worker int) chan protocol.Job {
+	hopMutex.RLock()
+	defer hopMutex.RUnlock()
+
+	lanes, hasLanes := Lanes[HopKey(hop, group)]
+	if hasLanes {
+		return lanes[worker%len(lanes)]
+	}
+
+	return getJobChan(hop, group)
+}
+
+// SetLanes splits the hop of the group into lanes, one for each consumer
+// worker, and dispatches jobs with the same id to the same lane
+func SetLanes(ctx context.Context, hop string, group string, number int) {
+	size := getChanSize(hop, group)
+	lanes := make([](chan protocol.Job), number)
+	for i := range lanes {
+		lanes[i] = make(chan protocol.Job, size)
+	}
+
+	hopMutex.Lock()
+	Lanes[HopKey(hop, group)] = lanes
+	inbound := getJobChan(hop, group)
+	hopMutex.Unlock()
+
+	go dispatchLanes(ctx, inbound, lanes)
+}
+
+func dispatchLanes(ctx context.Context, inbound chan protocol.Job, lanes [](chan protocol.Job)) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case job, isChnOpen := <-inbound:
+			if !isChnOpen {
+				for _, lane := range lanes {
+					close(lane)
+				}
+
+				return
+			}
+
+			hash := fnv.New32a()
+			_, _ = hash.Write([]byte(job.ID))
+			select {
+			case <-ctx.Done():
+				return
+			case lanes[hash.Sum32()%uint32(len(lanes))] <- job:
+			}
+		}
+	}
+}
+
+func countLanes[T any](lanes []chan T) int64 {
+	var count int64
+	for _, lane := range lanes {
+		count += int64(len(lane))
+	}
+
+	return count
+}
+
+// HopStat is the number of messages which are left in the hop of a group
+type HopStat struct {
+	Hop   string
+	Group string
+	Depth int64
+}
+
+// GetHopStats returns the depth of every hop, the messages delivered from a
+// durable queue count until they are acknowledged
+func GetHopStats() []HopStat {
+	hopMutex.RLock()
+	stats := []HopStat{}
+	for group, inbound := range I2TChan {
+		depth := int64(len(inbound)) + countLanes(MsgLanes[HopKey(I2T, group)])
+		stats = append(stats, HopStat{Hop: I2T, Group: group, Depth: depth})
+	}
+
+	for group, inbound := range T2PChan {
+		depth := int64(len(inbound)) + countLanes(Lanes[HopKey(T2P, group)])
+		stats = append(stats, HopStat{Hop: T2P, Group: group, Depth: depth})
+	}
+
+	for group, inbound := range P2OChan {
+		depth := int64(len(inbound)) + countLanes(Lanes[HopKey(P2O, group)])
+		stats = append(stats, HopStat{Hop: P2O, Group: group, Depth: depth})
+	}
+	hopMutex.RUnlock()
+
+	indexes := make(map[string]int)
+	for i, stat := range stats {
+		indexes[HopKey(stat.Hop, stat.Group)] = i
+	}
+
+	for _, stat := range GetQueueStats() {
+		i, isExisted := indexes[HopKey(stat.Hop, stat.Group)]
+		if !isExisted {
+			stats = append(stats, HopStat{Hop: stat.Hop, Group: stat.Group, Depth: stat.Depth})
+			continue
+		}
+
+		stats[i].Depth = stat.Depth
+	}
+
+	return stats
+}
+
+// CountRemaining returns the number of messages which are left in every hop
+func CountRemaining() map[string]int64 {
+	remaining := make(map[string]int64)
+	for _, stat := range GetHopStats() {
+		remaining[HopKey(stat.Hop, stat.Group)] = stat.Depth
+	}
+
+	return remaining
+}
@@ -0,0 +1,94 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/bigstack-oss/plane-go/pkg/base/protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReleaseProducer(t *testing.T) {
+	ResetHops()
+	T2PChan["hop"] = make(chan protocol.Job, 1)
+	defer delete(T2PChan, "hop")
+
+	AddProducers(T2P, "hop", 2)
+	ReleaseProducer(T2P, "hop")
+	select {
+	case <-T2PChan["hop"]:
+		t.Fatal("failed to keep the hop open while a producer is still running")
+	default:
+	}
+
+	ReleaseProducer(T2P, "hop")
+	_, isChnOpen := <-T2PChan["hop"]
+	assert.Equal(t, false, isChnOpen, "failed to close the hop after all producers are released")
+}
+
+func TestLanes(t *testing.T) {
+	ResetHops()
+	AddHop(P2O, "lane", 10)
+	defer RemoveGroup("lane")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	SetLanes(ctx, P2O, "lane", 3)
+
+	for i := 0; i < 6; i++ {
+		P2OChan["lane"] <- protocol.Job{ID: fmt.Sprintf("job-%d", i%2), Version: i}
+	}
+	close(P2OChan["lane"])
+
+	jobLanes := map[string]int{}
+	for worker := 0; worker < 3; worker++ {
+		lastVersion := -1
+		for job := range GetJobChan(P2O, "lane", worker) {
+			if lane, isExisted := jobLanes[job.ID]; isExisted {
+				assert.Equal(t, worker, lane, "failed to dispatch the same job id to the same lane")
+			}
+
+			assert.Less(t, lastVersion, job.Version, "failed to keep the order of jobs in lane")
+			jobLanes[job.ID] = worker
+			lastVersion = job.Version
+		}
+	}
+
+	assert.Equal(t, 2, len(jobLanes), "failed to dispatch all jobs")
+}
+
+func TestCountRemaining(t *testing.T) {
+	ResetHops()
+	T2PChan["remaining"] = make(chan protocol.Job, 2)
+	defer delete(T2PChan, "remaining")
+
+	T2PChan["remaining"] <- protocol.Job{}
+	assert.Equal(t, int64(1), CountRemaining()[HopKey(T2P, "remaining")], "failed to count the messages left in hop")
+}
+
+func TestNextWorker(t *testing.T) {
+	ResetHops()
+	defer ResetHops()
+
+	assert.Equal(t, 0, NextWorker(P2O, "1"), "failed to number the first worker")
+	assert.Equal(t, 1, NextWorker(P2O, "1"), "failed to number the worker of another plugin of the node")
+	assert.Equal(t, 0, NextWorker(T2P, "1"), "failed to number the workers of every hop on their own")
+
+	AddHop(P2O, "1", 0)
+	assert.Equal(t, 0, NextWorker(P2O, "1"), "failed to number the workers again once the hop is recreated")
+}
+
+func TestCompleteWorker(t *testing.T) {
+	ResetHops()
+	defer ResetHops()
+
+	CompleteWorker(Output, "1")
+	CompleteWorker(Output, "1")
+	CompleteWorker(Output, "2")
+	assert.Equal(t, int64(2), CountDone(Output, "1"), "failed to count the workers done of the node")
+
+	RemoveGroup("1")
+	assert.Equal(t, int64(0), CountDone(Output, "1"), "failed to count the workers of the reinstalled group from zero")
+	assert.Equal(t, int64(1), CountDone(Output, "2"), "failed to keep the workers done of other groups")
+}
@@ -5,6 +5,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/bigstack-oss/plane-go/pkg/frame/oneway/metric"
 	"github.com/bigstack-oss/plane-go/pkg/frame/oneway/plugin"
 	"github.com/bigstack-oss/plane-go/pkg/frame/oneway/plugin/plug"
 )
@@ -18,7 +19,17 @@ type Input interface {
 	DoInput()
 }
 
-func WrapWithSingleMsgLoop(ctx context.Context, wg *sync.WaitGroup, group string, coreFunc func() ([]byte, error), interval time.Duration) func() {
+func complete(group string) {
+	plugin.CompleteWorker(plugin.Input, group)
+	plugin.ReleaseTargets(plugin.Input, group)
+}
+
+// release lets the downstream nodes drain and close once the input is stopped
+func release(group string) {
+	plugin.ReleaseTargets(plugin.Input, group)
+}
+
+func WrapWithSingleMsgLoop(ctx context.Context, wg *sync.WaitGroup, part plugin.Part, coreFunc func() ([]byte, error), interval time.Duration) func() {
 	return func() {
 		wg.Add(1)
 		defer wg.Done()
@@ -26,17 +37,20 @@ func WrapWithSingleMsgLoop(ctx context.Context, wg *sync.WaitGroup, group string
 		for {
 			select {
 			case <-ctx.Done():
+				release(part.Group)
 				return
 			default:
+				handle := metric.StartHandle(plugin.Input, part)
 				msg, err := coreFunc()
+				handle.Done(err)
 				if err != nil {
 					continue
 				}
 
-				plugin.I2TChan[group] <- msg
+				plugin.SendMsg(ctx, plugin.Input, part.Group, msg)
 
 				if plugin.IsOneTimeExec {
-					close(plugin.I2TChan[group])
+					complete(part.Group)
 					return
 				}
 
@@ -46,7 +60,7 @@ func WrapWithSingleMsgLoop(ctx context.Context, wg *sync.WaitGroup, group string
 	}
 }
 
-func WrapWithBatchMsgLoop(ctx context.Context, wg *sync.WaitGroup, group string, coreFunc func() ([][]byte, error), interval time.Duration) func() {
+func WrapWithBatchMsgLoop(ctx context.Context, wg *sync.WaitGroup, part plugin.Part, coreFunc func() ([][]byte, error), interval time.Duration) func() {
 	return func() {
 		wg.Add(1)
 		defer wg.Done()
@@ -54,19 +68,22 @@ func WrapWithBatchMsgLoop(ctx context.Context, wg *sync.WaitGroup, group string,
 		for {
 			select {
 			case <-ctx.Done():
+				release(part.Group)
 				return
 			default:
+				handle := metric.StartHandle(plugin.Input, part)
 				msgs, err := coreFunc()
+				handle.Done(err)
 				if err != nil {
 					continue
 				}
 
 				for _, msg := range msgs {
-					plugin.I2TChan[group] <- msg
+					plugin.SendMsg(ctx, plugin.Input, part.Group, msg)
 				}
 
 				if plugin.IsOneTimeExec {
-					close(plugin.I2TChan[group])
+					complete(part.Group)
 					return
 				}
 
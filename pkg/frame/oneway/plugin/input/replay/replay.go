@@ -0,0 +1,159 @@
+package replay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bigstack-oss/plane-go/pkg/base/log"
+	"github.com/bigstack-oss/plane-go/pkg/frame/oneway/plugin"
+	"github.com/bigstack-oss/plane-go/pkg/frame/oneway/plugin/deadletter"
+	"github.com/bigstack-oss/plane-go/pkg/frame/oneway/plugin/input"
+	"github.com/goinggo/mapstructure"
+	"go.uber.org/zap"
+	"gopkg.in/go-playground/validator.v9"
+)
+
+const (
+	module = "dead-letter-replay"
+)
+
+// Replayer tails a dead-letter file written by the file sink and feeds the
+// payloads back to the transit plugins of its group. payloads dropped after
+// the transit stage are jobs in json, so the transit plugin of the replay
+// group is expected to decode them. the offset of the letters replayed is
+// saved next to the file, so that a restart resumes from there. the offset
+// is kept by a single worker, so the replayer can not run concurrently
+type Replayer struct {
+	wg     *sync.WaitGroup
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	input func()
+	// offset is read up to, and saved is the offset in the offset file
+	offset int64
+	saved  int64
+	config
+
+	log  *zap.Logger
+	logf *zap.SugaredLogger
+}
+
+type config struct {
+	Name          string `validate:"required"`
+	plugin.Part   `mapstructure:",squash"`
+	Path          string `validate:"required"`
+	Stage         string
+	FetchInterval int `validate:"required"`
+	Concurrency   int `validate:"max=1"`
+}
+
+func init() {
+	input.Plugin[module] = &Replayer{}
+}
+
+func (r *Replayer) SetConfig(conf interface{}) {
+	_ = mapstructure.Decode(conf, &r.config)
+
+	r.wg = &sync.WaitGroup{}
+	r.ctx, r.cancel = context.WithCancel(context.Background())
+	r.input = input.WrapWithBatchMsgLoop(r.ctx, r.wg, r.Part, r.coreFunc, time.Duration(r.FetchInterval))
+
+	r.log = log.GetLogger(module)
+	r.logf = r.log.Sugar()
+}
+
+func (r *Replayer) CheckConfig() error {
+	return validator.New().Struct(r.config)
+}
+
+// getOffsetPath returns the offset file of the replayer, every replayer of
+// the same file keeps its own offset since they may replay different stages
+func (r *Replayer) getOffsetPath() string {
+	return fmt.Sprintf("%s.%s.offset", r.Path, r.Name)
+}
+
+// loadOffset reads the offset saved last time, the file is replayed from
+// the beginning if there is no offset saved
+func (r *Replayer) loadOffset() {
+	b, err := os.ReadFile(r.getOffsetPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return
+	}
+	if err != nil {
+		r.logf.Errorf("failed to load replay offset of %s, replay from the beginning. error: %s", r.Path, err.Error())
+		return
+	}
+
+	offset, err := strconv.ParseInt(strings.TrimSpace(string(b)), 10, 64)
+	if err != nil {
+		r.logf.Errorf("invalid replay offset of %s, replay from the beginning. error: %s", r.Path, err.Error())
+		return
+	}
+
+	r.offset, r.saved = offset, offset
+}
+
+// saveOffset writes the offset of the letters read so far, the letters are
+// read again after a restart if the offset failed to be saved
+func (r *Replayer) saveOffset() {
+	if r.offset == r.saved {
+		return
+	}
+
+	path := r.getOffsetPath()
+	err := os.WriteFile(path+".tmp", []byte(strconv.FormatInt(r.offset, 10)), 0644)
+	if err == nil {
+		err = os.Rename(path+".tmp", path)
+	}
+	if err != nil {
+		r.logf.Errorf("failed to save replay offset of %s. error: %s", r.Path, err.Error())
+		return
+	}
+
+	r.saved = r.offset
+}
+
+// coreFunc saves the offset of the letters returned last time before reading
+// more, since they have been sent to the transit plugins by then
+func (r *Replayer) coreFunc() ([][]byte, error) {
+	r.saveOffset()
+	letters, offset, err := deadletter.ReadFile(r.Path, r.offset)
+	r.offset = offset
+	if err != nil {
+		r.logf.Errorf("failed to read dead letters from %s. error: %s", r.Path, err.Error())
+		time.Sleep(time.Duration(r.FetchInterval) * time.Second)
+		return nil, err
+	}
+
+	msgs := [][]byte{}
+	for _, letter := range letters {
+		if r.Stage != "" && r.Stage != letter.Stage {
+			continue
+		}
+
+		msgs = append(msgs, letter.Payload)
+	}
+
+	if len(msgs) > 0 {
+		r.logf.Infof("replay %d dead letters from %s", len(msgs), r.Path)
+	}
+
+	return msgs, nil
+}
+
+func (r *Replayer) DoInput() {
+	r.loadOffset()
+	r.input()
+}
+
+func (r *Replayer) Stop() {
+	r.cancel()
+	r.wg.Wait()
+	r.saveOffset()
+}
@@ -0,0 +1,49 @@
+package replay
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/bigstack-oss/plane-go/pkg/frame/oneway/plugin"
+	"github.com/bigstack-oss/plane-go/pkg/frame/oneway/plugin/deadletter"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestReplayer(t *testing.T, path string) *Replayer {
+	r := &Replayer{}
+	r.SetConfig(map[string]interface{}{"name": "replay", "group": "1", "path": path, "fetchInterval": 1})
+	assert.Equal(t, nil, r.CheckConfig(), "failed to check replay config")
+
+	r.loadOffset()
+	return r
+}
+
+func TestReplayOffset(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dead-letter.log")
+	deadletter.Sinks["1"] = &deadletter.File{}
+	deadletter.Sinks["1"].SetConfig(map[string]interface{}{"group": "1", "sink": "file", "path": path})
+	assert.Equal(t, nil, deadletter.Sinks["1"].CheckConfig(), "failed to check file sink config")
+	defer delete(deadletter.Sinks, "1")
+
+	deadletter.Send(context.Background(), "1", plugin.Transit, []byte("msg-1"), errors.New("failed"))
+	r := newTestReplayer(t, path)
+	msgs, err := r.coreFunc()
+	assert.Equal(t, nil, err, "failed to read dead letters")
+	assert.Equal(t, [][]byte{[]byte("msg-1")}, msgs, "failed to replay the payloads")
+	r.Stop()
+
+	deadletter.Send(context.Background(), "1", plugin.Transit, []byte("msg-2"), errors.New("failed"))
+	deadletter.Sinks["1"].Stop()
+	r = newTestReplayer(t, path)
+	msgs, err = r.coreFunc()
+	assert.Equal(t, nil, err, "failed to read dead letters")
+	assert.Equal(t, [][]byte{[]byte("msg-2")}, msgs, "failed to resume from the saved offset")
+}
+
+func TestReplayConcurrency(t *testing.T) {
+	r := &Replayer{}
+	r.SetConfig(map[string]interface{}{"name": "replay", "group": "1", "path": "dead-letter.log", "fetchInterval": 1, "concurrency": 2})
+	assert.NotEqual(t, nil, r.CheckConfig(), "failed to reject the concurrent replayer")
+}
@@ -2,11 +2,18 @@ package output
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/bigstack-oss/plane-go/pkg/base/protocol"
+	"github.com/bigstack-oss/plane-go/pkg/frame/oneway/metric"
 	"github.com/bigstack-oss/plane-go/pkg/frame/oneway/plugin"
+	"github.com/bigstack-oss/plane-go/pkg/frame/oneway/plugin/deadletter"
 	"github.com/bigstack-oss/plane-go/pkg/frame/oneway/plugin/plug"
+	"github.com/bigstack-oss/plane-go/pkg/frame/oneway/plugin/retry"
 )
 
 var (
@@ -18,24 +25,199 @@ type Output interface {
 	DoOutput()
 }
 
-func WrapWithSingleMsgLoop(ctx context.Context, wg *sync.WaitGroup, group string, coreFunc func(protocol.Job) error) func() {
+// BatchError can be returned by coreFunc of a batch output when only some of
+// the jobs failed, the failed jobs are keyed by their index in the batch
+type BatchError struct {
+	Failed map[int]error
+}
+
+func (b *BatchError) Error() string {
+	return fmt.Sprintf("%d jobs of the batch failed", len(b.Failed))
+}
+
+// WrapWithSingleMsgLoop returns the loop of an output worker. the loop can be
+// run by several goroutines when the plugin is configured with concurrency,
+// so coreFunc has to be safe for concurrent use in that case
+func WrapWithSingleMsgLoop(ctx context.Context, wg *sync.WaitGroup, part plugin.Part, coreFunc func(protocol.Job) error) func() {
 	return func() {
 		wg.Add(1)
 		defer wg.Done()
 
+		worker := plugin.NextWorker(plugin.P2O, part.Group)
+		inbound := plugin.GetJobChan(plugin.P2O, part.Group, worker)
 		for {
 			select {
 			case <-ctx.Done():
 				return
-			case message, isChnOpen := <-plugin.P2OChan[group]:
+			case message, isChnOpen := <-inbound:
 				switch isChnOpen {
 				case true:
-					err := coreFunc(message)
+					handle := metric.StartHandle(plugin.Output, part, message)
+					err := retry.Do(ctx, plugin.Output, part.Parter, func() error {
+						return coreFunc(message)
+					})
+					handle.Done(err)
 					if err != nil {
+						if ctx.Err() != nil {
+							return
+						}
+
+						isPassed := deadletter.Send(ctx, part.Group, plugin.Output, message.Bytes(), err)
+						plugin.Settle(plugin.P2O, part.Group, worker, isPassed)
 						continue
 					}
 
+					plugin.Ack(plugin.P2O, part.Group, worker)
 				case false:
+					plugin.CompleteWorker(plugin.Output, part.Group)
+					return
+				}
+			}
+		}
+	}
+}
+
+func getJobError(err error, index int) error {
+	var batchErr *BatchError
+	if errors.As(err, &batchErr) {
+		return batchErr.Failed[index]
+	}
+
+	return err
+}
+
+// flushBatch hands the batch over to coreFunc under the retry policy, only
+// the retryable failed jobs are handed over again. it returns the errors of
+// the failed jobs keyed by their index in the batch
+func flushBatch(ctx context.Context, parter string, batch []protocol.Job, coreFunc func([]protocol.Job) error) map[int]error {
+	failed := make(map[int]error)
+	pending := make([]int, len(batch))
+	for i := range pending {
+		pending[i] = i
+	}
+
+	_ = retry.Do(ctx, plugin.Output, parter, func() error {
+		jobs := make([]protocol.Job, len(pending))
+		for i, index := range pending {
+			jobs[i] = batch[index]
+		}
+
+		err := coreFunc(jobs)
+		retrying := []int{}
+		for i, index := range pending {
+			delete(failed, index)
+			jobErr := getJobError(err, i)
+			if jobErr == nil {
+				continue
+			}
+
+			failed[index] = jobErr
+			if retry.IsRetryable(jobErr) {
+				retrying = append(retrying, index)
+			}
+		}
+
+		pending = retrying
+		if len(pending) == 0 {
+			return nil
+		}
+
+		return err
+	})
+
+	return failed
+}
+
+// reportBatch sends the failed jobs to the dead-letter sink, it returns the
+// indexes of the failed jobs which the sink failed to take
+func reportBatch(ctx context.Context, group string, batch []protocol.Job, failed map[int]error) map[int]bool {
+	atomic.AddInt64(&plugin.Metrics.OutputOK, int64(len(batch)-len(failed)))
+	atomic.AddInt64(&plugin.Metrics.OutputErr, int64(len(failed)))
+
+	lost := make(map[int]bool)
+	for i, job := range batch {
+		err, isFailed := failed[i]
+		if isFailed && !deadletter.Send(ctx, group, plugin.Output, job.Bytes(), err) {
+			lost[i] = true
+		}
+	}
+
+	return lost
+}
+
+// WrapWithBatchMsgLoop returns the loop of an output worker which hands the
+// jobs over to coreFunc in batches. a batch is flushed once it has
+// maxBatchSize jobs, or maxLatency passed since its first job, and the rest
+// of the jobs are flushed when the channel is closed or the context is done.
+// the batches are only flushed by size if maxLatency is 0. the jobs from the
+// first failed one on are left unsettled if the context is done while the
+// batch is retried
+func WrapWithBatchMsgLoop(ctx context.Context, wg *sync.WaitGroup, part plugin.Part, maxBatchSize int, maxLatency time.Duration, coreFunc func([]protocol.Job) error) func() {
+	if maxBatchSize < 1 {
+		maxBatchSize = 1
+	}
+
+	return func() {
+		wg.Add(1)
+		defer wg.Done()
+
+		worker := plugin.NextWorker(plugin.P2O, part.Group)
+		inbound := plugin.GetJobChan(plugin.P2O, part.Group, worker)
+		batch := make([]protocol.Job, 0, maxBatchSize)
+
+		var timer *time.Timer
+		var deadline <-chan time.Time
+		flush := func() bool {
+			if timer != nil {
+				timer.Stop()
+				timer, deadline = nil, nil
+			}
+			if len(batch) == 0 {
+				return true
+			}
+
+			handle := metric.StartHandle(plugin.Output, part, batch...)
+			failed := flushBatch(ctx, part.Parter, batch, coreFunc)
+			handle.DoneBatch(len(batch)-len(failed), len(failed))
+			if len(failed) > 0 && ctx.Err() != nil {
+				atomic.AddInt64(&plugin.Metrics.OutputOK, int64(len(batch)-len(failed)))
+				for i := 0; failed[i] == nil; i++ {
+					plugin.Ack(plugin.P2O, part.Group, worker)
+				}
+				return false
+			}
+
+			lost := reportBatch(ctx, part.Group, batch, failed)
+			for i := range batch {
+				plugin.Settle(plugin.P2O, part.Group, worker, !lost[i])
+			}
+
+			batch = make([]protocol.Job, 0, maxBatchSize)
+			return true
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				flush()
+				return
+			case <-deadline:
+				if !flush() {
+					return
+				}
+			case message, isChnOpen := <-inbound:
+				if !isChnOpen {
+					flush()
+					plugin.CompleteWorker(plugin.Output, part.Group)
+					return
+				}
+
+				batch = append(batch, message)
+				if len(batch) == 1 && maxLatency > 0 {
+					timer = time.NewTimer(maxLatency)
+					deadline = timer.C
+				}
+				if len(batch) >= maxBatchSize && !flush() {
 					return
 				}
 			}
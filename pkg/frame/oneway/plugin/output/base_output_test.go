@@ -0,0 +1,126 @@
+package output
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bigstack-oss/plane-go/pkg/base/protocol"
+	"github.com/bigstack-oss/plane-go/pkg/frame/oneway/plugin"
+	"github.com/bigstack-oss/plane-go/pkg/frame/oneway/plugin/deadletter"
+	"github.com/bigstack-oss/plane-go/pkg/frame/oneway/plugin/retry"
+	"github.com/stretchr/testify/assert"
+)
+
+func sendJobs(group string, from int, to int) {
+	for i := from; i < to; i++ {
+		plugin.P2OChan[group] <- protocol.Job{ID: strconv.Itoa(i)}
+	}
+}
+
+func TestBatchBySizeAndClose(t *testing.T) {
+	plugin.ResetHops()
+	plugin.AddHop(plugin.P2O, "batch", 10)
+	defer plugin.RemoveGroup("batch")
+
+	batches := [][]protocol.Job{}
+	loop := WrapWithBatchMsgLoop(context.Background(), &sync.WaitGroup{}, plugin.Part{Group: "batch"}, 2, 0, func(jobs []protocol.Job) error {
+		batches = append(batches, jobs)
+		return nil
+	})
+
+	sendJobs("batch", 0, 5)
+	close(plugin.P2OChan["batch"])
+	loop()
+
+	assert.Equal(t, 3, len(batches), "failed to split jobs by batch size")
+	assert.Equal(t, 1, len(batches[2]), "failed to flush the remaining jobs on close")
+}
+
+func TestBatchByLatency(t *testing.T) {
+	plugin.ResetHops()
+	plugin.AddHop(plugin.P2O, "latency", 10)
+	defer plugin.RemoveGroup("latency")
+
+	flushed := make(chan []protocol.Job, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	wg := &sync.WaitGroup{}
+	loop := WrapWithBatchMsgLoop(ctx, wg, plugin.Part{Group: "latency"}, 10, 50*time.Millisecond, func(jobs []protocol.Job) error {
+		flushed <- jobs
+		return nil
+	})
+
+	go loop()
+	sendJobs("latency", 0, 3)
+
+	select {
+	case jobs := <-flushed:
+		assert.Equal(t, 3, len(jobs), "failed to flush the batch by latency")
+	case <-time.After(time.Second):
+		assert.Fail(t, "failed to flush the batch by latency")
+	}
+
+	cancel()
+	wg.Wait()
+}
+
+func TestBatchPartialFailure(t *testing.T) {
+	plugin.Metrics = &plugin.Metric{}
+	retry.SetPolicy("output-partial", &retry.Policy{Max: 2})
+	defer retry.SetPolicy("output-partial", nil)
+
+	attempts := [][]protocol.Job{}
+	failed := flushBatch(context.Background(), "output-partial", []protocol.Job{{ID: "0"}, {ID: "1"}, {ID: "2"}}, func(jobs []protocol.Job) error {
+		attempts = append(attempts, jobs)
+		if len(attempts) > 1 {
+			return nil
+		}
+
+		return &BatchError{Failed: map[int]error{
+			0: retry.Permanent(errors.New("bad job")),
+			2: errors.New("timeout"),
+		}}
+	})
+
+	assert.Equal(t, []protocol.Job{{ID: "2"}}, attempts[1], "failed to retry the retryable job only")
+	assert.Equal(t, 1, len(failed), "failed to report the failed job")
+	assert.NotEqual(t, nil, failed[0], "failed to report the failed job by its index")
+
+	reportBatch(context.Background(), "partial", []protocol.Job{{ID: "0"}, {ID: "1"}, {ID: "2"}}, failed)
+	assert.Equal(t, int64(2), plugin.Metrics.OutputOK, "failed to count the succeeded jobs")
+	assert.Equal(t, int64(1), plugin.Metrics.OutputErr, "failed to count the failed jobs")
+}
+
+func TestCancelDuringRetry(t *testing.T) {
+	plugin.ResetHops()
+	plugin.AddHop(plugin.P2O, "canceled", 10)
+	defer plugin.RemoveGroup("canceled")
+	plugin.Metrics = &plugin.Metric{}
+	retry.SetPolicy("output-canceled", &retry.Policy{Max: 5, InitialBackoff: 60000, MaxBackoff: 60000})
+	defer retry.SetPolicy("output-canceled", nil)
+
+	deadletter.Sinks["canceled"] = &deadletter.File{}
+	deadletter.Sinks["canceled"].SetConfig(map[string]interface{}{"group": "canceled", "sink": "file", "path": filepath.Join(t.TempDir(), "dead-letter.log")})
+	assert.Equal(t, nil, deadletter.Sinks["canceled"].CheckConfig(), "failed to check file sink config")
+	defer deadletter.RemoveSink("canceled")
+
+	attempted := make(chan bool, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	loop := WrapWithSingleMsgLoop(ctx, &sync.WaitGroup{}, plugin.Part{Group: "canceled", Parter: "output-canceled"}, func(protocol.Job) error {
+		attempted <- true
+		return errors.New("timeout")
+	})
+
+	sendJobs("canceled", 0, 1)
+	go func() {
+		<-attempted
+		cancel()
+	}()
+	loop()
+
+	assert.Equal(t, int64(0), plugin.Metrics.DeadLetter, "failed to leave the job unsettled when the context is done")
+}
@@ -1,5 +1,11 @@
 package plug
 
+import (
+	"time"
+
+	"github.com/bigstack-oss/plane-go/pkg/base/config"
+)
+
 type ConfigChecker interface {
 	CheckConfig() error
 }
@@ -11,3 +17,7 @@ type Stopper interface {
 type Statuser interface {
 	GetStatus() bool
 }
+
+type Reloader interface {
+	Reload(config.Configer, time.Duration) error
+}
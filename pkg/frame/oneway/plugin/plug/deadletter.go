@@ -0,0 +1,6 @@
+package plug
+
+type DeadLetterUser interface {
+	SetDeadLetter(string)
+	StopDeadLetters()
+}
@@ -1,5 +1,7 @@
 package plug
 
+import "time"
+
 var Parters = make(map[string]Parter)
 
 type PartConfigSetter interface {
@@ -12,8 +14,15 @@ type Parter interface {
 	Stopper
 }
 
+// Targeter is implemented by the parters which pick the downstream nodes of
+// every job by themselves, the targets are connected as the `to` option
+type Targeter interface {
+	GetTargets() []string
+}
+
 type PartUser interface {
 	SetParter(string)
 	StartParters()
 	StopParters()
+	DrainParters(time.Duration)
 }
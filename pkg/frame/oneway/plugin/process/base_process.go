@@ -5,8 +5,12 @@ import (
 	"sync"
 
 	"github.com/bigstack-oss/plane-go/pkg/base/protocol"
+	"github.com/bigstack-oss/plane-go/pkg/frame/oneway/metric"
 	"github.com/bigstack-oss/plane-go/pkg/frame/oneway/plugin"
+	"github.com/bigstack-oss/plane-go/pkg/frame/oneway/plugin/deadletter"
 	"github.com/bigstack-oss/plane-go/pkg/frame/oneway/plugin/plug"
+	"github.com/bigstack-oss/plane-go/pkg/frame/oneway/plugin/retry"
+	"github.com/mohae/deepcopy"
 )
 
 var (
@@ -18,26 +22,49 @@ type Process interface {
 	DoProcess()
 }
 
-func WrapWithSingleMsgLoop(ctx context.Context, wg *sync.WaitGroup, group string, coreFunc func(protocol.Job, bool) (protocol.Job, error)) func() {
+func complete(group string) {
+	plugin.CompleteWorker(plugin.Process, group)
+	plugin.ReleaseTargets(plugin.Process, group)
+}
+
+// WrapWithSingleMsgLoop returns the loop of a process worker. the loop can be
+// run by several goroutines when the plugin is configured with concurrency,
+// so coreFunc has to be safe for concurrent use in that case
+func WrapWithSingleMsgLoop(ctx context.Context, wg *sync.WaitGroup, part plugin.Part, coreFunc func(protocol.Job, bool) (protocol.Job, error)) func() {
 	return func() {
 		wg.Add(1)
 		defer wg.Done()
 
+		worker := plugin.NextWorker(plugin.T2P, part.Group)
+		inbound := plugin.GetJobChan(plugin.T2P, part.Group, worker)
 		for {
 			select {
 			case <-ctx.Done():
 				return
-			case msg, isChnOpen := <-plugin.T2PChan[group]:
+			case msg, isChnOpen := <-inbound:
 				switch isChnOpen {
 				case true:
-					msg, err := coreFunc(msg, isChnOpen)
+					var job protocol.Job
+					handle := metric.StartHandle(plugin.Process, part, msg)
+					err := retry.Do(ctx, plugin.Process, part.Parter, func() (err error) {
+						job, err = coreFunc(msg, isChnOpen)
+						return err
+					})
+					handle.Done(err)
 					if err != nil {
+						if ctx.Err() != nil {
+							return
+						}
+
+						isPassed := deadletter.Send(ctx, part.Group, plugin.Process, msg.Bytes(), err)
+						plugin.Settle(plugin.T2P, part.Group, worker, isPassed)
 						continue
 					}
 
-					plugin.P2OChan[group] <- msg
+					isPassed := plugin.SendJob(ctx, plugin.Process, part.Group, job)
+					plugin.Settle(plugin.T2P, part.Group, worker, isPassed)
 				case false:
-					close(plugin.P2OChan[group])
+					complete(part.Group)
 					return
 				}
 			}
@@ -45,28 +72,98 @@ func WrapWithSingleMsgLoop(ctx context.Context, wg *sync.WaitGroup, group string
 	}
 }
 
-func WrapWithBatchMsgLoop(ctx context.Context, wg *sync.WaitGroup, group string, coreFunc func(protocol.Job, bool) ([]protocol.Job, error)) func() {
+func WrapWithBatchMsgLoop(ctx context.Context, wg *sync.WaitGroup, part plugin.Part, coreFunc func(protocol.Job, bool) ([]protocol.Job, error)) func() {
 	return func() {
 		wg.Add(1)
 		defer wg.Done()
 
+		worker := plugin.NextWorker(plugin.T2P, part.Group)
+		inbound := plugin.GetJobChan(plugin.T2P, part.Group, worker)
 		for {
 			select {
 			case <-ctx.Done():
 				return
-			case msg, isChnOpen := <-plugin.T2PChan[group]:
+			case msg, isChnOpen := <-inbound:
 				switch isChnOpen {
 				case true:
-					msgs, err := coreFunc(msg, isChnOpen)
+					var jobs []protocol.Job
+					handle := metric.StartHandle(plugin.Process, part, msg)
+					err := retry.Do(ctx, plugin.Process, part.Parter, func() (err error) {
+						jobs, err = coreFunc(msg, isChnOpen)
+						return err
+					})
+					handle.Done(err)
 					if err != nil {
+						if ctx.Err() != nil {
+							return
+						}
+
+						isPassed := deadletter.Send(ctx, part.Group, plugin.Process, msg.Bytes(), err)
+						plugin.Settle(plugin.T2P, part.Group, worker, isPassed)
 						continue
 					}
 
-					for _, msg := range msgs {
-						plugin.P2OChan[group] <- msg
+					isPassed := true
+					for _, job := range jobs {
+						isPassed = plugin.SendJob(ctx, plugin.Process, part.Group, job) && isPassed
+					}
+					plugin.Settle(plugin.T2P, part.Group, worker, isPassed)
+				case false:
+					complete(part.Group)
+					return
+				}
+			}
+		}
+	}
+}
+
+// WrapWithRouteLoop returns the loop of a process worker which picks the
+// downstream nodes of every job. coreFunc returns the references of the nodes,
+// which have to be provided by the GetTargets of the plugin, and the job is
+// dropped when no reference is returned
+func WrapWithRouteLoop(ctx context.Context, wg *sync.WaitGroup, part plugin.Part, coreFunc func(protocol.Job) ([]string, error)) func() {
+	return func() {
+		wg.Add(1)
+		defer wg.Done()
+
+		worker := plugin.NextWorker(plugin.T2P, part.Group)
+		inbound := plugin.GetJobChan(plugin.T2P, part.Group, worker)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, isChnOpen := <-inbound:
+				switch isChnOpen {
+				case true:
+					var references []string
+					handle := metric.StartHandle(plugin.Process, part, msg)
+					err := retry.Do(ctx, plugin.Process, part.Parter, func() (err error) {
+						references, err = coreFunc(msg)
+						return err
+					})
+					handle.Done(err)
+					if err != nil {
+						if ctx.Err() != nil {
+							return
+						}
+
+						isPassed := deadletter.Send(ctx, part.Group, plugin.Process, msg.Bytes(), err)
+						plugin.Settle(plugin.T2P, part.Group, worker, isPassed)
+						continue
+					}
+
+					isPassed := true
+					for i, reference := range references {
+						job := msg
+						if i < len(references)-1 {
+							job = deepcopy.Copy(msg).(protocol.Job)
+						}
+
+						isPassed = plugin.SendJobTo(ctx, plugin.Process, part.Group, reference, job) && isPassed
 					}
+					plugin.Settle(plugin.T2P, part.Group, worker, isPassed)
 				case false:
-					close(plugin.P2OChan[group])
+					complete(part.Group)
 					return
 				}
 			}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/bigstack-oss/plane-go/pkg/base/log"
 	"github.com/bigstack-oss/plane-go/pkg/base/protocol"
+	"github.com/bigstack-oss/plane-go/pkg/frame/oneway/plugin"
 	"github.com/bigstack-oss/plane-go/pkg/frame/oneway/plugin/process"
 	"github.com/goinggo/mapstructure"
 	"go.uber.org/zap"
@@ -28,8 +29,8 @@ type Dazer struct {
 }
 
 type Config struct {
-	Name  string `validate:"required"`
-	Group string `validate:"required"`
+	Name        string `validate:"required"`
+	plugin.Part `mapstructure:",squash"`
 }
 
 func init() {
@@ -45,7 +46,7 @@ func registerModule() {
 func (d *Dazer) SetConfig(conf interface{}) {
 	_ = mapstructure.Decode(conf, &d.Config)
 	d.ctx, d.cancel = context.WithCancel(context.Background())
-	d.process = process.WrapWithSingleMsgLoop(d.ctx, d.wg, d.Group, d.coreFunc)
+	d.process = process.WrapWithSingleMsgLoop(d.ctx, d.wg, d.Part, d.coreFunc)
 
 	d.log = log.GetLogger(module)
 	d.logf = d.log.Sugar()
@@ -0,0 +1,163 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/bigstack-oss/plane-go/pkg/base/log"
+	"github.com/bigstack-oss/plane-go/pkg/base/protocol"
+	"github.com/bigstack-oss/plane-go/pkg/frame/oneway/metric"
+	"github.com/bigstack-oss/plane-go/pkg/frame/oneway/plugin"
+	"github.com/bigstack-oss/plane-go/pkg/frame/oneway/plugin/process"
+	"github.com/goinggo/mapstructure"
+	"go.uber.org/zap"
+	"gopkg.in/go-playground/validator.v9"
+)
+
+const (
+	module       = "router"
+	defaultRoute = "default"
+)
+
+// Router forwards every job to the downstream nodes of the first matched
+// route, or to the default route when no route is matched. the `to` option
+// of the router itself is not used
+type Router struct {
+	wg     *sync.WaitGroup
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	process func()
+	config
+
+	log  *zap.Logger
+	logf *zap.SugaredLogger
+}
+
+type config struct {
+	Name        string `validate:"required"`
+	plugin.Part `mapstructure:",squash"`
+	Routes      []Route `validate:"dive"`
+	Default     Action
+}
+
+type Route struct {
+	Name   string `validate:"required"`
+	Match  Match
+	Action `mapstructure:",squash"`
+}
+
+// Match compares the fields with the job, the empty fields match anything
+type Match struct {
+	Operation    string
+	ResourceType string
+	Project      string
+	Status       string
+}
+
+type Action struct {
+	To   []string
+	Drop bool
+}
+
+func init() {
+	process.Plugin[module] = &Router{}
+}
+
+func (a Action) check(route string) error {
+	if a.Drop && len(a.To) > 0 {
+		return fmt.Errorf("route(%s) can not drop and forward jobs at the same time", route)
+	}
+
+	return nil
+}
+
+func (m Match) isMatched(job protocol.Job) bool {
+	operation, resourceType, project, status := "", "", "", ""
+	if job.Desired != nil {
+		operation = job.Desired.Operation
+		if job.Desired.Resource != nil {
+			resourceType = job.Desired.Resource.Type
+		}
+	}
+	if job.Applicant != nil {
+		project = job.Applicant.Project
+	}
+	if job.Result != nil {
+		status = job.Result.Status
+	}
+
+	return (m.Operation == "" || m.Operation == operation) &&
+		(m.ResourceType == "" || m.ResourceType == resourceType) &&
+		(m.Project == "" || m.Project == project) &&
+		(m.Status == "" || m.Status == status)
+}
+
+func (r *Router) SetConfig(conf interface{}) {
+	_ = mapstructure.Decode(conf, &r.config)
+
+	r.wg = &sync.WaitGroup{}
+	r.ctx, r.cancel = context.WithCancel(context.Background())
+	r.process = process.WrapWithRouteLoop(r.ctx, r.wg, r.Part, r.coreFunc)
+
+	r.log = log.GetLogger(module)
+	r.logf = r.log.Sugar()
+}
+
+func (r *Router) CheckConfig() error {
+	err := validator.New().Struct(r.config)
+	if err != nil {
+		return err
+	}
+
+	for _, route := range r.Routes {
+		err = route.check(route.Name)
+		if err != nil {
+			return err
+		}
+	}
+
+	return r.Default.check(defaultRoute)
+}
+
+func (r *Router) GetTargets() []string {
+	targets := []string{}
+	for _, route := range r.Routes {
+		targets = append(targets, route.To...)
+	}
+
+	return append(targets, r.Default.To...)
+}
+
+func (r *Router) coreFunc(job protocol.Job) ([]string, error) {
+	for _, route := range r.Routes {
+		if !route.Match.isMatched(job) {
+			continue
+		}
+
+		metric.CountRoute(r.Group, route.Name)
+		if route.Drop {
+			return nil, nil
+		}
+
+		return route.To, nil
+	}
+
+	metric.CountRoute(r.Group, defaultRoute)
+	if len(r.Default.To) == 0 {
+		r.logf.Debugf("drop job(%s) matched no route", job.ID)
+	}
+
+	return r.Default.To, nil
+}
+
+func (r *Router) DoProcess() {
+	r.process()
+}
+
+func (r *Router) Stop() {
+	r.cancel()
+	r.wg.Wait()
+	r.logf.Infof("stop process plugin: %s", module)
+}
@@ -0,0 +1,68 @@
+package router
+
+import (
+	"testing"
+
+	"github.com/bigstack-oss/plane-go/pkg/base/protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestRouter(t *testing.T, conf map[string]interface{}) *Router {
+	r := &Router{}
+	r.SetConfig(conf)
+	assert.Equal(t, nil, r.CheckConfig(), "failed to check router config")
+
+	return r
+}
+
+func TestRoute(t *testing.T) {
+	r := newTestRouter(t, map[string]interface{}{
+		"name":  module,
+		"group": "1",
+		"routes": []interface{}{
+			map[string]interface{}{
+				"name":  "delete",
+				"match": map[string]interface{}{"operation": "delete"},
+				"to":    []interface{}{"deleter", "output/audit"},
+			},
+			map[string]interface{}{
+				"name":  "test-project",
+				"match": map[string]interface{}{"project": "test", "resourceType": "vm"},
+				"drop":  true,
+			},
+		},
+		"default": map[string]interface{}{
+			"to": []interface{}{"creator"},
+		},
+	})
+
+	assert.Equal(t, []string{"deleter", "output/audit", "creator"}, r.GetTargets(), "failed to list router targets")
+
+	deleteJob := protocol.Job{Desired: &protocol.Desired{Operation: "delete"}}
+	targets, err := r.coreFunc(deleteJob)
+	assert.Equal(t, nil, err, "failed to route job")
+	assert.Equal(t, []string{"deleter", "output/audit"}, targets, "failed to route job by operation")
+
+	testJob := protocol.Job{
+		Applicant: &protocol.Applicant{Project: "test"},
+		Desired:   &protocol.Desired{Operation: "create", Resource: &protocol.Resource{Type: "vm"}},
+	}
+	targets, _ = r.coreFunc(testJob)
+	assert.Equal(t, 0, len(targets), "failed to drop job")
+
+	targets, _ = r.coreFunc(protocol.Job{})
+	assert.Equal(t, []string{"creator"}, targets, "failed to route job to default route")
+}
+
+func TestInvalidRoute(t *testing.T) {
+	r := &Router{}
+	r.SetConfig(map[string]interface{}{
+		"name":  module,
+		"group": "1",
+		"routes": []interface{}{
+			map[string]interface{}{"name": "both", "to": []interface{}{"a"}, "drop": true},
+		},
+	})
+
+	assert.NotEqual(t, nil, r.CheckConfig(), "failed to detect route which drops and forwards")
+}
@@ -0,0 +1,139 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bigstack-oss/plane-go/pkg/frame/oneway/plugin"
+)
+
+var (
+	// the policies keyed by the names of the parters
+	Policies = make(map[string]Policy)
+
+	policyMutex = sync.RWMutex{}
+)
+
+// Policy is decoded from the `retry` block of a plugin config.
+// backoffs are in milliseconds and jitter is a ratio between 0 and 1
+type Policy struct {
+	Max            int     `validate:"min=0"`
+	InitialBackoff int     `validate:"min=0"`
+	MaxBackoff     int     `validate:"gtefield=InitialBackoff"`
+	Jitter         float64 `validate:"min=0,max=1"`
+}
+
+// Retryabler can be implemented by the errors returned from coreFunc to
+// decide whether the failed message deserves another attempt
+type Retryabler interface {
+	Retryable() bool
+}
+
+type permanentError struct {
+	err error
+}
+
+func (p *permanentError) Error() string {
+	return p.err.Error()
+}
+
+func (p *permanentError) Unwrap() error {
+	return p.err
+}
+
+func (p *permanentError) Retryable() bool {
+	return false
+}
+
+// Permanent marks the error as not retryable, so the message goes to the
+// dead-letter sink right away
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &permanentError{err: err}
+}
+
+func IsRetryable(err error) bool {
+	var retryabler Retryabler
+	if errors.As(err, &retryabler) {
+		return retryabler.Retryable()
+	}
+
+	return true
+}
+
+func (p Policy) backoff(attempt int) time.Duration {
+	backoff := float64(p.InitialBackoff)
+	for i := 1; i < attempt && backoff < float64(p.MaxBackoff); i++ {
+		backoff *= 2
+	}
+
+	if backoff > float64(p.MaxBackoff) {
+		backoff = float64(p.MaxBackoff)
+	}
+
+	backoff += backoff * p.Jitter * (2*rand.Float64() - 1)
+	return time.Duration(backoff) * time.Millisecond
+}
+
+func countRetry(stage string) {
+	switch stage {
+	case plugin.Transit:
+		atomic.AddInt64(&plugin.Metrics.TransitRetry, 1)
+	case plugin.Process:
+		atomic.AddInt64(&plugin.Metrics.ProcessRetry, 1)
+	case plugin.Output:
+		atomic.AddInt64(&plugin.Metrics.OutputRetry, 1)
+	}
+}
+
+// SetPolicy replaces the policy of the parter, the policy is removed when
+// nil is given
+func SetPolicy(parter string, policy *Policy) {
+	policyMutex.Lock()
+	defer policyMutex.Unlock()
+
+	if policy == nil {
+		delete(Policies, parter)
+		return
+	}
+
+	Policies[parter] = *policy
+}
+
+// Do runs coreFunc of the parter of the stage under its retry policy. it
+// stops retrying when the error is permanent, the attempts are used up or
+// the context is done, and returns the last error. the message is left
+// unsettled by the wrappers if the context is done, so that it is delivered
+// again instead of being dead-lettered
+func Do(ctx context.Context, stage string, parter string, coreFunc func() error) error {
+	policyMutex.RLock()
+	policy := Policies[parter]
+	policyMutex.RUnlock()
+
+	err := coreFunc()
+	for attempt := 1; err != nil && attempt <= policy.Max; attempt++ {
+		if !IsRetryable(err) {
+			return err
+		}
+
+		timer := time.NewTimer(policy.backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+
+		countRetry(stage)
+		err = coreFunc()
+	}
+
+	return err
+}
@@ -0,0 +1,81 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bigstack-oss/plane-go/pkg/frame/oneway/plugin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDoWithoutPolicy(t *testing.T) {
+	attempts := 0
+	err := Do(context.Background(), plugin.Transit, "no-policy", func() error {
+		attempts++
+		return errors.New("failed")
+	})
+
+	assert.NotEqual(t, nil, err, "failed to return the error")
+	assert.Equal(t, 1, attempts, "failed to run coreFunc exactly once")
+}
+
+func TestDoUntilSuccess(t *testing.T) {
+	plugin.Metrics = &plugin.Metric{}
+	Policies["parter-1"] = Policy{Max: 5, InitialBackoff: 1, MaxBackoff: 4}
+	defer delete(Policies, "parter-1")
+
+	attempts := 0
+	err := Do(context.Background(), plugin.Process, "parter-1", func() error {
+		attempts++
+		if attempts < 3 {
+			return errors.New("failed")
+		}
+
+		return nil
+	})
+
+	assert.Equal(t, nil, err, "failed to succeed after retries")
+	assert.Equal(t, 3, attempts, "failed to stop retrying after success")
+	assert.Equal(t, int64(2), plugin.Metrics.ProcessRetry, "failed to count retries")
+}
+
+func TestDoWithPermanentError(t *testing.T) {
+	Policies["parter-1"] = Policy{Max: 5}
+	defer delete(Policies, "parter-1")
+
+	attempts := 0
+	cause := errors.New("bad request")
+	err := Do(context.Background(), plugin.Output, "parter-1", func() error {
+		attempts++
+		return Permanent(cause)
+	})
+
+	assert.Equal(t, true, errors.Is(err, cause), "failed to keep the original error")
+	assert.Equal(t, false, IsRetryable(err), "failed to mark error as permanent")
+	assert.Equal(t, 1, attempts, "failed to skip retries of permanent error")
+}
+
+func TestDoWithCanceledContext(t *testing.T) {
+	Policies["parter-1"] = Policy{Max: 5, InitialBackoff: 60000, MaxBackoff: 60000}
+	defer delete(Policies, "parter-1")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		cancel()
+	}()
+
+	start := time.Now()
+	err := Do(ctx, plugin.Transit, "parter-1", func() error { return errors.New("failed") })
+	assert.NotEqual(t, nil, err, "failed to return the last error")
+	assert.Less(t, time.Since(start), 10*time.Second, "failed to stop retrying when context is done")
+}
+
+func TestBackoff(t *testing.T) {
+	policy := Policy{InitialBackoff: 100, MaxBackoff: 1000}
+	assert.Equal(t, 100*time.Millisecond, policy.backoff(1), "failed to use initial backoff")
+	assert.Equal(t, 400*time.Millisecond, policy.backoff(3), "failed to double backoff")
+	assert.Equal(t, 1000*time.Millisecond, policy.backoff(10), "failed to cap backoff")
+}
@@ -0,0 +1,170 @@
+package plugin
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/bigstack-oss/plane-go/pkg/base/protocol"
+	"github.com/bigstack-oss/plane-go/pkg/base/queue"
+	"github.com/mohae/deepcopy"
+)
+
+var (
+	Edges      = make(map[Node][]Node)
+	References = make(map[Node]map[string]Node)
+)
+
+// Node is a plugin in the pipeline, it is identified by its stage and group
+type Node struct {
+	Stage string
+	Group string
+}
+
+func (n Node) String() string {
+	return strings.Join([]string{n.Stage, n.Group}, "/")
+}
+
+// InboundHop returns the hop which the plugins of the stage consume from
+func InboundHop(stage string) string {
+	switch stage {
+	case Transit:
+		return I2T
+	case Process:
+		return T2P
+	case Output:
+		return P2O
+	default:
+		return ""
+	}
+}
+
+// AddEdges connects the nodes, the existing edges of other nodes are kept
+func AddEdges(edges map[Node][]Node, references map[Node]map[string]Node) {
+	hopMutex.Lock()
+	defer hopMutex.Unlock()
+
+	for source, targets := range edges {
+		Edges[source] = targets
+	}
+
+	for source, targets := range references {
+		References[source] = targets
+	}
+}
+
+func getTargets(node Node) []Node {
+	hopMutex.RLock()
+	defer hopMutex.RUnlock()
+
+	return Edges[node]
+}
+
+func getReference(node Node, reference string) (Node, bool) {
+	hopMutex.RLock()
+	defer hopMutex.RUnlock()
+
+	target, isExisted := References[node][reference]
+	return target, isExisted
+}
+
+// appendRecord writes the record into the durable queue, the records which
+// failed to be written are logged and counted
+func appendRecord(log *queue.Log, target Node, record []byte) bool {
+	_, err := log.Append(record)
+	if err != nil {
+		atomic.AddInt64(&Metrics.DurableErr, 1)
+		queueLoggerf.Errorf("failed to write the record into the queue of %s. error: %s", target.String(), err.Error())
+		return false
+	}
+
+	return true
+}
+
+// SendMsg passes the message of an input node to all of its transit nodes
+func SendMsg(ctx context.Context, stage string, group string, msg []byte) {
+	for _, target := range getTargets(Node{Stage: stage, Group: group}) {
+		log, isDurable := getQueue(I2T, target.Group)
+		if isDurable {
+			appendRecord(log, target, msg)
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case getMsgChan(target.Group) <- msg:
+		}
+	}
+}
+
+// SendJobToNode writes the job into the durable queue of the node if there
+// is one, otherwise into the channel of the node
+func SendJobToNode(ctx context.Context, target Node, job protocol.Job) bool {
+	hop := InboundHop(target.Stage)
+	log, isDurable := getQueue(hop, target.Group)
+	if isDurable {
+		return appendRecord(log, target, job.Bytes())
+	}
+
+	hopMutex.RLock()
+	inbound := getJobChan(hop, target.Group)
+	hopMutex.RUnlock()
+	if inbound == nil {
+		return false
+	}
+
+	job.SetEnqueuedAt(time.Now())
+
+	select {
+	case <-ctx.Done():
+		return false
+	case inbound <- job:
+		return true
+	}
+}
+
+// SendJob passes the job to all the downstream nodes of the node, every node
+// gets its own copy of the job when the job is broadcasted. it returns
+// whether all the nodes got the job
+func SendJob(ctx context.Context, stage string, group string, job protocol.Job) bool {
+	isDelivered := true
+	targets := getTargets(Node{Stage: stage, Group: group})
+	for i, target := range targets {
+		targetJob := job
+		if i < len(targets)-1 {
+			targetJob = deepcopy.Copy(job).(protocol.Job)
+		}
+
+		if SendJobToNode(ctx, target, targetJob) {
+			continue
+		}
+
+		isDelivered = false
+		if ctx.Err() != nil {
+			return false
+		}
+	}
+
+	return isDelivered
+}
+
+// SendJobTo passes the job to the downstream node referred by the reference
+// in the `to` option of the node
+func SendJobTo(ctx context.Context, stage string, group string, reference string, job protocol.Job) bool {
+	target, isExisted := getReference(Node{Stage: stage, Group: group}, reference)
+	if !isExisted {
+		return false
+	}
+
+	return SendJobToNode(ctx, target, job)
+}
+
+// ReleaseTargets releases a producer worker of the node from all of its
+// downstream nodes
+func ReleaseTargets(stage string, group string) {
+	for _, target := range getTargets(Node{Stage: stage, Group: group}) {
+		ReleaseProducer(InboundHop(target.Stage), target.Group)
+	}
+}
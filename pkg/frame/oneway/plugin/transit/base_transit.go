@@ -2,12 +2,14 @@ package transit
 
 import (
 	"context"
-
 	"sync"
 
 	"github.com/bigstack-oss/plane-go/pkg/base/protocol"
+	"github.com/bigstack-oss/plane-go/pkg/frame/oneway/metric"
 	"github.com/bigstack-oss/plane-go/pkg/frame/oneway/plugin"
+	"github.com/bigstack-oss/plane-go/pkg/frame/oneway/plugin/deadletter"
 	"github.com/bigstack-oss/plane-go/pkg/frame/oneway/plugin/plug"
+	"github.com/bigstack-oss/plane-go/pkg/frame/oneway/plugin/retry"
 )
 
 var (
@@ -19,26 +21,49 @@ type Transit interface {
 	DoTransit()
 }
 
-func WrapWithSingleMsgLoop(ctx context.Context, wg *sync.WaitGroup, group string, coreFunc func([]byte) (protocol.Job, error)) func() {
+func complete(group string) {
+	plugin.CompleteWorker(plugin.Transit, group)
+	plugin.ReleaseTargets(plugin.Transit, group)
+}
+
+// WrapWithSingleMsgLoop returns the loop of a transit worker. the loop can be
+// run by several goroutines when the plugin is configured with concurrency,
+// so coreFunc has to be safe for concurrent use in that case
+func WrapWithSingleMsgLoop(ctx context.Context, wg *sync.WaitGroup, part plugin.Part, coreFunc func([]byte) (protocol.Job, error)) func() {
 	return func() {
 		wg.Add(1)
 		defer wg.Done()
 
+		worker := plugin.NextWorker(plugin.I2T, part.Group)
+		inbound := plugin.GetMsgChan(part.Group, worker)
 		for {
 			select {
 			case <-ctx.Done():
 				return
-			case msg, isChnOpen := <-plugin.I2TChan[group]:
+			case msg, isChnOpen := <-inbound:
 				switch isChnOpen {
 				case true:
-					task, err := coreFunc(msg)
+					var task protocol.Job
+					handle := metric.StartHandle(plugin.Transit, part)
+					err := retry.Do(ctx, plugin.Transit, part.Parter, func() (err error) {
+						task, err = coreFunc(msg)
+						return err
+					})
+					handle.Done(err)
 					if err != nil {
+						if ctx.Err() != nil {
+							return
+						}
+
+						isPassed := deadletter.Send(ctx, part.Group, plugin.Transit, msg, err)
+						plugin.Settle(plugin.I2T, part.Group, worker, isPassed)
 						continue
 					}
 
-					plugin.T2PChan[group] <- task
+					isPassed := plugin.SendJob(ctx, plugin.Transit, part.Group, task)
+					plugin.Settle(plugin.I2T, part.Group, worker, isPassed)
 				case false:
-					close(plugin.T2PChan[group])
+					complete(part.Group)
 					return
 				}
 			}
@@ -46,28 +71,44 @@ func WrapWithSingleMsgLoop(ctx context.Context, wg *sync.WaitGroup, group string
 	}
 }
 
-func WrapWithBatchMsgLoop(ctx context.Context, wg *sync.WaitGroup, group string, coreFunc func([]byte) ([]protocol.Job, error)) func() {
+func WrapWithBatchMsgLoop(ctx context.Context, wg *sync.WaitGroup, part plugin.Part, coreFunc func([]byte) ([]protocol.Job, error)) func() {
 	return func() {
 		wg.Add(1)
 		defer wg.Done()
 
+		worker := plugin.NextWorker(plugin.I2T, part.Group)
+		inbound := plugin.GetMsgChan(part.Group, worker)
 		for {
 			select {
 			case <-ctx.Done():
 				return
-			case msg, isChnOpen := <-plugin.I2TChan[group]:
+			case msg, isChnOpen := <-inbound:
 				switch isChnOpen {
 				case true:
-					tasks, err := coreFunc(msg)
-					if err != nil || len(tasks) == 0 {
+					var tasks []protocol.Job
+					handle := metric.StartHandle(plugin.Transit, part)
+					err := retry.Do(ctx, plugin.Transit, part.Parter, func() (err error) {
+						tasks, err = coreFunc(msg)
+						return err
+					})
+					handle.Done(err)
+					if err != nil {
+						if ctx.Err() != nil {
+							return
+						}
+
+						isPassed := deadletter.Send(ctx, part.Group, plugin.Transit, msg, err)
+						plugin.Settle(plugin.I2T, part.Group, worker, isPassed)
 						continue
 					}
 
+					isPassed := true
 					for _, task := range tasks {
-						plugin.T2PChan[group] <- task
+						isPassed = plugin.SendJob(ctx, plugin.Transit, part.Group, task) && isPassed
 					}
+					plugin.Settle(plugin.I2T, part.Group, worker, isPassed)
 				case false:
-					close(plugin.T2PChan[group])
+					complete(part.Group)
 					return
 				}
 			}
@@ -0,0 +1,74 @@
+package worker
+
+import (
+	"path/filepath"
+	"reflect"
+
+	"github.com/bigstack-oss/plane-go/pkg/base/config"
+	"github.com/bigstack-oss/plane-go/pkg/base/queue"
+	"github.com/bigstack-oss/plane-go/pkg/frame/oneway/plugin"
+	"github.com/goinggo/mapstructure"
+	"gopkg.in/go-playground/validator.v9"
+)
+
+// durableOptions backs the hops consumed by the plugins of the group with
+// segment logs under the path, so that the jobs survive restarts
+type durableOptions struct {
+	Group       string `validate:"required"`
+	Path        string `validate:"required"`
+	SegmentSize int64  `validate:"min=0"`
+	Sync        bool
+}
+
+func loadDurableOptions(cfg config.Configer) (map[string]*durableOptions, error) {
+	durables := make(map[string]*durableOptions)
+	rawConfig := reflect.ValueOf(cfg.Get(plugin.Durable))
+	if !rawConfig.IsValid() {
+		return durables, nil
+	}
+
+	for i := 0; i < rawConfig.Len(); i++ {
+		options := &durableOptions{}
+		_ = mapstructure.Decode(rawConfig.Index(i).Interface(), options)
+		err := validator.New().Struct(options)
+		if err != nil {
+			return nil, err
+		}
+
+		durables[options.Group] = options
+	}
+
+	return durables, nil
+}
+
+// setDurables opens the queues of the durable groups of the parters, one for
+// each node, and returns the names of the plugins which consume from them
+func (o *Onewayer) setDurables(parters map[string]*parterOptions) (map[string]bool, error) {
+	durableParters := make(map[string]bool)
+	nodes, err := getNodes(parters)
+	if err != nil {
+		return nil, err
+	}
+
+	for node, parterNames := range nodes {
+		durable, isDurable := o.durables[node.Group]
+		if !isDurable || node.Stage == plugin.Input {
+			continue
+		}
+
+		hop := plugin.InboundHop(node.Stage)
+		log, err := queue.Open(filepath.Join(durable.Path, hop), durable.SegmentSize, durable.Sync)
+		if err != nil {
+			return nil, err
+		}
+
+		o.logf.Infof("%s of group(%s) is backed by queue: %s, %d jobs left", hop, node.Group, filepath.Join(durable.Path, hop), log.Depth())
+		isOrdered := parters[parterNames[0]].Ordered
+		plugin.SetDurable(o.getGroupContext(node.Group), hop, node.Group, log, getNodeConcurrency(parters, parterNames), isOrdered)
+		for _, parterName := range parterNames {
+			durableParters[parterName] = true
+		}
+	}
+
+	return durableParters, nil
+}
@@ -1,28 +1,41 @@
 package worker
 
 import (
+	"context"
+	"fmt"
 	"os"
 	"reflect"
-	"strconv"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/bigstack-oss/plane-go/pkg/base/config"
 	"github.com/bigstack-oss/plane-go/pkg/base/log"
-	"github.com/bigstack-oss/plane-go/pkg/base/protocol"
+	"github.com/bigstack-oss/plane-go/pkg/frame/oneway/metric"
 	"github.com/bigstack-oss/plane-go/pkg/frame/oneway/plugin"
 	"github.com/bigstack-oss/plane-go/pkg/frame/oneway/plugin/cronjob"
+	"github.com/bigstack-oss/plane-go/pkg/frame/oneway/plugin/deadletter"
 	"github.com/bigstack-oss/plane-go/pkg/frame/oneway/plugin/input"
 	"github.com/bigstack-oss/plane-go/pkg/frame/oneway/plugin/output"
 	"github.com/bigstack-oss/plane-go/pkg/frame/oneway/plugin/plug"
 	"github.com/bigstack-oss/plane-go/pkg/frame/oneway/plugin/process"
+	"github.com/bigstack-oss/plane-go/pkg/frame/oneway/plugin/retry"
 	"github.com/bigstack-oss/plane-go/pkg/frame/oneway/plugin/transit"
+	"github.com/goinggo/mapstructure"
 	"github.com/mohae/deepcopy"
 	"go.uber.org/zap"
+	"gopkg.in/go-playground/validator.v9"
 )
 
 const (
-	name   = "name"
-	module = "onewayer"
+	name     = "name"
+	group    = "group"
+	sink     = "sink"
+	policy   = "retry"
+	chanSize = "channelSize"
+	module   = "onewayer"
+
+	drainInterval = time.Second
 )
 
 var (
@@ -37,191 +50,660 @@ type Onewayer struct {
 	Outputs   []string
 	Crons     []string
 
+	DeadLetters []string
+
+	parters  map[string]*parterOptions
+	durables map[string]*durableOptions
+	letters  map[string]map[string]interface{}
+	crons    map[string]map[string]interface{}
+
+	// the queues and the lanes of a group are stopped with the context of
+	// the group, so that a group can be reloaded alone
+	ctx          context.Context
+	cancel       context.CancelFunc
+	groupCtxs    map[string]context.Context
+	groupCancels map[string]context.CancelFunc
+
+	// the size of the channels of the hops added next
+	chanSize int32
+
 	log  *zap.Logger
 	logf *zap.SugaredLogger
 }
 
+// parterOptions keeps the framework options of a configured plugin
+type parterOptions struct {
+	Name        string `validate:"required"`
+	Stage       string
+	Group       string `validate:"required"`
+	Concurrency int    `validate:"min=0"`
+	Ordered     bool
+	To          []string
+	From        []string
+
+	raw    map[string]interface{}
+	policy *retry.Policy
+	index  int
+}
+
 func InitWorker() Worker {
 	logger := log.GetLogger(module)
 
-	return &Onewayer{
-		log:  logger,
-		logf: logger.Sugar(),
+	o := &Onewayer{
+		parters:  make(map[string]*parterOptions),
+		durables: make(map[string]*durableOptions),
+		letters:  make(map[string]map[string]interface{}),
+		crons:    make(map[string]map[string]interface{}),
+		log:      logger,
+		logf:     logger.Sugar(),
+	}
+
+	o.resetContexts()
+	return o
+}
+
+func (o *Onewayer) resetContexts() {
+	if o.cancel != nil {
+		o.cancel()
+	}
+
+	o.ctx, o.cancel = context.WithCancel(context.Background())
+	o.groupCtxs = make(map[string]context.Context)
+	o.groupCancels = make(map[string]context.CancelFunc)
+}
+
+func (o *Onewayer) getGroupContext(group string) context.Context {
+	ctx, isExisted := o.groupCtxs[group]
+	if !isExisted {
+		ctx, o.groupCancels[group] = context.WithCancel(o.ctx)
+		o.groupCtxs[group] = ctx
+	}
+
+	return ctx
+}
+
+func (o *Onewayer) cancelGroup(group string) {
+	cancel, isExisted := o.groupCancels[group]
+	if isExisted {
+		cancel()
+	}
+
+	delete(o.groupCtxs, group)
+	delete(o.groupCancels, group)
+}
+
+// getParterName names the parter after its plugin and its node, which stays
+// the same across reloads. the plugins configured more than once in the
+// same node are numbered in the order of the conf
+func getParterName(options *parterOptions) string {
+	return strings.Join([]string{options.Stage, options.Name, options.Group}, "-")
+}
+
+func getRetryPolicy(pluginType string, subConfig map[string]interface{}) (*retry.Policy, error) {
+	rawPolicy, hasPolicy := subConfig[policy]
+	if !hasPolicy || pluginType == plugin.Input {
+		return nil, nil
 	}
+
+	retryPolicy := &retry.Policy{}
+	_ = mapstructure.Decode(rawPolicy, retryPolicy)
+	err := validator.New().Struct(retryPolicy)
+	if err != nil {
+		return nil, fmt.Errorf("invalid retry policy: %s", err.Error())
+	}
+
+	return retryPolicy, nil
 }
 
-func (o *Onewayer) getParterConfigs(pluginType string) ([]string, []interface{}) {
-	parterNames := []string{}
-	rawConfigs := configer.Get(pluginType).([]interface{})
+func newParterOptions(pluginType string, subConfig map[string]interface{}) (*parterOptions, error) {
+	options := &parterOptions{raw: subConfig}
+	_ = mapstructure.Decode(subConfig, options)
+	options.Stage = pluginType
+	if options.Concurrency == 0 {
+		options.Concurrency = 1
+	}
+
+	err := validator.New().Struct(options)
+	if err != nil {
+		return nil, err
+	}
+
+	if options.Ordered && (pluginType == plugin.Input || pluginType == plugin.Transit) {
+		return nil, fmt.Errorf("ordered is only supported by %s and %s plugins", plugin.Process, plugin.Output)
+	}
+
+	options.policy, err = getRetryPolicy(pluginType, subConfig)
+	return options, err
+}
+
+// loadParterOptions reads the options of the plugins of the stage from cfg
+func loadParterOptions(cfg config.Configer, pluginType string) (map[string]*parterOptions, error) {
+	parters := make(map[string]*parterOptions)
+	rawConfigs, _ := cfg.Get(pluginType).([]interface{})
 
 	for i, rawConfig := range rawConfigs {
-		subConfig := (rawConfig.(map[string]interface{}))
-		pluginName := subConfig[name].(string)
-		pluginGroup := subConfig["group"].(string)
-		parterName := strings.Join([]string{pluginType, pluginName, strconv.Itoa(i)}, "-")
+		subConfig, _ := rawConfig.(map[string]interface{})
+		options, err := newParterOptions(pluginType, subConfig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to set options of %s plugin #%d. error: %s", pluginType, i, err.Error())
+		}
 
-		switch pluginType {
-		case plugin.Input:
-			plug.Parters[parterName] = deepcopy.Copy(input.Plugin[pluginName]).(input.Input)
-			input.Plugin[parterName] = plug.Parters[parterName].(input.Input)
+		parterName := getParterName(options)
+		for n := 2; parters[parterName] != nil; n++ {
+			parterName = fmt.Sprintf("%s-%d", getParterName(options), n)
+		}
 
-			o.Inputs = append(o.Inputs, parterName)
-			plugin.I2TChan[pluginGroup] = make(chan []byte, plugin.ChanSize)
+		options.index = i
+		parters[parterName] = options
+	}
 
-		case plugin.Transit:
-			plug.Parters[parterName] = deepcopy.Copy(transit.Plugin[pluginName]).(transit.Transit)
-			transit.Plugin[parterName] = plug.Parters[parterName].(transit.Transit)
+	return parters, nil
+}
 
-			o.Transits = append(o.Transits, parterName)
-			plugin.T2PChan[pluginGroup] = make(chan protocol.Job, plugin.ChanSize)
+func getTemplate(pluginType string, pluginName string) (plug.Parter, bool) {
+	switch pluginType {
+	case plugin.Input:
+		template, isExisted := input.Plugin[pluginName]
+		return template, isExisted
+	case plugin.Transit:
+		template, isExisted := transit.Plugin[pluginName]
+		return template, isExisted
+	case plugin.Process:
+		template, isExisted := process.Plugin[pluginName]
+		return template, isExisted
+	case plugin.Output:
+		template, isExisted := output.Plugin[pluginName]
+		return template, isExisted
+	default:
+		return nil, false
+	}
+}
 
-		case plugin.Process:
-			plug.Parters[parterName] = deepcopy.Copy(process.Plugin[pluginName]).(process.Process)
-			process.Plugin[parterName] = plug.Parters[parterName].(process.Process)
+// newParter copies the registered plugin and sets the copy with its config
+// and its parter name, the targets picked by the plugin itself are added to
+// its `to` option
+func newParter(parterName string, options *parterOptions) (plug.Parter, error) {
+	template, isExisted := getTemplate(options.Stage, options.Name)
+	if !isExisted {
+		return nil, fmt.Errorf("%s plugin(%s) was not defined", options.Stage, options.Name)
+	}
+
+	conf := map[string]interface{}{}
+	for key, value := range options.raw {
+		conf[key] = value
+	}
+	conf[plugin.ParterKey] = parterName
 
-			o.Processes = append(o.Processes, parterName)
-			plugin.P2OChan[pluginGroup] = make(chan protocol.Job, plugin.ChanSize)
+	parter := deepcopy.Copy(template).(plug.Parter)
+	parter.SetConfig(conf)
+	err := parter.CheckConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to set plugin: %s. error: %s", parterName, err.Error())
+	}
+
+	targeter, isTargeter := parter.(plug.Targeter)
+	if isTargeter {
+		options.To = append(options.To, targeter.GetTargets()...)
+	}
 
-		case plugin.Output:
-			plug.Parters[parterName] = deepcopy.Copy(output.Plugin[pluginName]).(output.Output)
-			output.Plugin[parterName] = plug.Parters[parterName].(output.Output)
+	return parter, nil
+}
+
+func (o *Onewayer) addParter(parterName string, options *parterOptions, parter plug.Parter) {
+	plug.Parters[parterName] = parter
+
+	switch options.Stage {
+	case plugin.Input:
+		input.Plugin[parterName] = parter.(input.Input)
+		o.Inputs = append(o.Inputs, parterName)
+	case plugin.Transit:
+		transit.Plugin[parterName] = parter.(transit.Transit)
+		o.Transits = append(o.Transits, parterName)
+		plugin.AddHop(plugin.I2T, options.Group, o.chanSize)
+	case plugin.Process:
+		process.Plugin[parterName] = parter.(process.Process)
+		o.Processes = append(o.Processes, parterName)
+		plugin.AddHop(plugin.T2P, options.Group, o.chanSize)
+	case plugin.Output:
+		output.Plugin[parterName] = parter.(output.Output)
+		o.Outputs = append(o.Outputs, parterName)
+		plugin.AddHop(plugin.P2O, options.Group, o.chanSize)
+	}
+
+	o.parters[parterName] = options
+	retry.SetPolicy(parterName, options.policy)
+	metric.SetParter(parterName, plugin.Node{Stage: options.Stage, Group: options.Group}, options.Name, options.index)
+}
 
-			o.Outputs = append(o.Outputs, parterName)
+func removeName(names []string, target string) []string {
+	remaining := []string{}
+	for _, name := range names {
+		if name != target {
+			remaining = append(remaining, name)
 		}
+	}
+
+	return remaining
+}
+
+func (o *Onewayer) removeParter(parterName string) {
+	options, isExisted := o.parters[parterName]
+	if !isExisted {
+		return
+	}
 
-		parterNames = append(parterNames, parterName)
+	delete(plug.Parters, parterName)
+
+	switch options.Stage {
+	case plugin.Input:
+		delete(input.Plugin, parterName)
+		o.Inputs = removeName(o.Inputs, parterName)
+	case plugin.Transit:
+		delete(transit.Plugin, parterName)
+		o.Transits = removeName(o.Transits, parterName)
+	case plugin.Process:
+		delete(process.Plugin, parterName)
+		o.Processes = removeName(o.Processes, parterName)
+	case plugin.Output:
+		delete(output.Plugin, parterName)
+		o.Outputs = removeName(o.Outputs, parterName)
 	}
 
-	return parterNames, rawConfigs
+	delete(o.parters, parterName)
+	retry.SetPolicy(parterName, nil)
+	metric.RemoveParter(parterName)
 }
 
-func (o *Onewayer) setParterConfig(parterNames []string, parterConfigs []interface{}) {
-	for i, parterName := range parterNames {
-		plug.Parters[parterName].SetConfig(parterConfigs[i])
-		err := plug.Parters[parterName].CheckConfig()
+func (o *Onewayer) SetParter(pluginType string) {
+	o.chanSize = configer.GetInt32(chanSize)
+	for _, parterName := range o.getParterNames(pluginType) {
+		o.removeParter(parterName)
+	}
+
+	parters, err := loadParterOptions(configer, pluginType)
+	if err != nil {
+		o.logf.Errorf("failed to load %s plugins. error: %s", pluginType, err.Error())
+		osExit(1)
+		return
+	}
+
+	for _, parterName := range getStageParters(parters, pluginType) {
+		parter, err := newParter(parterName, parters[parterName])
 		if err != nil {
-			o.logf.Errorf("failed to set plugin: %s", parterName)
 			o.logf.Errorf("error details: %s", err.Error())
 			osExit(1)
+			return
 		}
+
+		o.addParter(parterName, parters[parterName], parter)
 	}
 }
 
-func (o *Onewayer) SetParter(pluginType string) {
-	parterNames, parterConfigs := o.getParterConfigs(pluginType)
-	o.setParterConfig(parterNames, parterConfigs)
+func (o *Onewayer) getParterNames(pluginType string) []string {
+	switch pluginType {
+	case plugin.Input:
+		return o.Inputs
+	case plugin.Transit:
+		return o.Transits
+	case plugin.Process:
+		return o.Processes
+	case plugin.Output:
+		return o.Outputs
+	default:
+		return nil
+	}
+}
+
+// setHops connects the nodes, so that the channel of a hop is closed only
+// after all of its producer workers are done, backs the hops of the durable
+// groups with queues, and splits the hops consumed by ordered plugins into lanes
+func (o *Onewayer) setHops(parters map[string]*parterOptions) error {
+	err := o.connectParters(parters)
+	if err != nil {
+		return fmt.Errorf("failed to build the pipeline topology. error: %s", err.Error())
+	}
+
+	durableParters, err := o.setDurables(parters)
+	if err != nil {
+		return fmt.Errorf("failed to open the durable queues. error: %s", err.Error())
+	}
+
+	nodes, _ := getNodes(parters)
+	for node, parterNames := range nodes {
+		concurrency := getNodeConcurrency(parters, parterNames)
+		if !parters[parterNames[0]].Ordered || concurrency == 1 || durableParters[parterNames[0]] {
+			continue
+		}
+
+		plugin.SetLanes(o.getGroupContext(node.Group), plugin.InboundHop(node.Stage), node.Group, concurrency)
+	}
+
+	return nil
+}
+
+func (o *Onewayer) startWorkers(pluginType string, parterName string, doFunc func()) {
+	concurrency := o.parters[parterName].Concurrency
+	o.logf.Infof("start %s plugin(%s) with %d workers", pluginType, parterName, concurrency)
+
+	for i := 0; i < concurrency; i++ {
+		go doFunc()
+	}
+}
+
+func getDoFunc(pluginType string, parterName string) func() {
+	switch pluginType {
+	case plugin.Input:
+		return input.Plugin[parterName].DoInput
+	case plugin.Transit:
+		return transit.Plugin[parterName].DoTransit
+	case plugin.Process:
+		return process.Plugin[parterName].DoProcess
+	default:
+		return output.Plugin[parterName].DoOutput
+	}
+}
+
+func (o *Onewayer) startParters(parters map[string]*parterOptions) {
+	for _, stage := range stages {
+		for _, parterName := range getStageParters(parters, stage) {
+			o.startWorkers(stage, parterName, getDoFunc(stage, parterName))
+		}
+	}
 }
 
 func (o *Onewayer) StartParters() {
-	for _, name := range o.Inputs {
-		o.logf.Infof("start input plugin(%s)", name)
-		go input.Plugin[name].DoInput()
+	plugin.ResetHops()
+	o.resetContexts()
+
+	durables, err := loadDurableOptions(configer)
+	if err != nil {
+		o.logf.Errorf("failed to load the durable options. error: %s", err.Error())
+		osExit(1)
+		return
 	}
 
-	for _, name := range o.Transits {
-		o.logf.Infof("start transit plugin(%s)", name)
-		go transit.Plugin[name].DoTransit()
+	o.durables = durables
+	err = o.setHops(o.parters)
+	if err != nil {
+		o.logf.Errorf(err.Error())
+		osExit(1)
+		return
 	}
 
-	for _, name := range o.Processes {
-		o.logf.Infof("start process plugin(%s)", name)
-		go process.Plugin[name].DoProcess()
+	o.startParters(o.parters)
+}
+
+func stopParter(pluginType string, parterName string) {
+	switch pluginType {
+	case plugin.Input:
+		input.Plugin[parterName].Stop()
+	case plugin.Transit:
+		transit.Plugin[parterName].Stop()
+	case plugin.Process:
+		process.Plugin[parterName].Stop()
+	case plugin.Output:
+		output.Plugin[parterName].Stop()
 	}
+}
 
-	for _, name := range o.Outputs {
-		o.logf.Infof("start output plugin(%s)", name)
-		go output.Plugin[name].DoOutput()
+func (o *Onewayer) stopParters(pluginType string) {
+	for _, name := range o.getParterNames(pluginType) {
+		stopParter(pluginType, name)
+		o.logf.Infof("stop %s plugin(%s)", pluginType, name)
 	}
 }
 
 func (o *Onewayer) StopParters() {
-	for _, name := range o.Inputs {
-		input.Plugin[name].Stop()
-		o.logf.Infof("stop input plugin(%s)", name)
+	if o.cancel != nil {
+		o.cancel()
+	}
+
+	for _, stage := range stages {
+		o.stopParters(stage)
+	}
+
+	plugin.CloseQueues()
+}
+
+// isDrained tells whether all the workers of the nodes which have upstream
+// workers are done, the others are never closed by their upstream. only the
+// nodes of the groups are checked if the groups are given
+func (o *Onewayer) isDrained(groups map[string]bool) bool {
+	nodes, _ := getNodes(o.parters)
+	for node, parterNames := range nodes {
+		if groups != nil && !groups[node.Group] {
+			continue
+		}
+		if node.Stage == plugin.Input || !plugin.HasProducers(plugin.InboundHop(node.Stage), node.Group) {
+			continue
+		}
+
+		if plugin.CountDone(node.Stage, node.Group) < int64(getNodeConcurrency(o.parters, parterNames)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (o *Onewayer) logRemaining(msg string) {
+	remaining := plugin.CountRemaining()
+	hops := make([]string, 0, len(remaining))
+	for hop, count := range remaining {
+		hops = append(hops, fmt.Sprintf("%s=%d", hop, count))
 	}
 
-	for _, name := range o.Transits {
-		transit.Plugin[name].Stop()
-		o.logf.Infof("stop transit plugin(%s)", name)
+	sort.Strings(hops)
+	o.logf.Infof("%s, remaining messages: %s", msg, strings.Join(hops, " "))
+}
+
+func (o *Onewayer) waitDrained(timeout time.Duration, groups map[string]bool) bool {
+	ticker := time.NewTicker(drainInterval)
+	defer ticker.Stop()
+	deadline := time.After(timeout)
+
+	for !o.isDrained(groups) {
+		select {
+		case <-ticker.C:
+			o.logRemaining("draining pipeline")
+		case <-deadline:
+			return false
+		}
 	}
 
-	for _, name := range o.Processes {
-		process.Plugin[name].Stop()
-		o.logf.Infof("stop process plugin(%s)", name)
+	return true
+}
+
+// DrainParters stops the input plugins first, and lets the other plugins
+// consume until their upstream hops are closed and empty. the plugins are
+// stopped anyway once the timeout is reached
+func (o *Onewayer) DrainParters(timeout time.Duration) {
+	o.stopParters(plugin.Input)
+	if o.waitDrained(timeout, nil) {
+		o.log.Info("pipeline has been drained")
+	} else {
+		o.logRemaining(fmt.Sprintf("failed to drain pipeline in %s", timeout))
 	}
 
-	for _, name := range o.Outputs {
-		output.Plugin[name].Stop()
-		o.logf.Infof("stop output plugin(%s)", name)
+	if o.cancel != nil {
+		o.cancel()
 	}
+
+	for _, stage := range stages[1:] {
+		o.stopParters(stage)
+	}
+
+	plugin.CloseQueues()
 }
 
-func (o *Onewayer) getCronnerConfig(pluginType string) map[string]map[string]interface{} {
+func loadCronnerConfigs(cfg config.Configer, pluginType string) map[string]map[string]interface{} {
 	cronConfigs := make(map[string]map[string]interface{})
-	rawConfig := reflect.ValueOf(configer.Get(pluginType))
+	rawConfig := reflect.ValueOf(cfg.Get(pluginType))
 	if !rawConfig.IsValid() {
-		return map[string]map[string]interface{}{}
+		return cronConfigs
 	}
 
 	for i := 0; i < rawConfig.Len(); i++ {
 		pluginConfig := rawConfig.Index(i).Interface().(map[string]interface{})
-		pluginName := pluginConfig[name].(string)
-		cronName := strings.Join([]string{pluginType, pluginName}, "-")
-
-		cronConfigs[pluginName] = make(map[string]interface{})
+		pluginName, _ := pluginConfig[name].(string)
 		cronConfigs[pluginName] = pluginConfig
-		plug.Cronners[cronName] = cronjob.Plugin[pluginName]
 	}
 
 	return cronConfigs
 }
 
-func (o *Onewayer) setCronnerConfig(pluginType string, cronConfigs map[string]map[string]interface{}) {
-	var cronName string
-	defer func() {
-		if err := recover(); err != nil {
-			o.logf.Errorf("cronjob plugin(%s) was not defined", cronName)
-			osExit(1)
-		}
-	}()
+// checkCronner sets a copy of the cronjob plugin with the config, so that
+// the running one is not touched when the config is invalid
+func checkCronner(cronName string, cronConfig map[string]interface{}) error {
+	cronner, isExisted := cronjob.Plugin[cronName]
+	if !isExisted {
+		return fmt.Errorf("cronjob plugin(%s) was not defined", cronName)
+	}
+
+	copied := deepcopy.Copy(cronner).(cronjob.Cronjob)
+	copied.SetConfig(cronConfig)
+	err := copied.CheckConfig()
+	if err != nil {
+		return fmt.Errorf("failed to set cronjob plugin(%s). error: %s", cronName, err.Error())
+	}
+
+	return nil
+}
 
-	for cronName, cronConfig := range cronConfigs {
-		cronner := strings.Join([]string{pluginType, cronName}, "-")
-		plug.Cronners[cronner].SetConfig(cronConfig)
-		err := plug.Cronners[cronner].CheckConfig()
+func setCronner(pluginType string, cronName string, cronConfig map[string]interface{}) error {
+	cronner, isExisted := cronjob.Plugin[cronName]
+	if !isExisted {
+		return fmt.Errorf("cronjob plugin(%s) was not defined", cronName)
+	}
+
+	cronner.SetConfig(cronConfig)
+	err := cronner.CheckConfig()
+	if err != nil {
+		return fmt.Errorf("failed to set cronjob plugin(%s). error: %s", cronName, err.Error())
+	}
+
+	plug.Cronners[strings.Join([]string{pluginType, cronName}, "-")] = cronner
+	return nil
+}
+
+func (o *Onewayer) SetCronner(pluginType string) {
+	o.crons = loadCronnerConfigs(configer, pluginType)
+	o.Crons = getSortedKeys(o.crons)
+
+	for _, cronName := range o.Crons {
+		err := setCronner(pluginType, cronName, o.crons[cronName])
 		if err != nil {
-			o.logf.Errorf("failed to set cronjob plugin(%s). error: %s", cronName, err.Error())
+			o.logf.Errorf(err.Error())
 			osExit(1)
+			return
 		}
-
-		o.Crons = append(o.Crons, cronName)
 	}
 }
 
-func (o *Onewayer) SetCronner(pluginType string) {
-	cronConfigs := o.getCronnerConfig(pluginType)
-	o.setCronnerConfig(pluginType, cronConfigs)
+func (o *Onewayer) startCronner(cronName string) {
+	o.logf.Infof("start cronjob plugin(%s)", cronName)
+	go cronjob.Plugin[cronName].DoSchedule()
+}
+
+func (o *Onewayer) stopCronner(cronName string) {
+	cronjob.Plugin[cronName].Stop()
+	o.logf.Infof("stop cronjob plugin(%s)", cronName)
 }
 
 func (o *Onewayer) StartCronners() {
 	for _, cron := range o.Crons {
-		o.logf.Infof("start cronjob plugin(%s)", cron)
-		go cronjob.Plugin[cron].DoSchedule()
+		o.startCronner(cron)
 	}
 }
 
 func (o *Onewayer) StopCronners() {
 	for _, cron := range o.Crons {
-		cronjob.Plugin[cron].Stop()
-		o.logf.Infof("stop cronjob plugin(%s)", cron)
+		o.stopCronner(cron)
 	}
 }
 
+func loadDeadLetterConfigs(cfg config.Configer, pluginType string) map[string]map[string]interface{} {
+	letterConfigs := make(map[string]map[string]interface{})
+	rawConfig := reflect.ValueOf(cfg.Get(pluginType))
+	if !rawConfig.IsValid() {
+		return letterConfigs
+	}
+
+	for i := 0; i < rawConfig.Len(); i++ {
+		letterConfig := rawConfig.Index(i).Interface().(map[string]interface{})
+		letterGroup, _ := letterConfig[group].(string)
+		letterConfigs[letterGroup] = letterConfig
+	}
+
+	return letterConfigs
+}
+
+func hasOutput(parters map[string]*parterOptions, group string) bool {
+	for _, options := range parters {
+		if options.Stage == plugin.Output && options.Group == group {
+			return true
+		}
+	}
+
+	return false
+}
+
+// newSink copies the registered sink and sets the copy with its config, the
+// sinks handing the letters over to another group are checked against the
+// given plugins, which may not be running yet
+func newSink(letterGroup string, letterConfig map[string]interface{}, parters map[string]*parterOptions) (deadletter.Sink, error) {
+	sinkName, _ := letterConfig[sink].(string)
+	sinkPlugin, isExisted := deadletter.Plugin[sinkName]
+	if !isExisted {
+		return nil, fmt.Errorf("dead letter sink(%s) of group(%s) was not defined", sinkName, letterGroup)
+	}
+
+	letterSink := deepcopy.Copy(sinkPlugin).(deadletter.Sink)
+	letterSink.SetConfig(letterConfig)
+	err := letterSink.CheckConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to set dead letter sink of group(%s). error: %s", letterGroup, err.Error())
+	}
+
+	targeter, isTargeter := letterSink.(deadletter.Targeter)
+	if isTargeter && !hasOutput(parters, targeter.GetTargetGroup()) {
+		return nil, fmt.Errorf("failed to set dead letter sink of group(%s). error: no output plugin was found in group(%s)", letterGroup, targeter.GetTargetGroup())
+	}
+
+	return letterSink, nil
+}
+
+func (o *Onewayer) SetDeadLetter(pluginType string) {
+	o.letters = loadDeadLetterConfigs(configer, pluginType)
+	o.DeadLetters = getSortedKeys(o.letters)
+
+	for _, letterGroup := range o.DeadLetters {
+		letterSink, err := newSink(letterGroup, o.letters[letterGroup], o.parters)
+		if err != nil {
+			o.logf.Errorf(err.Error())
+			osExit(1)
+			return
+		}
+
+		deadletter.SetSink(letterGroup, letterSink)
+	}
+}
+
+func (o *Onewayer) StopDeadLetters() {
+	for _, letterGroup := range o.DeadLetters {
+		deadletter.RemoveSink(letterGroup)
+		o.logf.Infof("stop dead letter sink of group(%s)", letterGroup)
+	}
+}
+
+// GetStatus tells whether all the workers of every node are done
 func (o *Onewayer) GetStatus() bool {
-	isInputDone := *plugin.InputDone == int64(len(o.Inputs))
-	isTransitDone := *plugin.TransitDone == int64(len(o.Transits))
-	isProcessDone := *plugin.ProcessDone == int64(len(o.Processes))
-	isOutputDone := *plugin.OutputDone == int64(len(o.Outputs))
+	nodes, _ := getNodes(o.parters)
+	for node, parterNames := range nodes {
+		if plugin.CountDone(node.Stage, node.Group) != int64(getNodeConcurrency(o.parters, parterNames)) {
+			return false
+		}
+	}
 
-	return isInputDone && isTransitDone && isProcessDone && isOutputDone
+	return true
 }
@@ -0,0 +1,368 @@
+package worker
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/bigstack-oss/plane-go/pkg/base/config"
+	"github.com/bigstack-oss/plane-go/pkg/frame/oneway/plugin"
+	"github.com/bigstack-oss/plane-go/pkg/frame/oneway/plugin/deadletter"
+	"github.com/bigstack-oss/plane-go/pkg/frame/oneway/plugin/plug"
+)
+
+func getSortedKeys[T any](items map[string]T) []string {
+	keys := make([]string, 0, len(items))
+	for key := range items {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+	return keys
+}
+
+// getChangedKeys returns the keys which are added, removed or changed
+func getChangedKeys[T any](running map[string]T, loaded map[string]T) map[string]bool {
+	changed := make(map[string]bool)
+	for key, value := range loaded {
+		runningValue, isExisted := running[key]
+		if !isExisted || !reflect.DeepEqual(runningValue, value) {
+			changed[key] = true
+		}
+	}
+
+	for key := range running {
+		if _, isExisted := loaded[key]; !isExisted {
+			changed[key] = true
+		}
+	}
+
+	return changed
+}
+
+func getRawConfigs(parters map[string]*parterOptions) map[string]map[string]interface{} {
+	raws := make(map[string]map[string]interface{})
+	for parterName, options := range parters {
+		raws[parterName] = options.raw
+	}
+
+	return raws
+}
+
+func loadParters(cfg config.Configer) (map[string]*parterOptions, error) {
+	parters := make(map[string]*parterOptions)
+	for _, stage := range stages {
+		stageParters, err := loadParterOptions(cfg, stage)
+		if err != nil {
+			return nil, err
+		}
+
+		for parterName, options := range stageParters {
+			parters[parterName] = options
+		}
+	}
+
+	return parters, nil
+}
+
+// getChangedGroups returns the groups whose plugins or durable queues are
+// added, removed or changed
+func (o *Onewayer) getChangedGroups(parters map[string]*parterOptions, durables map[string]*durableOptions) map[string]bool {
+	changed := make(map[string]bool)
+	for parterName := range getChangedKeys(getRawConfigs(o.parters), getRawConfigs(parters)) {
+		if options, isExisted := o.parters[parterName]; isExisted {
+			changed[options.Group] = true
+		}
+		if options, isExisted := parters[parterName]; isExisted {
+			changed[options.Group] = true
+		}
+	}
+
+	for group := range getChangedKeys(o.durables, durables) {
+		changed[group] = true
+	}
+
+	return changed
+}
+
+// newParters creates the plugins of the changed groups and the groups
+// connected to them, and returns the affected groups with their new options
+// and plugins. nothing is returned if the new topology is invalid
+func (o *Onewayer) newParters(parters map[string]*parterOptions, changed map[string]bool) (map[string]bool, map[string]*parterOptions, map[string]plug.Parter, error) {
+	instances := make(map[string]plug.Parter)
+	merged := make(map[string]*parterOptions)
+	for parterName, options := range o.parters {
+		if !changed[options.Group] {
+			merged[parterName] = options
+		}
+	}
+
+	for parterName, options := range parters {
+		if !changed[options.Group] {
+			continue
+		}
+
+		parter, err := newParter(parterName, options)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		instances[parterName] = parter
+		merged[parterName] = options
+	}
+
+	nodes, _ := getNodes(o.parters)
+	runningEdges, _, _ := getEdges(nodes, o.parters)
+	edges, _, err := o.getTopology(merged)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// the unchanged groups connected to the changed ones are restarted with
+	// them, since the channels of their hops are recreated
+	affected := getAffectedGroups(changed, runningEdges, edges)
+	installing := make(map[string]*parterOptions)
+	for parterName, options := range parters {
+		if !affected[options.Group] {
+			continue
+		}
+
+		installing[parterName] = options
+		if instances[parterName] != nil {
+			continue
+		}
+
+		instances[parterName], err = newParter(parterName, options)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	return affected, installing, instances, nil
+}
+
+func newSinks(letters map[string]map[string]interface{}, changed map[string]bool, parters map[string]*parterOptions) (map[string]deadletter.Sink, error) {
+	sinks := make(map[string]deadletter.Sink)
+	for letterGroup := range changed {
+		letterConfig, isExisted := letters[letterGroup]
+		if !isExisted {
+			continue
+		}
+
+		letterSink, err := newSink(letterGroup, letterConfig, parters)
+		if err != nil {
+			for _, created := range sinks {
+				created.Stop()
+			}
+
+			return nil, err
+		}
+
+		sinks[letterGroup] = letterSink
+	}
+
+	return sinks, nil
+}
+
+// drainGroups stops the input plugins of the groups, and lets the other
+// plugins of the groups which are not durable consume until their upstream
+// hops are closed and empty, the jobs of the durable groups are kept by their
+// queues. the groups are left as they are once the timeout is reached
+func (o *Onewayer) drainGroups(groups map[string]bool, timeout time.Duration) {
+	for _, parterName := range o.getParterNames(plugin.Input) {
+		if !groups[o.parters[parterName].Group] {
+			continue
+		}
+
+		stopParter(plugin.Input, parterName)
+		o.logf.Infof("stop %s plugin(%s)", plugin.Input, parterName)
+		o.removeParter(parterName)
+	}
+
+	draining := make(map[string]bool)
+	for group := range groups {
+		if _, isDurable := o.durables[group]; !isDurable {
+			draining[group] = true
+		}
+	}
+
+	if len(draining) == 0 || timeout <= 0 {
+		return
+	}
+
+	if !o.waitDrained(timeout, draining) {
+		o.logRemaining(fmt.Sprintf("failed to drain groups %v in %s", getSortedKeys(draining), timeout))
+	}
+}
+
+// removeGroups stops the plugins of the groups from the input stage on, and
+// drops their hops, queues and lanes
+func (o *Onewayer) removeGroups(groups map[string]bool) {
+	for _, stage := range stages {
+		for _, parterName := range o.getParterNames(stage) {
+			if !groups[o.parters[parterName].Group] {
+				continue
+			}
+
+			stopParter(stage, parterName)
+			o.logf.Infof("stop %s plugin(%s)", stage, parterName)
+			o.removeParter(parterName)
+		}
+	}
+
+	for group := range groups {
+		o.cancelGroup(group)
+		plugin.RemoveGroup(group)
+	}
+}
+
+// installGroups adds the parters with the hops of their groups, and starts
+// them once the hops are set
+func (o *Onewayer) installGroups(parters map[string]*parterOptions, instances map[string]plug.Parter) error {
+	for _, stage := range stages {
+		for _, parterName := range getStageParters(parters, stage) {
+			o.addParter(parterName, parters[parterName], instances[parterName])
+		}
+	}
+
+	err := o.setHops(parters)
+	if err != nil {
+		return err
+	}
+
+	o.startParters(parters)
+	return nil
+}
+
+// restoreGroups installs the parters which were running before a reload
+// failed to install the new ones
+func (o *Onewayer) restoreGroups(parters map[string]*parterOptions) error {
+	instances := make(map[string]plug.Parter)
+	for parterName, options := range parters {
+		parter, err := newParter(parterName, options)
+		if err != nil {
+			return err
+		}
+
+		instances[parterName] = parter
+	}
+
+	return o.installGroups(parters, instances)
+}
+
+func (o *Onewayer) reloadDeadLetters(letters map[string]map[string]interface{}, changed map[string]bool, sinks map[string]deadletter.Sink) {
+	for letterGroup := range changed {
+		letterSink, isExisted := sinks[letterGroup]
+		if !isExisted {
+			deadletter.RemoveSink(letterGroup)
+			o.logf.Infof("stop dead letter sink of group(%s)", letterGroup)
+			continue
+		}
+
+		deadletter.SetSink(letterGroup, letterSink)
+		o.logf.Infof("set dead letter sink of group(%s)", letterGroup)
+	}
+
+	o.letters = letters
+	o.DeadLetters = getSortedKeys(letters)
+}
+
+func (o *Onewayer) reloadCronners(crons map[string]map[string]interface{}, changed map[string]bool) error {
+	errs := []error{}
+	for cronName := range changed {
+		if _, isRunning := o.crons[cronName]; isRunning {
+			o.stopCronner(cronName)
+		}
+
+		cronConfig, isExisted := crons[cronName]
+		if !isExisted {
+			continue
+		}
+
+		err := setCronner(plugin.CronJob, cronName, cronConfig)
+		if err != nil {
+			errs = append(errs, err)
+			delete(crons, cronName)
+			continue
+		}
+
+		o.startCronner(cronName)
+	}
+
+	o.crons = crons
+	o.Crons = getSortedKeys(crons)
+	return errors.Join(errs...)
+}
+
+// Reload applies the conf to the running pipeline. the groups whose plugins
+// or durable queues changed are restarted together with the groups connected
+// to them, while the other groups keep running. the restarted groups are
+// drained until the timeout first, and they are restored with the previous
+// plugins if the new ones failed to be installed. nothing is touched if the
+// conf is invalid
+func (o *Onewayer) Reload(cfg config.Configer, timeout time.Duration) error {
+	parters, err := loadParters(cfg)
+	if err != nil {
+		return err
+	}
+
+	durables, err := loadDurableOptions(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to load the durable options. error: %s", err.Error())
+	}
+
+	crons := loadCronnerConfigs(cfg, plugin.CronJob)
+	changedCrons := getChangedKeys(o.crons, crons)
+	for cronName := range changedCrons {
+		if cronConfig, isExisted := crons[cronName]; isExisted {
+			err = checkCronner(cronName, cronConfig)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	changed := o.getChangedGroups(parters, durables)
+	affected, installing, instances, err := o.newParters(parters, changed)
+	if err != nil {
+		return err
+	}
+
+	letters := loadDeadLetterConfigs(cfg, plugin.DeadLetter)
+	changedLetters := getChangedKeys(o.letters, letters)
+	sinks, err := newSinks(letters, changedLetters, parters)
+	if err != nil {
+		return err
+	}
+
+	running := make(map[string]*parterOptions)
+	for parterName, options := range o.parters {
+		if affected[options.Group] {
+			running[parterName] = options
+		}
+	}
+
+	o.logf.Infof("reloading groups: %v", getSortedKeys(affected))
+	o.drainGroups(affected, timeout)
+	o.removeGroups(affected)
+
+	errs := []error{}
+	runningDurables, runningSize := o.durables, o.chanSize
+	o.durables, o.chanSize = durables, cfg.GetInt32(chanSize)
+	err = o.installGroups(installing, instances)
+	if err != nil {
+		o.removeGroups(affected)
+		o.durables, o.chanSize = runningDurables, runningSize
+		errs = append(errs, fmt.Errorf("failed to reload groups %v, restoring the previous plugins. error: %w", getSortedKeys(affected), err))
+		err = o.restoreGroups(running)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to restore groups %v. error: %w", getSortedKeys(affected), err))
+		}
+	}
+
+	o.reloadDeadLetters(letters, changedLetters, sinks)
+	errs = append(errs, o.reloadCronners(crons, changedCrons))
+	return errors.Join(errs...)
+}
@@ -0,0 +1,125 @@
+package worker
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bigstack-oss/plane-go/pkg/base/config"
+	"github.com/bigstack-oss/plane-go/pkg/frame/oneway/plugin"
+	"github.com/bigstack-oss/plane-go/pkg/frame/oneway/plugin/process"
+	"github.com/goinggo/mapstructure"
+	"github.com/stretchr/testify/assert"
+)
+
+func newRawOptions(stage string, pluginName string, pluginGroup string, raw map[string]interface{}) *parterOptions {
+	raw[name] = pluginName
+	raw[group] = pluginGroup
+	return &parterOptions{Name: pluginName, Stage: stage, Group: pluginGroup, Concurrency: 1, raw: raw}
+}
+
+func TestGetChangedGroups(t *testing.T) {
+	o := InitWorker().(*Onewayer)
+	for _, options := range []*parterOptions{
+		newRawOptions(plugin.Input, "in", "1", map[string]interface{}{"interval": 1}),
+		newRawOptions(plugin.Output, "out", "1", map[string]interface{}{}),
+		newRawOptions(plugin.Input, "in", "2", map[string]interface{}{}),
+		newRawOptions(plugin.Output, "out", "2", map[string]interface{}{}),
+		newRawOptions(plugin.Output, "out", "3", map[string]interface{}{}),
+	} {
+		o.parters[getParterName(options)] = options
+	}
+
+	parters := make(map[string]*parterOptions)
+	for _, options := range []*parterOptions{
+		newRawOptions(plugin.Input, "in", "1", map[string]interface{}{"interval": 2}),
+		newRawOptions(plugin.Output, "out", "1", map[string]interface{}{}),
+		newRawOptions(plugin.Input, "in", "2", map[string]interface{}{}),
+		newRawOptions(plugin.Output, "out", "2", map[string]interface{}{}),
+		newRawOptions(plugin.Output, "out", "4", map[string]interface{}{}),
+	} {
+		parters[getParterName(options)] = options
+	}
+
+	durables := map[string]*durableOptions{"2": {Group: "2", Path: "/tmp/2"}}
+	changed := o.getChangedGroups(parters, durables)
+	assert.Equal(t, map[string]bool{"1": true, "2": true, "3": true, "4": true}, changed, "failed to detect changed groups")
+
+	o.durables = durables
+	changed = o.getChangedGroups(parters, durables)
+	assert.Equal(t, map[string]bool{"1": true, "3": true, "4": true}, changed, "failed to skip the unchanged group")
+}
+
+func TestGetAffectedGroups(t *testing.T) {
+	running := map[plugin.Node][]plugin.Node{
+		{Stage: plugin.Input, Group: "1"}:   {{Stage: plugin.Transit, Group: "a"}},
+		{Stage: plugin.Transit, Group: "a"}: {{Stage: plugin.Output, Group: "a"}},
+		{Stage: plugin.Input, Group: "2"}:   {{Stage: plugin.Output, Group: "2"}},
+	}
+	loaded := map[plugin.Node][]plugin.Node{
+		{Stage: plugin.Input, Group: "1"}:   {{Stage: plugin.Transit, Group: "b"}},
+		{Stage: plugin.Transit, Group: "b"}: {{Stage: plugin.Output, Group: "b"}},
+		{Stage: plugin.Input, Group: "2"}:   {{Stage: plugin.Output, Group: "2"}},
+	}
+
+	affected := getAffectedGroups(map[string]bool{"b": true}, running, loaded)
+	assert.Equal(t, map[string]bool{"1": true, "a": true, "b": true}, affected, "failed to restart the connected groups")
+}
+
+func TestLoadParterOptions(t *testing.T) {
+	cfg := config.NewConfiger()
+	cfg.SetConfigType("yaml")
+	err := cfg.ReadConfig(strings.NewReader(`
+output:
+  - name: "out"
+    group: "1"
+  - name: "out"
+    group: "1"
+    concurrency: 2
+`))
+	assert.Equal(t, nil, err, "failed to read conf")
+
+	parters, err := loadParterOptions(cfg, plugin.Output)
+	assert.Equal(t, nil, err, "failed to load the plugin configured twice in a node")
+	assert.Equal(t, 1, parters["output-out-1"].Concurrency, "failed to keep the name of the first plugin")
+	assert.Equal(t, 2, parters["output-out-1-2"].Concurrency, "failed to number the second plugin")
+}
+
+type partProcess struct {
+	plugin.Part `mapstructure:",squash"`
+}
+
+func (p *partProcess) SetConfig(conf interface{}) {
+	_ = mapstructure.Decode(conf, p)
+}
+
+func (p *partProcess) CheckConfig() error {
+	return nil
+}
+
+func (p *partProcess) Stop() {}
+
+func (p *partProcess) DoProcess() {}
+
+func TestNewParter(t *testing.T) {
+	process.Plugin["part"] = &partProcess{}
+	defer delete(process.Plugin, "part")
+
+	options := newRawOptions(plugin.Process, "part", "1", map[string]interface{}{})
+	parter, err := newParter("process-part-1-2", options)
+	assert.Equal(t, nil, err, "failed to set the plugin")
+	assert.Equal(t, plugin.Part{Group: "1", Parter: "process-part-1-2"}, parter.(*partProcess).Part, "failed to give the parter name to the plugin")
+	assert.Equal(t, nil, options.raw[plugin.ParterKey], "failed to keep the conf of the plugin")
+}
+
+func TestNewSink(t *testing.T) {
+	letterConfig := map[string]interface{}{group: "1", sink: "output", "to": "dead-letter"}
+	parters := map[string]*parterOptions{
+		"output-1": newRawOptions(plugin.Output, "test", "1", map[string]interface{}{}),
+	}
+	_, err := newSink("1", letterConfig, parters)
+	assert.NotEqual(t, nil, err, "failed to detect the target group without output plugins")
+
+	parters["output-dead-letter"] = newRawOptions(plugin.Output, "test", "dead-letter", map[string]interface{}{})
+	_, err = newSink("1", letterConfig, parters)
+	assert.Equal(t, nil, err, "failed to check the target group against the plugins to install")
+}
@@ -0,0 +1,326 @@
+package worker
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/bigstack-oss/plane-go/pkg/frame/oneway/plugin"
+)
+
+var (
+	stages = []string{plugin.Input, plugin.Transit, plugin.Process, plugin.Output}
+
+	// the stages which are looked up, in order, when an edge refers to a group only
+	downstreams = map[string][]string{
+		plugin.Input:   {plugin.Transit},
+		plugin.Transit: {plugin.Process, plugin.Output},
+		plugin.Process: {plugin.Output},
+	}
+	upstreams = map[string][]string{
+		plugin.Transit: {plugin.Input},
+		plugin.Process: {plugin.Transit},
+		plugin.Output:  {plugin.Process, plugin.Transit},
+	}
+
+	// the stages which can be connected by an explicit `stage/group` edge
+	allowedEdges = map[string][]string{
+		plugin.Input:   {plugin.Transit},
+		plugin.Transit: {plugin.Process, plugin.Output},
+		plugin.Process: {plugin.Process, plugin.Output},
+	}
+)
+
+func contains(items []string, target string) bool {
+	for _, item := range items {
+		if item == target {
+			return true
+		}
+	}
+
+	return false
+}
+
+// getStageParters returns the names of the parters of the stage in order
+func getStageParters(parters map[string]*parterOptions, stage string) []string {
+	parterNames := []string{}
+	for parterName, options := range parters {
+		if options.Stage == stage {
+			parterNames = append(parterNames, parterName)
+		}
+	}
+
+	sort.Strings(parterNames)
+	return parterNames
+}
+
+// getNodes returns the names of the parters of every node in order. the
+// parters of a node consume the same hop, so they have to share the ordering
+// of the node
+func getNodes(parters map[string]*parterOptions) (map[plugin.Node][]string, error) {
+	nodes := make(map[plugin.Node][]string)
+	for _, stage := range stages {
+		for _, parterName := range getStageParters(parters, stage) {
+			options := parters[parterName]
+			node := plugin.Node{Stage: options.Stage, Group: options.Group}
+			if len(nodes[node]) > 0 {
+				first := parters[nodes[node][0]]
+				if first.Ordered != options.Ordered {
+					return nil, fmt.Errorf("%s and %s of node(%s) have different ordered options", nodes[node][0], parterName, node)
+				}
+			}
+
+			nodes[node] = append(nodes[node], parterName)
+		}
+	}
+
+	return nodes, nil
+}
+
+// getNodeConcurrency returns the number of the workers of the parters of the
+// node
+func getNodeConcurrency(parters map[string]*parterOptions, parterNames []string) int {
+	concurrency := 0
+	for _, parterName := range parterNames {
+		concurrency += parters[parterName].Concurrency
+	}
+
+	return concurrency
+}
+
+// resolveNode finds the node referred by an edge of the given node. the
+// reference is either `stage/group`, or a group which is looked up in the
+// nearest stages next to the node
+func resolveNode(nodes map[plugin.Node][]string, node plugin.Node, reference string, nearStages map[string][]string) (plugin.Node, error) {
+	if strings.Contains(reference, "/") {
+		parts := strings.SplitN(reference, "/", 2)
+		target := plugin.Node{Stage: parts[0], Group: parts[1]}
+		if _, isExisted := nodes[target]; !isExisted {
+			return plugin.Node{}, fmt.Errorf("dangling edge from node(%s): node(%s) was not found", node, reference)
+		}
+
+		return target, nil
+	}
+
+	for _, stage := range nearStages[node.Stage] {
+		target := plugin.Node{Stage: stage, Group: reference}
+		if _, isExisted := nodes[target]; isExisted {
+			return target, nil
+		}
+	}
+
+	return plugin.Node{}, fmt.Errorf("dangling edge from node(%s): group(%s) was not found in stages %v", node, reference, nearStages[node.Stage])
+}
+
+func appendEdge(edges map[plugin.Node][]plugin.Node, source plugin.Node, target plugin.Node) error {
+	if !contains(allowedEdges[source.Stage], target.Stage) {
+		return fmt.Errorf("invalid edge from node(%s) to node(%s)", source, target)
+	}
+
+	for _, existedTarget := range edges[source] {
+		if existedTarget == target {
+			return nil
+		}
+	}
+
+	edges[source] = append(edges[source], target)
+	return nil
+}
+
+func getExplicitEdges(nodes map[plugin.Node][]string, parters map[string]*parterOptions) (map[plugin.Node][]plugin.Node, map[plugin.Node]map[string]plugin.Node, error) {
+	edges := make(map[plugin.Node][]plugin.Node)
+	references := make(map[plugin.Node]map[string]plugin.Node)
+
+	for _, stage := range stages {
+		for _, parterName := range getStageParters(parters, stage) {
+			options := parters[parterName]
+			node := plugin.Node{Stage: options.Stage, Group: options.Group}
+			if references[node] == nil {
+				references[node] = make(map[string]plugin.Node)
+			}
+
+			for _, reference := range options.To {
+				target, err := resolveNode(nodes, node, reference, downstreams)
+				if err != nil {
+					return nil, nil, err
+				}
+
+				err = appendEdge(edges, node, target)
+				if err != nil {
+					return nil, nil, err
+				}
+
+				references[node][reference] = target
+			}
+
+			for _, reference := range options.From {
+				source, err := resolveNode(nodes, node, reference, upstreams)
+				if err != nil {
+					return nil, nil, err
+				}
+
+				err = appendEdge(edges, source, node)
+				if err != nil {
+					return nil, nil, err
+				}
+			}
+		}
+	}
+
+	return edges, references, nil
+}
+
+// getEdges connects every node without explicit edges to the nearest
+// downstream node of the same group, which keeps the linear pipelines working
+func getEdges(nodes map[plugin.Node][]string, parters map[string]*parterOptions) (map[plugin.Node][]plugin.Node, map[plugin.Node]map[string]plugin.Node, error) {
+	edges, references, err := getExplicitEdges(nodes, parters)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for node := range nodes {
+		if node.Stage == plugin.Output || len(edges[node]) > 0 {
+			continue
+		}
+
+		target, err := resolveNode(nodes, node, node.Group, downstreams)
+		if err != nil {
+			return nil, nil, fmt.Errorf("node(%s) has no downstream node", node)
+		}
+
+		edges[node] = []plugin.Node{target}
+	}
+
+	return edges, references, nil
+}
+
+func checkCycle(edges map[plugin.Node][]plugin.Node, node plugin.Node, visiting map[plugin.Node]bool, visited map[plugin.Node]bool) error {
+	if visiting[node] {
+		return fmt.Errorf("cyclic edge detected at node(%s)", node)
+	}
+	if visited[node] {
+		return nil
+	}
+
+	visiting[node] = true
+	for _, target := range edges[node] {
+		err := checkCycle(edges, target, visiting, visited)
+		if err != nil {
+			return err
+		}
+	}
+
+	visiting[node] = false
+	visited[node] = true
+	return nil
+}
+
+func (o *Onewayer) checkTopology(nodes map[plugin.Node][]string, edges map[plugin.Node][]plugin.Node) error {
+	visiting := make(map[plugin.Node]bool)
+	visited := make(map[plugin.Node]bool)
+	for node := range nodes {
+		err := checkCycle(edges, node, visiting, visited)
+		if err != nil {
+			return err
+		}
+	}
+
+	sources := make(map[plugin.Node]int)
+	for _, targets := range edges {
+		for _, target := range targets {
+			sources[target]++
+		}
+	}
+
+	for node := range nodes {
+		if node.Stage == plugin.Input || sources[node] > 0 {
+			continue
+		}
+
+		// an output node without upstream can still be fed by the dead-letter sinks
+		if node.Stage == plugin.Output {
+			o.logf.Warnf("node(%s) has no upstream node", node)
+			continue
+		}
+
+		return fmt.Errorf("dangling node(%s): no upstream node was found", node)
+	}
+
+	return nil
+}
+
+// getTopology builds the edges between nodes from the `to` and `from` options
+// of the parters, and checks them without touching the running pipeline
+func (o *Onewayer) getTopology(parters map[string]*parterOptions) (map[plugin.Node][]plugin.Node, map[plugin.Node]map[string]plugin.Node, error) {
+	nodes, err := getNodes(parters)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	edges, references, err := getEdges(nodes, parters)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = o.checkTopology(nodes, edges)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return edges, references, nil
+}
+
+// connectParters connects the nodes of the parters, and counts the producer
+// workers of every hop for fan-in. the parters are not connected to any node
+// out of them, so a set of groups can be connected on its own
+func (o *Onewayer) connectParters(parters map[string]*parterOptions) error {
+	edges, references, err := o.getTopology(parters)
+	if err != nil {
+		return err
+	}
+
+	nodes, _ := getNodes(parters)
+	for source, targets := range edges {
+		concurrency := getNodeConcurrency(parters, nodes[source])
+		for _, target := range targets {
+			plugin.AddProducers(plugin.InboundHop(target.Stage), target.Group, concurrency)
+		}
+	}
+
+	plugin.AddEdges(edges, references)
+	return nil
+}
+
+// getAffectedGroups returns the changed groups and all the groups connected
+// to them by the edges, which have to be restarted together
+func getAffectedGroups(changed map[string]bool, edgeSets ...map[plugin.Node][]plugin.Node) map[string]bool {
+	neighbors := make(map[string][]string)
+	for _, edges := range edgeSets {
+		for source, targets := range edges {
+			for _, target := range targets {
+				neighbors[source.Group] = append(neighbors[source.Group], target.Group)
+				neighbors[target.Group] = append(neighbors[target.Group], source.Group)
+			}
+		}
+	}
+
+	affected := make(map[string]bool)
+	pending := []string{}
+	for group := range changed {
+		affected[group] = true
+		pending = append(pending, group)
+	}
+
+	for len(pending) > 0 {
+		group := pending[0]
+		pending = pending[1:]
+		for _, neighbor := range neighbors[group] {
+			if !affected[neighbor] {
+				affected[neighbor] = true
+				pending = append(pending, neighbor)
+			}
+		}
+	}
+
+	return affected
+}
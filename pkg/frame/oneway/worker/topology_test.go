@@ -0,0 +1,171 @@
+package worker
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/bigstack-oss/plane-go/pkg/frame/oneway/plugin"
+	"github.com/bigstack-oss/plane-go/pkg/frame/oneway/plugin/retry"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestOnewayer(options ...*parterOptions) *Onewayer {
+	o := InitWorker().(*Onewayer)
+	for i, option := range options {
+		if option.Concurrency == 0 {
+			option.Concurrency = 1
+		}
+
+		parterName := strings.Join([]string{option.Stage, option.Group, strconv.Itoa(i)}, "-")
+		o.parters[parterName] = option
+		switch option.Stage {
+		case plugin.Input:
+			o.Inputs = append(o.Inputs, parterName)
+		case plugin.Transit:
+			o.Transits = append(o.Transits, parterName)
+		case plugin.Process:
+			o.Processes = append(o.Processes, parterName)
+		case plugin.Output:
+			o.Outputs = append(o.Outputs, parterName)
+		}
+	}
+
+	return o
+}
+
+func TestLinearTopology(t *testing.T) {
+	plugin.ResetHops()
+	o := newTestOnewayer(
+		&parterOptions{Stage: plugin.Input, Group: "1"},
+		&parterOptions{Stage: plugin.Transit, Group: "1", Concurrency: 2},
+		&parterOptions{Stage: plugin.Output, Group: "1"},
+	)
+
+	err := o.connectParters(o.parters)
+	assert.Equal(t, nil, err, "failed to build linear topology")
+	assert.Equal(t, []plugin.Node{{Stage: plugin.Transit, Group: "1"}}, plugin.Edges[plugin.Node{Stage: plugin.Input, Group: "1"}], "failed to connect input to transit")
+	assert.Equal(t, []plugin.Node{{Stage: plugin.Output, Group: "1"}}, plugin.Edges[plugin.Node{Stage: plugin.Transit, Group: "1"}], "failed to skip the missing process stage")
+}
+
+func TestFanOutAndFanInTopology(t *testing.T) {
+	plugin.ResetHops()
+	o := newTestOnewayer(
+		&parterOptions{Stage: plugin.Input, Group: "1", To: []string{"a", "b"}},
+		&parterOptions{Stage: plugin.Transit, Group: "a"},
+		&parterOptions{Stage: plugin.Transit, Group: "b"},
+		&parterOptions{Stage: plugin.Output, Group: "m", From: []string{"a", "b"}},
+		&parterOptions{Stage: plugin.Output, Group: "audit", From: []string{"transit/b"}},
+	)
+
+	err := o.connectParters(o.parters)
+	assert.Equal(t, nil, err, "failed to build fan-out and fan-in topology")
+	assert.Equal(t, 2, len(plugin.Edges[plugin.Node{Stage: plugin.Input, Group: "1"}]), "failed to fan out input")
+	assert.Equal(t, 2, len(plugin.Edges[plugin.Node{Stage: plugin.Transit, Group: "b"}]), "failed to broadcast transit")
+}
+
+func TestMultiParterNode(t *testing.T) {
+	plugin.ResetHops()
+	defer plugin.ResetHops()
+	o := newTestOnewayer(
+		&parterOptions{Stage: plugin.Input, Group: "1"},
+		&parterOptions{Stage: plugin.Transit, Group: "1", Concurrency: 2},
+		&parterOptions{Stage: plugin.Transit, Group: "1", Concurrency: 3},
+		&parterOptions{Stage: plugin.Output, Group: "1"},
+	)
+
+	err := o.connectParters(o.parters)
+	assert.Equal(t, nil, err, "failed to build topology with two plugins in a node")
+	assert.Equal(t, []plugin.Node{{Stage: plugin.Output, Group: "1"}}, plugin.Edges[plugin.Node{Stage: plugin.Transit, Group: "1"}], "failed to connect the node of two plugins")
+
+	plugin.AddHop(plugin.P2O, "1", 0)
+	for i := 0; i < 5; i++ {
+		plugin.ReleaseTargets(plugin.Transit, "1")
+	}
+	_, isChnOpen := <-plugin.P2OChan["1"]
+	assert.Equal(t, false, isChnOpen, "failed to wait for the workers of both plugins")
+
+	o = newTestOnewayer(
+		&parterOptions{Stage: plugin.Input, Group: "1"},
+		&parterOptions{Stage: plugin.Output, Group: "1", Ordered: true},
+		&parterOptions{Stage: plugin.Output, Group: "1"},
+	)
+	_, err = getNodes(o.parters)
+	assert.NotEqual(t, nil, err, "failed to detect the plugins of a node with different ordering")
+
+	o = newTestOnewayer(
+		&parterOptions{Stage: plugin.Input, Group: "1"},
+		&parterOptions{Stage: plugin.Output, Group: "1", policy: &retry.Policy{Max: 1}},
+		&parterOptions{Stage: plugin.Output, Group: "1", policy: &retry.Policy{Max: 3}},
+	)
+	_, err = getNodes(o.parters)
+	assert.Equal(t, nil, err, "failed to allow the plugins of a node with their own retry policies")
+
+	retry.SetPolicy("output-1-1", o.parters["output-1-1"].policy)
+	retry.SetPolicy("output-1-2", o.parters["output-1-2"].policy)
+	defer retry.SetPolicy("output-1-2", nil)
+	o.removeParter("output-1-1")
+	_, isExisted := retry.Policies["output-1-1"]
+	assert.Equal(t, false, isExisted, "failed to remove the retry policy of the plugin")
+	assert.Equal(t, 3, retry.Policies["output-1-2"].Max, "failed to keep the retry policy of the other plugin")
+}
+
+func TestInvalidTopology(t *testing.T) {
+	plugin.ResetHops()
+	o := newTestOnewayer(
+		&parterOptions{Stage: plugin.Transit, Group: "1", To: []string{"missing"}},
+	)
+	assert.NotEqual(t, nil, o.connectParters(o.parters), "failed to detect dangling edge")
+
+	o = newTestOnewayer(
+		&parterOptions{Stage: plugin.Input, Group: "1"},
+		&parterOptions{Stage: plugin.Transit, Group: "1"},
+		&parterOptions{Stage: plugin.Process, Group: "1", To: []string{"process/2"}},
+		&parterOptions{Stage: plugin.Process, Group: "2", To: []string{"process/1"}},
+	)
+	assert.NotEqual(t, nil, o.connectParters(o.parters), "failed to detect cyclic edge")
+
+	o = newTestOnewayer(
+		&parterOptions{Stage: plugin.Input, Group: "1"},
+		&parterOptions{Stage: plugin.Transit, Group: "1"},
+		&parterOptions{Stage: plugin.Transit, Group: "2"},
+		&parterOptions{Stage: plugin.Output, Group: "1"},
+		&parterOptions{Stage: plugin.Output, Group: "2"},
+	)
+	assert.NotEqual(t, nil, o.connectParters(o.parters), "failed to detect dangling node")
+
+	o = newTestOnewayer(
+		&parterOptions{Stage: plugin.Input, Group: "1", To: []string{"output/1"}},
+		&parterOptions{Stage: plugin.Output, Group: "1"},
+	)
+	assert.NotEqual(t, nil, o.connectParters(o.parters), "failed to detect invalid edge")
+}
+
+func TestIsDrained(t *testing.T) {
+	plugin.ResetHops()
+	defer plugin.ResetHops()
+	o := newTestOnewayer(
+		&parterOptions{Stage: plugin.Input, Group: "1"},
+		&parterOptions{Stage: plugin.Transit, Group: "1", Concurrency: 2},
+		&parterOptions{Stage: plugin.Output, Group: "1"},
+		&parterOptions{Stage: plugin.Output, Group: "letters"},
+	)
+
+	err := o.connectParters(o.parters)
+	assert.Equal(t, nil, err, "failed to build topology")
+	assert.Equal(t, false, o.isDrained(nil), "failed to wait for the running workers")
+
+	// the workers of a group which was removed on reload are not counted
+	plugin.CompleteWorker(plugin.Output, "1")
+	plugin.RemoveGroup("1")
+	err = o.connectParters(o.parters)
+	assert.Equal(t, nil, err, "failed to build topology")
+
+	plugin.CompleteWorker(plugin.Transit, "1")
+	plugin.CompleteWorker(plugin.Transit, "1")
+	assert.Equal(t, false, o.isDrained(nil), "failed to wait for the output fed by the transit")
+	assert.Equal(t, true, o.isDrained(map[string]bool{"letters": true}), "failed to check the given groups only")
+
+	plugin.CompleteWorker(plugin.Output, "1")
+	assert.Equal(t, true, o.isDrained(nil), "failed to skip the output without upstream")
+}
@@ -5,5 +5,7 @@ import "github.com/bigstack-oss/plane-go/pkg/frame/oneway/plugin/plug"
 type Worker interface {
 	plug.PartUser
 	plug.CronUser
+	plug.DeadLetterUser
 	plug.Statuser
+	plug.Reloader
 }
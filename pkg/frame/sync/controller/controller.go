@@ -33,8 +33,9 @@ var (
 	once sync.Once
 	wg   sync.WaitGroup = sync.WaitGroup{}
 
-	configer = config.GetConfiger()
-	conf     string
+	configer  = config.GetConfiger()
+	conf      string
+	watchConf bool
 
 	logLevel int
 
@@ -49,6 +50,7 @@ type controller struct {
 	cancel context.CancelFunc
 
 	signalChannel chan os.Signal
+	watcher       *config.Watcher
 
 	log  *zap.Logger
 	logf *zap.SugaredLogger
@@ -58,6 +60,7 @@ type controller struct {
 
 func init() {
 	flag.StringVar(&conf, "conf", "", "")
+	flag.BoolVar(&watchConf, "watch-conf", false, "")
 	flag.IntVar(&logLevel, "log-level", 2, "")
 	flag.Parse()
 
@@ -74,29 +77,36 @@ func GetInstance() *controller {
 	return instance
 }
 
-func (c *controller) loadConfig(conf string) error {
+func readConfigFile(conf string) ([]byte, error) {
 	if conf == "" {
-		return errors.New("conf file is required, please specify the path of conf file")
+		return nil, errors.New("conf file is required, please specify the path of conf file")
 	}
 
-	content, err := ioutil.ReadFile(conf)
-	if err != nil {
-		return err
-	}
+	return ioutil.ReadFile(conf)
+}
+
+func parseConfig(cfg config.Configer, content []byte) error {
+	cfg.SetConfigType("yaml")
+	return cfg.ReadConfig(bytes.NewBuffer(content))
+}
 
-	configer.SetConfigType("yaml")
-	err = configer.ReadConfig(bytes.NewBuffer(content))
+func (c *controller) loadConfig(conf string) error {
+	content, err := readConfigFile(conf)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	return parseConfig(configer, content)
 }
 
 func (c *controller) initControllerParams() {
 	c.wg = &wg
 	c.ctx, c.cancel = context.WithCancel(context.Background())
-	c.signalChannel = make(chan os.Signal, 1)
+
+	// the signals are still trapped on the channel after a restart
+	if c.signalChannel == nil {
+		c.signalChannel = make(chan os.Signal, 1)
+	}
 }
 
 func (c *controller) initPluginParams() {
@@ -137,20 +147,50 @@ func (c *controller) Stop() {
 	c.log.Info("all tasks have been stopped. stopping service.")
 }
 
-func (c *controller) Restart() {
-	c.log.Info("restarting all workers")
-	defer c.wg.Done()
-	c.wg.Add(1)
-	c.Stop()
+// Reload applies the changes of the conf file to the running workers, only
+// the plugins whose config changed are restarted. the workers keep running
+// with the previous conf if the new one is invalid. the conf file is read
+// once, so that the conf applied is the one checked
+func (c *controller) Reload() error {
+	content, err := readConfigFile(conf)
+	if err != nil {
+		return err
+	}
+
+	cfg := config.NewConfiger()
+	err = parseConfig(cfg, content)
+	if err != nil {
+		return err
+	}
+
+	err = c.Worker.Reload(cfg)
+	if err != nil {
+		return err
+	}
 
-	c = GetInstance()
-	c.InitService()
-	c.ActivateService()
-	c.Start()
-	c.TrapSignals()
+	return parseConfig(configer, content)
+}
+
+// watchConfig reloads the service once the conf file is changed, the reload
+// is passed through the signal channel so that it never runs concurrently
+func (c *controller) watchConfig() {
+	if !watchConf || c.watcher != nil {
+		return
+	}
+
+	watcher, err := config.WatchFile(conf, func() { c.signalChannel <- syscall.SIGHUP })
+	if err != nil {
+		c.logf.Errorf("failed to watch conf file '%s'. error details: %s", conf, err.Error())
+		return
+	}
+
+	c.watcher = watcher
+	c.logf.Infof("watching conf file '%s'", conf)
 }
 
 func (c *controller) TrapSignals() {
+	c.watchConfig()
+
 	go func() {
 		signal.Notify(c.signalChannel, syscall.SIGHUP, syscall.SIGTERM)
 		c.log.Info("signal registered: SIGTERM, SIGUSR1")
@@ -158,8 +198,14 @@ func (c *controller) TrapSignals() {
 		for sig := range c.signalChannel {
 			switch sig {
 			case syscall.SIGHUP:
-				c.log.Info("SIGHUP received, restarting service...")
-				c.Restart()
+				c.log.Info("SIGHUP received, reloading service...")
+				err := c.Reload()
+				if err != nil {
+					c.logf.Errorf("failed to reload conf from '%s', keep running with the previous conf. error details: %s", conf, err.Error())
+					continue
+				}
+
+				c.log.Info("service has been reloaded")
 			case syscall.SIGTERM:
 				c.log.Info("SIGTERM received, stopping service ...")
 				c.Stop()
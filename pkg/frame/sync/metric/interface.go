@@ -0,0 +1,144 @@
+package metric
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/bigstack-oss/plane-go/pkg/base/protocol"
+	"github.com/bigstack-oss/plane-go/pkg/frame/sync/plugin"
+	"github.com/bigstack-oss/plane-go/pkg/frame/sync/plugin/plug"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	statusOK  = "ok"
+	statusErr = "err"
+)
+
+var (
+	requestCount = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "interface_requests_total",
+			Help: "number of requests served by each interface",
+		},
+		[]string{"service", "interface", "code"},
+	)
+
+	requestDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "interface_request_duration_seconds",
+			Help:    "seconds each interface took to serve a request",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"service", "interface"},
+	)
+
+	requestInFlight = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "interface_in_flight",
+			Help: "number of requests each interface is serving",
+		},
+		[]string{"service", "interface"},
+	)
+
+	stageCount = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "stage_executions_total",
+			Help: "number of executions of each stage of the interfaces",
+		},
+		[]string{"service", "interface", "stage", "index", "status"},
+	)
+
+	stageDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "stage_duration_seconds",
+			Help:    "seconds each stage of the interfaces took to execute",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"service", "interface", "stage", "index"},
+	)
+
+	stageRetryCount = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "stage_retries_total",
+			Help: "number of retries of each stage of the interfaces",
+		},
+		[]string{"service", "interface", "stage", "index"},
+	)
+)
+
+// Request measures one request served by an interface
+type Request struct {
+	interfaceName string
+	startedAt     time.Time
+}
+
+// measuredStager records the duration and the result of every execution of
+// the stage it wraps
+type measuredStager struct {
+	plug.ContextStager
+	labels []string
+}
+
+// StartRequest is called by the interact plugins before a request is handed
+// over to the stages of the interface
+func StartRequest(interfaceName string) *Request {
+	requestInFlight.WithLabelValues(plugin.Service, interfaceName).Inc()
+	return &Request{
+		interfaceName: interfaceName,
+		startedAt:     time.Now(),
+	}
+}
+
+// Done records the status code the request was answered with
+func (r *Request) Done(code int) {
+	requestInFlight.WithLabelValues(plugin.Service, r.interfaceName).Dec()
+	requestDuration.WithLabelValues(plugin.Service, r.interfaceName).Observe(time.Since(r.startedAt).Seconds())
+	requestCount.WithLabelValues(plugin.Service, r.interfaceName, strconv.Itoa(code)).Inc()
+}
+
+// CountRetry returns the function which counts the retries of a stage
+func CountRetry(interfaceName string, stageIndex int, stageName string) func() {
+	counter := stageRetryCount.WithLabelValues(plugin.Service, interfaceName, stageName, strconv.Itoa(stageIndex))
+	return counter.Inc
+}
+
+// WrapStager labels the executions of the stager with the interface, the
+// stage name and the index of the stage in the interface
+func WrapStager(interfaceName string, stageIndex int, stageName string, stager plug.Stager) plug.ContextStager {
+	return &measuredStager{
+		ContextStager: plug.WithContext(stager),
+		labels:        []string{plugin.Service, interfaceName, stageName, strconv.Itoa(stageIndex)},
+	}
+}
+
+func (s *measuredStager) observe(startedAt time.Time, err error) {
+	stageDuration.WithLabelValues(s.labels...).Observe(time.Since(startedAt).Seconds())
+
+	status := statusOK
+	if err != nil {
+		status = statusErr
+	}
+
+	stageCount.WithLabelValues(append(s.labels, status)...).Inc()
+}
+
+func (s *measuredStager) Unwrap() plug.Stager {
+	return s.ContextStager
+}
+
+func (s *measuredStager) Execute(job *protocol.Job) (bool, error) {
+	startedAt := time.Now()
+	result, err := s.ContextStager.Execute(job)
+	s.observe(startedAt, err)
+	return result, err
+}
+
+func (s *measuredStager) ExecuteContext(ctx context.Context, job *protocol.Job) (bool, error) {
+	startedAt := time.Now()
+	result, err := s.ContextStager.ExecuteContext(ctx, job)
+	s.observe(startedAt, err)
+	return result, err
+}
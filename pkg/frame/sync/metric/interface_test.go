@@ -0,0 +1,42 @@
+package metric
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/bigstack-oss/plane-go/pkg/base/protocol"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+type testStager struct {
+	err error
+}
+
+func (s *testStager) SetConfig(interface{}) {}
+
+func (s *testStager) CheckConfig() error {
+	return nil
+}
+
+func (s *testStager) Execute(*protocol.Job) (bool, error) {
+	return s.err == nil, s.err
+}
+
+func TestWrapStager(t *testing.T) {
+	stager := WrapStager("get", 1, "failing", &testStager{err: errors.New("failed")})
+	result, err := stager.Execute(&protocol.Job{})
+
+	assert.Equal(t, false, result, "failed to pass the result of the stage through")
+	assert.NotEqual(t, nil, err, "failed to pass the error of the stage through")
+	assert.Equal(t, float64(1), testutil.ToFloat64(stageCount.WithLabelValues("", "get", "failing", "1", statusErr)), "failed to count the failed execution")
+}
+
+func TestRequest(t *testing.T) {
+	request := StartRequest("post")
+	assert.Equal(t, float64(1), testutil.ToFloat64(requestInFlight.WithLabelValues("", "post")), "failed to count the request in flight")
+
+	request.Done(201)
+	assert.Equal(t, float64(0), testutil.ToFloat64(requestInFlight.WithLabelValues("", "post")), "failed to count the served request")
+	assert.Equal(t, float64(1), testutil.ToFloat64(requestCount.WithLabelValues("", "post", "201")), "failed to count the request by status code")
+}
@@ -0,0 +1,24 @@
+package metric
+
+import (
+	"github.com/bigstack-oss/plane-go/pkg/frame/sync/plugin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	limitedCount = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "limited_requests_total",
+			Help: "number of requests of each interface rejected by the limiters",
+		},
+		[]string{"service", "interface", "limiter", "key_by"},
+	)
+)
+
+// CountLimited counts a request rejected by the limiter, keyBy tells what
+// the requests are limited by rather than the key itself, so that the
+// applicants never blow up the series
+func CountLimited(interfaceName string, limiter string, keyBy string) {
+	limitedCount.WithLabelValues(plugin.Service, interfaceName, limiter, keyBy).Inc()
+}
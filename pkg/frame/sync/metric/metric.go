@@ -76,6 +76,13 @@ var (
 		},
 	)
 
+	stageRetry = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "stage_retry",
+			Help: "",
+		},
+	)
+
 	metricLogger = log.GetLogger(module)
 )
 
@@ -118,4 +125,6 @@ func setMetrics(metrics *plugin.Metric) {
 
 	requestOK.Set(float64(plugin.Metrics.RequestOK))
 	requestErr.Set(float64(plugin.Metrics.RequestErr))
+
+	stageRetry.Set(float64(plugin.Metrics.StageRetry))
 }
@@ -0,0 +1,25 @@
+package metric
+
+import (
+	"strconv"
+
+	"github.com/bigstack-oss/plane-go/pkg/frame/sync/plugin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	policyDenialCount = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "policy_denials_total",
+			Help: "number of requests denied by each policy rule, dry runs included",
+		},
+		[]string{"service", "interface", "rule", "dry_run"},
+	)
+)
+
+// CountDenial counts a request denied by the rule, the requests let through
+// by the dry run are labelled with dry_run="true"
+func CountDenial(interfaceName string, rule string, isDryRun bool) {
+	policyDenialCount.WithLabelValues(plugin.Service, interfaceName, rule, strconv.FormatBool(isDryRun)).Inc()
+}
@@ -32,4 +32,6 @@ type Metric struct {
 
 	RequestOK  uint64 `json:"requestOK"`
 	RequestErr uint64 `json:"requestErr"`
+
+	StageRetry uint64 `json:"stageRetry"`
 }
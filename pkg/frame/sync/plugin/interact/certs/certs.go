@@ -0,0 +1,245 @@
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/bigstack-oss/plane-go/pkg/base/config"
+	"github.com/bigstack-oss/plane-go/pkg/base/protocol"
+	"go.uber.org/zap"
+)
+
+var (
+	tlsVersions = map[string]uint16{
+		"1.0": tls.VersionTLS10,
+		"1.1": tls.VersionTLS11,
+		"1.2": tls.VersionTLS12,
+		"1.3": tls.VersionTLS13,
+	}
+
+	clientAuthTypes = map[string]tls.ClientAuthType{
+		"none":             tls.NoClientCert,
+		"request":          tls.RequestClientCert,
+		"requireAny":       tls.RequireAnyClientCert,
+		"verifyIfGiven":    tls.VerifyClientCertIfGiven,
+		"requireAndVerify": tls.RequireAndVerifyClientCert,
+	}
+)
+
+// Config serves tls if CertFile is set. the client certificates are
+// verified with the CAs of ClientCAFile, and the files are loaded again once
+// they are rotated
+type Config struct {
+	CertFile     string
+	KeyFile      string `validate:"required_with=CertFile"`
+	ClientCAFile string
+	MinVersion   string `validate:"omitempty,oneof=1.0 1.1 1.2 1.3"`
+	CipherSuites []string
+	ClientAuth   string `validate:"omitempty,oneof=none request requireAny verifyIfGiven requireAndVerify"`
+}
+
+// Reloader keeps the certificate and the client CAs loaded from the files,
+// so that the handshakes always take the rotated ones
+type Reloader struct {
+	sync.RWMutex
+	Config
+
+	base      *tls.Config
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+	watchers  []*config.Watcher
+
+	logf *zap.SugaredLogger
+}
+
+func (c Config) IsEnabled() bool {
+	return c.CertFile != ""
+}
+
+func getCipherSuites(names []string) ([]uint16, error) {
+	ids := make(map[string]uint16)
+	for _, suite := range tls.CipherSuites() {
+		ids[suite.Name] = suite.ID
+	}
+
+	suites := []uint16{}
+	for _, name := range names {
+		id, isExisted := ids[name]
+		if !isExisted {
+			return nil, fmt.Errorf("unsupported cipher suite(%s)", name)
+		}
+
+		suites = append(suites, id)
+	}
+
+	return suites, nil
+}
+
+// NewReloader loads the files of conf, and returns the reloader which is not
+// watching the files yet
+func NewReloader(conf Config, logf *zap.SugaredLogger) (*Reloader, error) {
+	suites, err := getCipherSuites(conf.CipherSuites)
+	if err != nil {
+		return nil, err
+	}
+
+	minVersion, isExisted := tlsVersions[conf.MinVersion]
+	if !isExisted {
+		minVersion = tls.VersionTLS12
+	}
+
+	clientAuth := clientAuthTypes[conf.ClientAuth]
+	if conf.ClientAuth == "" && conf.ClientCAFile != "" {
+		clientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	r := &Reloader{
+		Config: conf,
+		base:   &tls.Config{MinVersion: minVersion, ClientAuth: clientAuth},
+		logf:   logf,
+	}
+	if len(suites) > 0 {
+		r.base.CipherSuites = suites
+	}
+
+	err = r.load()
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+func loadCertPool(path string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificate was found in %s", path)
+	}
+
+	return pool, nil
+}
+
+// load reads the files, the loaded ones are kept if any of them is invalid
+func (r *Reloader) load() error {
+	cert, err := tls.LoadX509KeyPair(r.CertFile, r.KeyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load certificate. error: %s", err.Error())
+	}
+
+	var clientCAs *x509.CertPool
+	if r.ClientCAFile != "" {
+		clientCAs, err = loadCertPool(r.ClientCAFile)
+		if err != nil {
+			return fmt.Errorf("failed to load client CAs. error: %s", err.Error())
+		}
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	r.cert = &cert
+	r.clientCAs = clientCAs
+	return nil
+}
+
+func (r *Reloader) reload() {
+	err := r.load()
+	if err != nil {
+		r.logf.Errorf("failed to reload tls files, the loaded ones are kept. error: %s", err.Error())
+		return
+	}
+
+	r.logf.Infof("reloaded tls certificate(%s)", r.CertFile)
+}
+
+// Watch reloads the files once any of them is rotated
+func (r *Reloader) Watch() error {
+	files := []string{r.CertFile, r.KeyFile}
+	if r.ClientCAFile != "" {
+		files = append(files, r.ClientCAFile)
+	}
+
+	for _, file := range files {
+		watcher, err := config.WatchFile(file, r.reload)
+		if err != nil {
+			return err
+		}
+
+		r.watchers = append(r.watchers, watcher)
+	}
+
+	return nil
+}
+
+func (r *Reloader) Close() error {
+	errs := []error{}
+	for _, watcher := range r.watchers {
+		errs = append(errs, watcher.Close())
+	}
+
+	r.watchers = nil
+	return errors.Join(errs...)
+}
+
+// getConfigForClient gives every handshake the files loaded latest
+func (r *Reloader) getConfigForClient(*tls.ClientHelloInfo) (*tls.Config, error) {
+	r.RLock()
+	defer r.RUnlock()
+
+	conf := r.base.Clone()
+	conf.Certificates = []tls.Certificate{*r.cert}
+	conf.ClientCAs = r.clientCAs
+	return conf, nil
+}
+
+// TLSConfig returns the config of the servers, nextProtos are negotiated by
+// the handshakes along with the files loaded latest
+func (r *Reloader) TLSConfig(nextProtos ...string) *tls.Config {
+	conf := r.base.Clone()
+	conf.GetConfigForClient = func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+		conf, err := r.getConfigForClient(hello)
+		if err != nil {
+			return nil, err
+		}
+
+		conf.NextProtos = nextProtos
+		return conf, nil
+	}
+
+	return conf
+}
+
+// GetApplicant maps the subject of the verified client certificate into an
+// applicant, the common name is taken as the id and the name, and the first
+// organization, unit and country as the company, project and country
+func GetApplicant(state *tls.ConnectionState) (*protocol.Applicant, bool) {
+	if state == nil || len(state.VerifiedChains) == 0 {
+		return nil, false
+	}
+
+	subject := state.VerifiedChains[0][0].Subject
+	first := func(values []string) string {
+		if len(values) == 0 {
+			return ""
+		}
+
+		return values[0]
+	}
+
+	return &protocol.Applicant{
+		ID:      subject.CommonName,
+		Name:    subject.CommonName,
+		Project: first(subject.OrganizationalUnit),
+		Company: first(subject.Organization),
+		Country: first(subject.Country),
+	}, true
+}
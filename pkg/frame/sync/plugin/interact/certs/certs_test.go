@@ -0,0 +1,152 @@
+package certs
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCert(t *testing.T, subject pkix.Name, parent *testCert, usage x509.ExtKeyUsage) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Equal(t, nil, err, "failed to generate key")
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      subject,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{"localhost"},
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+
+	signer, signerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage = x509.KeyUsageCertSign
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	assert.Equal(t, nil, err, "failed to create certificate")
+
+	cert, _ := x509.ParseCertificate(der)
+	return &testCert{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+func (c *testCert) write(t *testing.T, certFile string, keyFile string) {
+	der, err := x509.MarshalECPrivateKey(c.key)
+	assert.Equal(t, nil, err, "failed to marshal key")
+
+	assert.Equal(t, nil, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), 0600), "failed to write key")
+	assert.Equal(t, nil, os.WriteFile(certFile, c.pem, 0600), "failed to write certificate")
+}
+
+func getServedName(t *testing.T, reloader *Reloader) string {
+	conf, err := reloader.getConfigForClient(nil)
+	assert.Equal(t, nil, err, "failed to get the config of the handshake")
+
+	cert, _ := x509.ParseCertificate(conf.Certificates[0].Certificate[0])
+	return cert.Subject.CommonName
+}
+
+func TestNewReloader(t *testing.T) {
+	_, err := NewReloader(Config{CertFile: "/not/existed", KeyFile: "/not/existed"}, zap.NewNop().Sugar())
+	assert.NotEqual(t, nil, err, "failed to reject the missing files")
+
+	_, err = NewReloader(Config{CipherSuites: []string{"unknown"}}, zap.NewNop().Sugar())
+	assert.NotEqual(t, nil, err, "failed to reject the unknown cipher suite")
+}
+
+func TestMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCert(t, pkix.Name{CommonName: "ca"}, nil, x509.ExtKeyUsageAny)
+	server := newTestCert(t, pkix.Name{CommonName: "server"}, ca, x509.ExtKeyUsageServerAuth)
+	client := newTestCert(t, pkix.Name{CommonName: "svc-a", OrganizationalUnit: []string{"demo"}}, ca, x509.ExtKeyUsageClientAuth)
+
+	conf := Config{
+		CertFile:     filepath.Join(dir, "tls.crt"),
+		KeyFile:      filepath.Join(dir, "tls.key"),
+		ClientCAFile: filepath.Join(dir, "ca.crt"),
+	}
+	server.write(t, conf.CertFile, conf.KeyFile)
+	assert.Equal(t, nil, os.WriteFile(conf.ClientCAFile, ca.pem, 0600), "failed to write CAs")
+
+	reloader, err := NewReloader(conf, zap.NewNop().Sugar())
+	assert.Equal(t, nil, err, "failed to load the tls files")
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		applicant, _ := GetApplicant(r.TLS)
+		_, _ = w.Write([]byte(applicant.ID + "/" + applicant.Project))
+	})
+
+	listener := httptest.NewUnstartedServer(handler)
+	listener.TLS = reloader.TLSConfig("h2", "http/1.1")
+	listener.EnableHTTP2 = true
+	listener.StartTLS()
+	defer listener.Close()
+
+	clientFile, clientKeyFile := filepath.Join(dir, "client.crt"), filepath.Join(dir, "client.key")
+	client.write(t, clientFile, clientKeyFile)
+	clientCert, _ := tls.LoadX509KeyPair(clientFile, clientKeyFile)
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+
+	transport := &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool, ServerName: "localhost"}}
+	_, err = (&http.Client{Transport: transport}).Get(listener.URL + "/peer")
+	assert.NotEqual(t, nil, err, "failed to reject the client without certificate")
+
+	transport = &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool, ServerName: "localhost", Certificates: []tls.Certificate{clientCert}}, ForceAttemptHTTP2: true}
+	response, err := (&http.Client{Transport: transport}).Get(listener.URL + "/peer")
+	assert.Equal(t, nil, err, "failed to accept the client certificate")
+	defer response.Body.Close()
+	assert.Equal(t, 2, response.ProtoMajor, "failed to negotiate http/2")
+
+	body := make([]byte, 64)
+	n, _ := response.Body.Read(body)
+	assert.Equal(t, "svc-a/demo", string(body[:n]), "failed to map the client certificate into the applicant")
+}
+
+func TestCertRotation(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCert(t, pkix.Name{CommonName: "ca"}, nil, x509.ExtKeyUsageAny)
+	conf := Config{CertFile: filepath.Join(dir, "tls.crt"), KeyFile: filepath.Join(dir, "tls.key")}
+	newTestCert(t, pkix.Name{CommonName: "before"}, ca, x509.ExtKeyUsageServerAuth).write(t, conf.CertFile, conf.KeyFile)
+
+	reloader, err := NewReloader(conf, zap.NewNop().Sugar())
+	assert.Equal(t, nil, err, "failed to load the tls files")
+	assert.Equal(t, nil, reloader.Watch(), "failed to watch the tls files")
+	defer reloader.Close()
+
+	assert.Equal(t, "before", getServedName(t, reloader), "failed to serve the loaded certificate")
+
+	newTestCert(t, pkix.Name{CommonName: "after"}, ca, x509.ExtKeyUsageServerAuth).write(t, conf.CertFile, conf.KeyFile)
+	assert.Eventually(t, func() bool {
+		return getServedName(t, reloader) == "after"
+	}, 3*time.Second, 100*time.Millisecond, "failed to reload the rotated certificate")
+
+	assert.Equal(t, nil, os.WriteFile(conf.CertFile, []byte("broken"), 0600), "failed to break the certificate")
+	reloader.reload()
+	assert.Equal(t, "after", getServedName(t, reloader), "failed to keep the loaded certificate")
+}
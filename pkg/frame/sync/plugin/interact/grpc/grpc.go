@@ -0,0 +1,338 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/bigstack-oss/plane-go/pkg/base/log"
+	"github.com/bigstack-oss/plane-go/pkg/base/protocol"
+	"github.com/bigstack-oss/plane-go/pkg/frame/sync/metric"
+	"github.com/bigstack-oss/plane-go/pkg/frame/sync/plugin/interact"
+	"github.com/bigstack-oss/plane-go/pkg/frame/sync/plugin/interact/certs"
+	"github.com/bigstack-oss/plane-go/pkg/frame/sync/plugin/plug"
+	"github.com/bigstack-oss/plane-go/pkg/frame/sync/plugin/policy"
+	"github.com/goinggo/mapstructure"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/reflection"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	reflectionalphapb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"gopkg.in/go-playground/validator.v9"
+)
+
+const (
+	module = "grpc"
+)
+
+// Grpc serves the interfaces as the methods of the job service, every
+// method binds the request into a job, runs the stages of the interface and
+// responds with the job. the applicant of the job is the one of the verified
+// client certificate if there is, and the jobs are authorized by the policy
+// like the ones of the http interfaces. the middlewares and the request
+// validation of the http interfaces are not applied, so the clients are only
+// authenticated by tls, and the requests are neither rate limited nor
+// validated before the stages
+type Grpc struct {
+	server   *grpc.Server
+	health   *health.Server
+	reloader *certs.Reloader
+	policy   *policy.Engine
+	errs     []error
+	config
+
+	log  *zap.Logger
+	logf *zap.SugaredLogger
+}
+
+type config struct {
+	Name        string `validate:"required"`
+	Address     string `validate:"required"`
+	Port        int    `validate:"required"`
+	Tls         certs.Config
+	Reflection  bool
+	HealthCheck bool
+	Policy      map[string]interface{}
+	Interfaces  []Interface `validate:"dive"`
+}
+
+// Interface is served as Method of the job service
+type Interface struct {
+	Name    string `validate:"required"`
+	Method  string `validate:"required"`
+	Timeout int    `validate:"min=0"`
+	Stages  []Stage
+}
+
+// Stage is a stage of the interface, or a parallel block of stages
+type Stage struct {
+	Name     string
+	Parallel []Stage
+}
+
+func init() {
+	registerModule()
+}
+
+func registerModule() {
+	interact.Plugins[module] = &Grpc{}
+}
+
+// newExecutor appends the stages set for the interface in order, the stages
+// are run by the chain shared with the http interfaces
+func newExecutor(i Interface) *plug.Chain {
+	executor := &plug.Chain{}
+	for index, s := range i.Stages {
+		stageName := s.Name
+		if s.Parallel != nil {
+			stageName = plug.Parallel
+		}
+
+		executor.AppendStage(fmt.Sprintf("%s-%s-%d", i.Name, stageName, index))
+	}
+
+	return executor
+}
+
+// getStatus converts the error of the stages into a status, the stages can
+// return a status error to choose the code
+func getStatus(err error) error {
+	if _, isStatus := status.FromError(err); isStatus {
+		return err
+	}
+
+	switch {
+	case errors.Is(err, policy.ErrDenied):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
+
+// getPeerApplicant returns the applicant of the verified client certificate
+func getPeerApplicant(ctx context.Context) (*protocol.Applicant, bool) {
+	p, isExisted := peer.FromContext(ctx)
+	if !isExisted {
+		return nil, false
+	}
+
+	info, isTLS := p.AuthInfo.(credentials.TLSInfo)
+	if !isTLS {
+		return nil, false
+	}
+
+	return certs.GetApplicant(&info.State)
+}
+
+// execute authorizes the job and runs the stages of the interface with it
+// until the timeout of the interface, the job is not responded once the
+// deadline passed since the stages given up may still be running with it.
+// the applicant in the request is never trusted, and the job is authorized
+// as a POST request to the full method, which is how grpc is carried over
+// http/2
+func (g *Grpc) execute(ctx context.Context, i Interface, executor *plug.Chain, job *protocol.Job) (*dynamicpb.Message, error) {
+	job.Applicant = nil
+	if applicant, isVerified := getPeerApplicant(ctx); isVerified {
+		job.Applicant = applicant
+	}
+
+	if g.policy != nil {
+		ctx = policy.NewContext(ctx, g.policy, i.Name, http.MethodPost, fmt.Sprintf("/%s/%s", ServiceName, i.Method))
+	}
+
+	err := policy.Authorize(ctx, job)
+	if err != nil {
+		return nil, getStatus(err)
+	}
+	ctx = policy.WithAuthorized(ctx)
+
+	if i.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(i.Timeout)*time.Second)
+		defer cancel()
+	}
+
+	err = executor.Execute(ctx, job)
+	if err != nil {
+		return nil, getStatus(err)
+	}
+
+	response, err := NewJobMessage(job)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to convert job. error: %s", err.Error())
+	}
+
+	return response, nil
+}
+
+// newMethod returns the handler of the method serving the interface, the
+// requests are measured like the ones of the http interfaces
+func (g *Grpc) newMethod(i Interface) grpc.MethodDesc {
+	executor := newExecutor(i)
+	fullMethod := fmt.Sprintf("/%s/%s", ServiceName, i.Method)
+	handle := func(ctx context.Context, request interface{}) (interface{}, error) {
+		return g.execute(ctx, i, executor, request.(*protocol.Job))
+	}
+
+	return grpc.MethodDesc{
+		MethodName: i.Method,
+		Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+			request := metric.StartRequest(i.Name)
+			response, err := g.handle(ctx, dec, interceptor, fullMethod, handle)
+			request.Done(int(status.Code(err)))
+			return response, err
+		},
+	}
+}
+
+func (g *Grpc) handle(ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor, fullMethod string, handle grpc.UnaryHandler) (interface{}, error) {
+	message := dynamicpb.NewMessage(jobDescriptor)
+	err := dec(message)
+	if err != nil {
+		return nil, err
+	}
+
+	job, err := ToJob(message)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "failed to convert job. error: %s", err.Error())
+	}
+
+	if interceptor == nil {
+		return handle(ctx, job)
+	}
+
+	return interceptor(ctx, job, &grpc.UnaryServerInfo{Server: g, FullMethod: fullMethod}, handle)
+}
+
+// newPolicy creates the policy engine authorizing the jobs of every
+// interface, the jobs are not authorized if there is no policy
+func (g *Grpc) newPolicy() {
+	g.policy = nil
+	if g.Policy == nil {
+		return
+	}
+
+	engine, err := policy.New(g.Policy)
+	if err != nil {
+		g.errs = append(g.errs, err)
+		return
+	}
+
+	g.policy = engine
+}
+
+// setServer registers the job service with the methods of the interfaces,
+// and the health checking and the reflection if they are enabled
+func (g *Grpc) setServer() {
+	options := []grpc.ServerOption{}
+	if g.Tls.IsEnabled() {
+		reloader, err := certs.NewReloader(g.Tls, g.logf)
+		if err != nil {
+			g.errs = append(g.errs, fmt.Errorf("failed to set tls. error: %w", err))
+			return
+		}
+
+		g.reloader = reloader
+		options = append(options, grpc.Creds(credentials.NewTLS(reloader.TLSConfig("h2"))))
+	}
+
+	methods := []string{}
+	service := grpc.ServiceDesc{
+		ServiceName: ServiceName,
+		HandlerType: (*interface{})(nil),
+		Metadata:    serviceFile,
+	}
+	for _, i := range g.Interfaces {
+		methods = append(methods, i.Method)
+		service.Methods = append(service.Methods, g.newMethod(i))
+	}
+
+	files, err := newServiceFiles(methods)
+	if err != nil {
+		g.errs = append(g.errs, err)
+		return
+	}
+
+	g.server = grpc.NewServer(options...)
+	g.server.RegisterService(&service, g)
+
+	if g.HealthCheck {
+		g.health = health.NewServer()
+		g.health.SetServingStatus(ServiceName, healthpb.HealthCheckResponse_SERVING)
+		healthpb.RegisterHealthServer(g.server, g.health)
+	}
+
+	if g.Reflection {
+		reflectionOptions := reflection.ServerOptions{Services: g.server, DescriptorResolver: &resolver{files: files}}
+		reflectionpb.RegisterServerReflectionServer(g.server, reflection.NewServerV1(reflectionOptions))
+		reflectionalphapb.RegisterServerReflectionServer(g.server, reflection.NewServer(reflectionOptions))
+	}
+}
+
+func (g *Grpc) SetConfig(conf interface{}) {
+	_ = mapstructure.Decode(conf, &g.config)
+	g.log = log.GetLogger(module)
+	g.logf = g.log.Sugar()
+	g.errs = nil
+	g.server, g.health, g.reloader = nil, nil, nil
+
+	g.newPolicy()
+	g.setServer()
+}
+
+// CheckConfig also reports the server which failed to be set
+func (g *Grpc) CheckConfig() error {
+	err := validator.New().Struct(g.config)
+	return errors.Join(append([]error{err}, g.errs...)...)
+}
+
+// Serve serves the job service on the listener until the plugin is stopped
+func (g *Grpc) Serve(listener net.Listener) error {
+	if g.reloader != nil {
+		err := g.reloader.Watch()
+		if err != nil {
+			g.logf.Errorf("failed to watch tls files, they are not reloaded. error: %s", err.Error())
+		}
+
+		defer g.reloader.Close()
+	}
+
+	return g.server.Serve(listener)
+}
+
+func (g *Grpc) DoInteract() {
+	listener, err := net.Listen("tcp", fmt.Sprintf("%s:%d", g.Address, g.Port))
+	if err != nil {
+		g.logf.Errorf("error details of start grpc listener: %s", err.Error())
+		return
+	}
+
+	err = g.Serve(listener)
+	if err != nil {
+		g.logf.Errorf("error details of start grpc listener: %s", err.Error())
+	}
+}
+
+// Stop reports not serving to the health checking, and waits for the
+// requests in flight
+func (g *Grpc) Stop() {
+	if g.health != nil {
+		g.health.Shutdown()
+	}
+
+	g.server.GracefulStop()
+}
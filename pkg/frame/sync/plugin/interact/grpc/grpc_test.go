@@ -0,0 +1,190 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/bigstack-oss/plane-go/pkg/base/protocol"
+	"github.com/bigstack-oss/plane-go/pkg/frame/sync/plugin/plug"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+type testStager struct {
+	isHung bool
+	err    error
+}
+
+func (s *testStager) SetConfig(interface{}) {}
+
+func (s *testStager) CheckConfig() error {
+	return nil
+}
+
+func (s *testStager) Execute(job *protocol.Job) (bool, error) {
+	if s.isHung {
+		time.Sleep(time.Second)
+	}
+
+	job.Result = &protocol.Result{Status: "done", Desc: job.Desired.Operation}
+	return true, s.err
+}
+
+func newTestInterface(interfaceName string, method string, stager plug.Stager) map[string]interface{} {
+	plug.Stagers[interfaceName+"-test-0"] = stager
+	return map[string]interface{}{
+		"name":   interfaceName,
+		"method": method,
+		"stages": []interface{}{map[string]interface{}{"name": "test"}},
+	}
+}
+
+func newTestConfig() map[string]interface{} {
+	return map[string]interface{}{
+		"name":        module,
+		"address":     "127.0.0.1",
+		"port":        9090,
+		"reflection":  true,
+		"healthCheck": true,
+		"interfaces": []interface{}{
+			newTestInterface("create", "Create", &testStager{}),
+			newTestInterface("hang", "Hang", &testStager{isHung: true}),
+			newTestInterface("conflict", "Conflict", &testStager{err: status.Error(codes.AlreadyExists, "existed")}),
+			newTestInterface("fail", "Fail", &testStager{err: errors.New("failed")}),
+		},
+	}
+}
+
+func newTestClient(t *testing.T) *grpc.ClientConn {
+	return newTestClientWith(t, newTestConfig())
+}
+
+func newTestClientWith(t *testing.T, conf map[string]interface{}) *grpc.ClientConn {
+	g := &Grpc{}
+	g.SetConfig(conf)
+	assert.Equal(t, nil, g.CheckConfig(), "failed to set the grpc plugin")
+
+	listener := bufconn.Listen(1 << 20)
+	go func() {
+		_ = g.Serve(listener)
+	}()
+	t.Cleanup(g.Stop)
+
+	dialer := func(ctx context.Context, _ string) (net.Conn, error) {
+		return listener.DialContext(ctx)
+	}
+	conn, err := grpc.NewClient("passthrough:///bufnet", grpc.WithContextDialer(dialer), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	assert.Equal(t, nil, err, "failed to dial the grpc plugin")
+	t.Cleanup(func() {
+		conn.Close()
+	})
+
+	return conn
+}
+
+func invoke(ctx context.Context, conn *grpc.ClientConn, method string, job *protocol.Job) (*protocol.Job, error) {
+	request, err := NewJobMessage(job)
+	if err != nil {
+		return nil, err
+	}
+
+	response := dynamicpb.NewMessage(jobDescriptor)
+	err = conn.Invoke(ctx, "/"+ServiceName+"/"+method, request, response)
+	if err != nil {
+		return nil, err
+	}
+
+	return ToJob(response)
+}
+
+func TestCheckConfig(t *testing.T) {
+	g := &Grpc{}
+	g.SetConfig(map[string]interface{}{
+		"name":       module,
+		"address":    "127.0.0.1",
+		"port":       9090,
+		"interfaces": []interface{}{map[string]interface{}{"name": "invalid", "method": "not-a-method"}},
+	})
+	assert.NotEqual(t, nil, g.CheckConfig(), "failed to reject the invalid method")
+}
+
+func TestInvoke(t *testing.T) {
+	conn := newTestClient(t)
+	job := &protocol.Job{
+		ID:        "job-1",
+		Applicant: &protocol.Applicant{ID: "user-1", Roles: []string{"admin"}},
+		Desired:   &protocol.Desired{Operation: "create", Resource: &protocol.Resource{Type: "vm", Name: "vm-1"}},
+	}
+
+	message, err := NewJobMessage(job)
+	assert.Equal(t, nil, err, "failed to convert the job")
+	converted, err := ToJob(message)
+	assert.Equal(t, nil, err, "failed to convert the message")
+	assert.Equal(t, []string{"admin"}, converted.Applicant.Roles, "failed to convert the applicant")
+
+	response, err := invoke(context.Background(), conn, "Create", job)
+	assert.Equal(t, nil, err, "failed to invoke the interface")
+	assert.Equal(t, "job-1", response.ID, "failed to bind the request into the job")
+	assert.Equal(t, (*protocol.Applicant)(nil), response.Applicant, "failed to drop the applicant without a verified certificate")
+	assert.Equal(t, &protocol.Result{Status: "done", Desc: "create"}, response.Result, "failed to respond with the result of the stages")
+}
+
+func TestInvokeError(t *testing.T) {
+	conn := newTestClient(t)
+	job := &protocol.Job{Desired: &protocol.Desired{}}
+
+	_, err := invoke(context.Background(), conn, "Conflict", job)
+	assert.Equal(t, codes.AlreadyExists, status.Code(err), "failed to keep the status of the stage")
+
+	_, err = invoke(context.Background(), conn, "Fail", job)
+	assert.Equal(t, codes.Internal, status.Code(err), "failed to respond with internal by default")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = invoke(ctx, conn, "Hang", job)
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(err), "failed to give up once the deadline passed")
+
+	_, err = invoke(context.Background(), conn, "Missing", job)
+	assert.Equal(t, codes.Unimplemented, status.Code(err), "failed to reject the method not configured")
+}
+
+func TestInvokeDenied(t *testing.T) {
+	conf := newTestConfig()
+	conf["policy"] = map[string]interface{}{"default": "allow"}
+	conn := newTestClientWith(t, conf)
+
+	_, err := invoke(context.Background(), conn, "Create", &protocol.Job{Desired: &protocol.Desired{Operation: "create"}})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err), "failed to deny the job without a verified applicant")
+}
+
+func TestHealthCheck(t *testing.T) {
+	conn := newTestClient(t)
+	response, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{Service: ServiceName})
+	assert.Equal(t, nil, err, "failed to check health")
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, response.Status, "failed to report serving")
+}
+
+func TestReflection(t *testing.T) {
+	conn := newTestClient(t)
+	stream, err := reflectionpb.NewServerReflectionClient(conn).ServerReflectionInfo(context.Background())
+	assert.Equal(t, nil, err, "failed to open reflection")
+
+	err = stream.Send(&reflectionpb.ServerReflectionRequest{
+		MessageRequest: &reflectionpb.ServerReflectionRequest_FileContainingSymbol{FileContainingSymbol: ServiceName + ".Create"},
+	})
+	assert.Equal(t, nil, err, "failed to request the descriptor")
+
+	response, err := stream.Recv()
+	assert.Equal(t, nil, err, "failed to receive the descriptor")
+	assert.Equal(t, 2, len(response.GetFileDescriptorResponse().GetFileDescriptorProto()), "failed to describe the service and the job")
+}
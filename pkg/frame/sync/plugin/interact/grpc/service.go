@@ -0,0 +1,205 @@
+package grpc
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/bigstack-oss/plane-go/pkg/base/protocol"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+const (
+	protoPackage = "plane.sync.v1"
+	jobFile      = "plane/sync/v1/job.proto"
+	serviceFile  = "plane/sync/v1/job_service.proto"
+	jobMessage   = "." + protoPackage + ".Job"
+
+	// ServiceName is the service whose methods are the interfaces, every
+	// method takes a job and returns the job run by the stages
+	ServiceName = protoPackage + ".JobService"
+)
+
+var (
+	jobFileDescriptor = mustNewJobFile()
+	jobDescriptor     = jobFileDescriptor.Messages().ByName("Job")
+)
+
+// resolver finds the descriptors of the job service, and the ones of the
+// services registered globally like health checking
+type resolver struct {
+	files *protoregistry.Files
+}
+
+func field(fieldName string, number int32, fieldType descriptorpb.FieldDescriptorProto_Type, typeName string) *descriptorpb.FieldDescriptorProto {
+	f := &descriptorpb.FieldDescriptorProto{
+		Name:   proto.String(fieldName),
+		Number: proto.Int32(number),
+		Type:   fieldType.Enum(),
+		Label:  descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+	}
+	if typeName != "" {
+		f.TypeName = proto.String("." + protoPackage + "." + typeName)
+	}
+
+	return f
+}
+
+func message(messageName string, fields ...*descriptorpb.FieldDescriptorProto) *descriptorpb.DescriptorProto {
+	return &descriptorpb.DescriptorProto{Name: proto.String(messageName), Field: fields}
+}
+
+// mustNewJobFile describes protocol.Job in proto, the fields are named after
+// the json names of the job
+func mustNewJobFile() protoreflect.FileDescriptor {
+	const (
+		stringType  = descriptorpb.FieldDescriptorProto_TYPE_STRING
+		int32Type   = descriptorpb.FieldDescriptorProto_TYPE_INT32
+		bytesType   = descriptorpb.FieldDescriptorProto_TYPE_BYTES
+		messageType = descriptorpb.FieldDescriptorProto_TYPE_MESSAGE
+	)
+
+	roles := field("roles", 8, stringType, "")
+	roles.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+
+	file := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String(jobFile),
+		Package: proto.String(protoPackage),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			message("Job",
+				field("id", 1, stringType, ""),
+				field("version", 2, int32Type, ""),
+				field("applicant", 3, messageType, "Applicant"),
+				field("desired", 4, messageType, "Desired"),
+				field("result", 5, messageType, "Result"),
+			),
+			message("Applicant",
+				field("id", 1, stringType, ""),
+				field("name", 2, stringType, ""),
+				field("project", 3, stringType, ""),
+				field("email", 4, stringType, ""),
+				field("country", 5, stringType, ""),
+				field("company", 6, stringType, ""),
+				field("industry", 7, stringType, ""),
+				roles,
+			),
+			message("Desired",
+				field("operation", 1, stringType, ""),
+				field("resource", 2, messageType, "Resource"),
+			),
+			message("Resource",
+				field("type", 1, stringType, ""),
+				field("name", 2, stringType, ""),
+			),
+			message("Result",
+				field("status", 1, stringType, ""),
+				field("desc", 2, stringType, ""),
+				field("data", 3, bytesType, ""),
+			),
+		},
+	}
+
+	descriptor, err := protodesc.NewFile(file, nil)
+	if err != nil {
+		panic(fmt.Sprintf("failed to describe job. error: %s", err.Error()))
+	}
+
+	return descriptor
+}
+
+// newServiceFiles describes the job service with a method for every name in
+// methods, the files of the job and the service are returned for the
+// reflection
+func newServiceFiles(methods []string) (*protoregistry.Files, error) {
+	service := &descriptorpb.ServiceDescriptorProto{Name: proto.String("JobService")}
+	for _, method := range methods {
+		service.Method = append(service.Method, &descriptorpb.MethodDescriptorProto{
+			Name:       proto.String(method),
+			InputType:  proto.String(jobMessage),
+			OutputType: proto.String(jobMessage),
+		})
+	}
+
+	files := &protoregistry.Files{}
+	err := files.RegisterFile(jobFileDescriptor)
+	if err != nil {
+		return nil, err
+	}
+
+	file := &descriptorpb.FileDescriptorProto{
+		Name:       proto.String(serviceFile),
+		Package:    proto.String(protoPackage),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{jobFile},
+		Service:    []*descriptorpb.ServiceDescriptorProto{service},
+	}
+
+	descriptor, err := protodesc.NewFile(file, files)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe service. error: %w", err)
+	}
+
+	err = files.RegisterFile(descriptor)
+	if err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
+
+func (r *resolver) FindFileByPath(path string) (protoreflect.FileDescriptor, error) {
+	file, err := r.files.FindFileByPath(path)
+	if err == nil {
+		return file, nil
+	}
+
+	return protoregistry.GlobalFiles.FindFileByPath(path)
+}
+
+func (r *resolver) FindDescriptorByName(name protoreflect.FullName) (protoreflect.Descriptor, error) {
+	descriptor, err := r.files.FindDescriptorByName(name)
+	if err == nil {
+		return descriptor, nil
+	}
+
+	return protoregistry.GlobalFiles.FindDescriptorByName(name)
+}
+
+// NewJobMessage converts the job into the message taken by the methods of
+// the job service
+func NewJobMessage(job *protocol.Job) (*dynamicpb.Message, error) {
+	b, err := json.Marshal(job)
+	if err != nil {
+		return nil, err
+	}
+
+	message := dynamicpb.NewMessage(jobDescriptor)
+	err = protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(b, message)
+	if err != nil {
+		return nil, err
+	}
+
+	return message, nil
+}
+
+// ToJob converts the message of the job service back into the job
+func ToJob(message proto.Message) (*protocol.Job, error) {
+	b, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(message)
+	if err != nil {
+		return nil, err
+	}
+
+	job := &protocol.Job{}
+	err = json.Unmarshal(b, job)
+	if err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
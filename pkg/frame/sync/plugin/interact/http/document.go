@@ -0,0 +1,181 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strings"
+
+	"github.com/bigstack-oss/plane-go/pkg/base/protocol"
+	"github.com/bigstack-oss/plane-go/pkg/frame/sync/plugin"
+	"github.com/bigstack-oss/plane-go/pkg/frame/sync/plugin/interact/http/interfacehttp"
+	"github.com/bigstack-oss/plane-go/pkg/frame/sync/plugin/interact/http/openapi"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	documentPath   = "/openapi.json"
+	documentAssets = "https://unpkg.com/swagger-ui-dist@5"
+	defaultVersion = "1.0.0"
+)
+
+var (
+	swaggerUI = template.Must(template.New("swaggerUI").Parse(`<!DOCTYPE html>
+<html>
+<head>
+  <title>{{.Title}}</title>
+  <link rel="stylesheet" href="{{.Assets}}/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="{{.Assets}}/swagger-ui-bundle.js"></script>
+  <script>SwaggerUIBundle({url: "{{.Document}}", dom_id: "#swagger-ui"});</script>
+</body>
+</html>
+`))
+)
+
+// documentConfig serves the openapi document of the interfaces if Enabled,
+// and the swagger ui at SwaggerUI if it is set. the ui loads its assets from
+// SwaggerAssets, which can be replaced with a mirror
+type documentConfig struct {
+	Enabled       bool
+	Title         string
+	Version       string
+	Path          string
+	SwaggerUI     string
+	SwaggerAssets string
+}
+
+func (d documentConfig) withDefaults() documentConfig {
+	if d.Title == "" {
+		d.Title = plugin.Service
+	}
+	if d.Version == "" {
+		d.Version = defaultVersion
+	}
+	if d.Path == "" {
+		d.Path = documentPath
+	}
+	if d.SwaggerAssets == "" {
+		d.SwaggerAssets = documentAssets
+	}
+
+	return d
+}
+
+func getStageNames(stages []Stage) []string {
+	names := []string{}
+	for _, s := range stages {
+		if s.Parallel == nil {
+			names = append(names, s.Name)
+			continue
+		}
+
+		names = append(names, fmt.Sprintf("parallel(%s)", strings.Join(getStageNames(s.Parallel), ",")))
+	}
+
+	return names
+}
+
+func getMiddlewareNames(confs []map[string]interface{}) []string {
+	names := []string{}
+	for _, conf := range confs {
+		middlewareName, _ := conf["name"].(string)
+		names = append(names, middlewareName)
+	}
+
+	return names
+}
+
+func (h *Http) newDocument() {
+	h.openapi = nil
+	if !h.Openapi.Enabled {
+		return
+	}
+
+	conf := h.Openapi.withDefaults()
+	h.openapi = openapi.NewDocument(conf.Title, conf.Version)
+}
+
+// document describes the interface in the openapi document, the request
+// declared in config replaces the one of the interface, and the jobs of the
+// async interfaces are answered with 202
+func (h *Http) document(handler interfacehttp.Interface, i Interface, validator interfacehttp.RequestValidator) {
+	if h.openapi == nil {
+		return
+	}
+
+	schema := openapi.Schema{}
+	if schemer, isSchemer := handler.(interfacehttp.Schemer); isSchemer {
+		schema = schemer.Schema()
+	}
+	if validator != nil {
+		schema.Request = validator.Schema()
+	}
+
+	mode := i.Mode
+	if mode == modeAsync {
+		schema.Status = http.StatusAccepted
+		schema.Response = protocol.Job{}
+	}
+
+	extensions := map[string]interface{}{"stages": getStageNames(i.Stages)}
+	if mode != "" {
+		extensions["mode"] = mode
+	}
+	if len(i.Middlewares) > 0 {
+		extensions["middlewares"] = getMiddlewareNames(i.Middlewares)
+	}
+
+	h.openapi.AddOperation(openapi.Operation{
+		Method:     i.Method,
+		Path:       i.Path,
+		Name:       i.Name,
+		Schema:     schema,
+		Extensions: extensions,
+	})
+}
+
+// setDocument serves the openapi document built from the interfaces, and
+// the swagger ui if it is configured
+func (h *Http) setDocument() {
+	if h.openapi == nil {
+		return
+	}
+
+	conf := h.Openapi.withDefaults()
+	if h.store != nil {
+		h.openapi.AddOperation(openapi.Operation{
+			Method: http.MethodGet,
+			Path:   interfacehttp.JobsPath + "/:id",
+			Name:   "getJob",
+			Schema: openapi.Schema{Summary: "get the job run in the background", Response: protocol.Job{}},
+		})
+	}
+
+	document, err := json.Marshal(h.openapi)
+	if err != nil {
+		h.errs = append(h.errs, fmt.Errorf("failed to build openapi document. error: %w", err))
+		return
+	}
+
+	h.router.GET(conf.Path, func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/json", document)
+	})
+
+	if conf.SwaggerUI == "" {
+		return
+	}
+
+	h.router.GET(conf.SwaggerUI, func(c *gin.Context) {
+		c.Status(http.StatusOK)
+		c.Header("Content-Type", "text/html; charset=utf-8")
+		_ = swaggerUI.Execute(c.Writer, map[string]string{
+			"Title":    conf.Title,
+			"Assets":   conf.SwaggerAssets,
+			"Document": conf.Path,
+		})
+	})
+}
@@ -2,34 +2,60 @@ package http
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"reflect"
+	"time"
 
 	"github.com/bigstack-oss/plane-go/pkg/base/log"
+	"github.com/bigstack-oss/plane-go/pkg/frame/sync/metric"
 	"github.com/bigstack-oss/plane-go/pkg/frame/sync/plugin/interact"
+	"github.com/bigstack-oss/plane-go/pkg/frame/sync/plugin/interact/certs"
 	"github.com/bigstack-oss/plane-go/pkg/frame/sync/plugin/interact/http/interfacehttp"
+	"github.com/bigstack-oss/plane-go/pkg/frame/sync/plugin/interact/http/middleware"
+	"github.com/bigstack-oss/plane-go/pkg/frame/sync/plugin/interact/http/openapi"
+	"github.com/bigstack-oss/plane-go/pkg/frame/sync/plugin/jobstore"
 	"github.com/bigstack-oss/plane-go/pkg/frame/sync/plugin/plug"
+	"github.com/bigstack-oss/plane-go/pkg/frame/sync/plugin/policy"
 	"github.com/gin-gonic/gin"
 	"github.com/goinggo/mapstructure"
+	"github.com/mohae/deepcopy"
 	"go.uber.org/zap"
 	"gopkg.in/go-playground/validator.v9"
 )
 
 const (
 	module = "http"
+
+	modeAsync = "async"
 )
 
 var (
 	Router *gin.Engine
+
+	lastStore sharedStore
 )
 
+// sharedStore is the job store with the conf it was created with
+type sharedStore struct {
+	conf  map[string]interface{}
+	store jobstore.Store
+}
+
 type Http struct {
 	ctx    context.Context
 	cancel context.CancelFunc
 
 	listener plug.Listener
 	router   *gin.Engine
+	routes   map[string]Interface
+	policy   *policy.Engine
+	store    jobstore.Store
+	cleaner  *jobstore.Cleaner
+	openapi  *openapi.Document
+	errs     []error
 	config
 
 	log  *zap.Logger
@@ -37,22 +63,33 @@ type Http struct {
 }
 
 type config struct {
-	Name       string `validate:"required"`
-	Address    string `validate:"required"`
-	Port       int    `validate:"required"`
-	Interfaces []Interface
+	Name        string `validate:"required"`
+	Address     string `validate:"required"`
+	Port        int    `validate:"required"`
+	Tls         certs.Config
+	Middlewares []map[string]interface{}
+	Policy      map[string]interface{}
+	JobStore    map[string]interface{}
+	Openapi     documentConfig
+	Interfaces  []Interface
 }
 
 type Interface struct {
-	Name    string `validate:"required"`
-	Method  string `validate:"required"`
-	Path    string `validate:"required"`
-	Timeout int
-	Stages  []Stage
+	Name        string `validate:"required"`
+	Method      string `validate:"required"`
+	Path        string `validate:"required"`
+	Timeout     int    `validate:"min=0"`
+	Mode        string `validate:"omitempty,oneof=sync async"`
+	JobTimeout  int    `validate:"min=0"`
+	Request     requestConfig
+	Middlewares []map[string]interface{}
+	Stages      []Stage
 }
 
+// Stage is a stage of the interface, or a parallel block of stages
 type Stage struct {
-	Name string
+	Name     string
+	Parallel []Stage
 }
 
 func init() {
@@ -63,63 +100,312 @@ func registerModule() {
 	interact.Plugins[module] = &Http{}
 }
 
+// setServer serves https if tls is configured, the plain http server is
+// kept if the tls files failed to be loaded, and the config is reported
 func (h *Http) setServer() {
 	socket := fmt.Sprintf("%s:%d", h.Address, h.Port)
-	h.listener = &http.Server{
+	server := &http.Server{
 		Addr:    socket,
 		Handler: h.router,
 	}
+
+	h.listener = server
+	if !h.Tls.IsEnabled() {
+		return
+	}
+
+	reloader, err := certs.NewReloader(h.Tls, h.logf)
+	if err != nil {
+		h.errs = append(h.errs, fmt.Errorf("failed to set tls. error: %w", err))
+		return
+	}
+
+	server.TLSConfig = reloader.TLSConfig("h2", "http/1.1")
+	h.listener = &tlsServer{Server: server, reloader: reloader, logf: h.logf}
+}
+
+func getRoute(method string, path string) string {
+	return fmt.Sprintf("%s %s", method, path)
 }
 
 func (h *Http) setRouter() {
 	gin.DefaultWriter = ioutil.Discard
 	h.router = gin.New()
-	h.router.Use(gin.Recovery())
+	h.router.Use(gin.Recovery(), h.measure, h.setDeadline, h.setPolicy, h.setPeer, h.setJobStore)
+	h.routes = make(map[string]Interface)
+
+	chain, err := middleware.NewChain(h.Middlewares)
+	if err != nil {
+		h.errs = append(h.errs, err)
+		return
+	}
+
+	h.router.Use(chain...)
+	if h.store == nil {
+		return
+	}
+
+	handlers, err := h.newJobHandlers()
+	if err != nil {
+		h.errs = append(h.errs, err)
+		return
+	}
+
+	h.router.GET(interfacehttp.JobsPath+"/:id", handlers...)
+}
+
+// newJobHandlers authenticates the callers asking for the jobs by the auth
+// middleware of the first async interface configured with one, unless the
+// auth middleware is global. the callers have to be authenticated if any of
+// the async interfaces is behind an auth middleware
+func (h *Http) newJobHandlers() (gin.HandlersChain, error) {
+	if _, isGlobal := middleware.GetAuthConfig(h.Middlewares); isGlobal {
+		return gin.HandlersChain{interfacehttp.NewJobHandler(h.store, true)}, nil
+	}
+
+	for _, i := range h.Interfaces {
+		conf, isExisted := middleware.GetAuthConfig(i.Middlewares)
+		if i.Mode != modeAsync || !isExisted {
+			continue
+		}
+
+		auth, err := middleware.New(conf)
+		if err != nil {
+			return nil, fmt.Errorf("interface(%s): %w", i.Name, err)
+		}
+
+		return gin.HandlersChain{auth.Handle, interfacehttp.NewJobHandler(h.store, true)}, nil
+	}
+
+	return gin.HandlersChain{interfacehttp.NewJobHandler(h.store, false)}, nil
+}
+
+// measure records the requests of the registered interfaces, and names the
+// interface for the middlewares. the requests matching no route are not
+// recorded
+func (h *Http) measure(c *gin.Context) {
+	i, isExisted := h.routes[getRoute(c.Request.Method, c.FullPath())]
+	if !isExisted {
+		c.Next()
+		return
+	}
+
+	c.Set(middleware.InterfaceKey, i.Name)
+	request := metric.StartRequest(i.Name)
+	c.Next()
+	request.Done(c.Writer.Status())
+}
+
+// setDeadline limits the context of the request to the timeout of the
+// interface, the stages give up once the deadline passes
+func (h *Http) setDeadline(c *gin.Context) {
+	i, isExisted := h.routes[getRoute(c.Request.Method, c.FullPath())]
+	if !isExisted || i.Timeout == 0 {
+		c.Next()
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), time.Duration(i.Timeout)*time.Second)
+	defer cancel()
+
+	c.Request = c.Request.WithContext(ctx)
+	c.Next()
+}
+
+// newPolicy creates the policy engine authorizing the requests of every
+// interface, the requests are not authorized if there is no policy
+func (h *Http) newPolicy() {
+	h.policy = nil
+	if h.Policy == nil {
+		return
+	}
+
+	engine, err := policy.New(h.Policy)
+	if err != nil {
+		h.errs = append(h.errs, err)
+		return
+	}
+
+	h.policy = engine
 }
 
-func (h *Http) setStageOrder(interfaceName string, stages []Stage) {
+// setPolicy puts the policy engine into the context of the request, so that
+// the jobs of every interface are authorized before the chain runs their
+// stages, whether the interface authorizes them itself or not
+func (h *Http) setPolicy(c *gin.Context) {
+	i, isExisted := h.routes[getRoute(c.Request.Method, c.FullPath())]
+	if !isExisted || h.policy == nil {
+		c.Next()
+		return
+	}
+
+	c.Request = c.Request.WithContext(policy.NewContext(c.Request.Context(), h.policy, i.Name, c.Request.Method, c.Request.URL.Path))
+	c.Next()
+}
+
+// newJobStore creates the store of the jobs run in the background, the jobs
+// are kept in memory if there is an async interface but no store configured.
+// the store of the plugin set last is taken over if the conf is the same, so
+// that the jobs accepted before a reload are still answered
+func (h *Http) newJobStore() {
+	h.store, h.cleaner = nil, nil
+	conf := h.JobStore
+	for _, i := range h.Interfaces {
+		if conf == nil && i.Mode == modeAsync {
+			conf = map[string]interface{}{"name": jobstore.Memory}
+		}
+	}
+	if conf == nil {
+		return
+	}
+
+	if lastStore.store != nil && reflect.DeepEqual(lastStore.conf, conf) {
+		h.store = lastStore.store
+		h.cleaner = jobstore.NewCleaner(h.store)
+		return
+	}
+
+	store, err := jobstore.New(conf)
+	if err != nil {
+		h.errs = append(h.errs, err)
+		return
+	}
+
+	h.store = store
+	h.cleaner = jobstore.NewCleaner(store)
+	lastStore = sharedStore{conf: conf, store: store}
+}
+
+// setJobStore puts the job store into the context of the requests of the
+// async interfaces, so that their jobs run in the background until the job
+// timeout of the interface or the plugin is stopped, rather than until the
+// timeout of the request
+func (h *Http) setJobStore(c *gin.Context) {
+	i, isExisted := h.routes[getRoute(c.Request.Method, c.FullPath())]
+	if !isExisted || i.Mode != modeAsync || h.store == nil {
+		c.Next()
+		return
+	}
+
+	ctx := jobstore.NewContext(c.Request.Context(), h.store)
+	ctx = interfacehttp.NewJobContext(ctx, h.ctx, time.Duration(i.JobTimeout)*time.Second)
+	c.Request = c.Request.WithContext(ctx)
+	c.Next()
+}
+
+func (h *Http) setStageOrder(handler interfacehttp.Interface, interfaceName string, stages []Stage) {
 	for i, s := range stages {
-		stage := fmt.Sprintf("%s-%s-%d", interfaceName, s.Name, i)
-		interfacehttp.Plugins[interfaceName].AppendStage(stage)
+		stageName := s.Name
+		if s.Parallel != nil {
+			stageName = plug.Parallel
+		}
+
+		stage := fmt.Sprintf("%s-%s-%d", interfaceName, stageName, i)
+		handler.AppendStage(stage)
 	}
 }
 
+// registerRouter lets the interface register its routes behind the global
+// middlewares and the middlewares of the interface, the body is validated
+// after the middlewares if the interface declares its request
+func (h *Http) registerRouter(handler interfacehttp.Interface, i Interface, chain gin.HandlersChain) error {
+	global := h.router.Handlers
+	defer func() {
+		h.router.Handlers = global
+	}()
+
+	h.router.Handlers = append(append(gin.HandlersChain{}, global...), chain...)
+	return handler.RegisterRouter(h.router, i.Method, i.Path)
+}
+
+// setStages registers a copy of every interface, so that a reloaded plugin
+// never shares the stages with the one it replaces
 func (h *Http) setStages() {
 	for _, i := range h.Interfaces {
-		err := interfacehttp.Plugins[i.Name].RegisterRouter(h.router, i.Method, i.Path)
+		template, isExisted := interfacehttp.Plugins[i.Name]
+		if !isExisted {
+			h.logf.Errorf("fail to register router. interface(%s) was not defined", i.Name)
+			continue
+		}
+
+		err := middleware.CheckOrder(append(append([]map[string]interface{}{}, h.Middlewares...), i.Middlewares...))
+		if err != nil {
+			h.errs = append(h.errs, fmt.Errorf("interface(%s): %w", i.Name, err))
+			continue
+		}
+
+		chain, err := middleware.NewChain(i.Middlewares)
+		if err != nil {
+			h.errs = append(h.errs, fmt.Errorf("interface(%s): %w", i.Name, err))
+			continue
+		}
+
+		validator, err := newRequestValidator(i.Request)
+		if err != nil {
+			h.errs = append(h.errs, fmt.Errorf("interface(%s): %w", i.Name, err))
+			continue
+		}
+		if validator != nil {
+			chain = append(chain, interfacehttp.ValidateRequest(validator))
+		}
+
+		handler := deepcopy.Copy(template).(interfacehttp.Interface)
+		handler.SetConfig()
+		err = h.registerRouter(handler, i, chain)
 		if err != nil {
 			h.logf.Errorf("fail to register router. error details: %s", err.Error())
 		}
 
-		h.setStageOrder(i.Name, i.Stages)
+		h.routes[getRoute(i.Method, i.Path)] = i
+		h.document(handler, i, validator)
+
+		h.setStageOrder(handler, i.Name, i.Stages)
 	}
 }
 
 func (h *Http) SetConfig(conf interface{}) {
 	_ = mapstructure.Decode(conf, &h.config)
 	h.ctx, h.cancel = context.WithCancel(context.Background())
+	h.log = log.GetLogger(module)
+	h.logf = h.log.Sugar()
+	h.errs = nil
 
+	h.newPolicy()
+	h.newJobStore()
+	h.newDocument()
 	h.setRouter()
 	h.setStages()
+	h.setDocument()
 	h.setServer()
-
-	h.log = log.GetLogger(module)
-	h.logf = h.log.Sugar()
 }
 
+// CheckConfig also reports the middlewares which failed to be set
 func (h *Http) CheckConfig() error {
-	return validator.New().Struct(h.config)
+	err := validator.New().Struct(h.config)
+	return errors.Join(append([]error{err}, h.errs...)...)
 }
 
 func (h *Http) DoInteract() {
+	if h.cleaner != nil {
+		h.cleaner.Start()
+	}
+
 	err := h.listener.ListenAndServe()
 	if err != nil {
 		h.logf.Errorf("error details of start http listener: %s", err.Error())
 	}
 }
 
+// Stop waits for the requests in flight, and cancels the jobs running in the
+// background, which are saved as failed
 func (h *Http) Stop() {
+	if h.cleaner != nil {
+		h.cleaner.Stop()
+	}
+
 	if err := h.listener.Shutdown(h.ctx); err != nil {
 		h.logf.Errorf("failed to stop interact plugin(%s). error: %s", module, err.Error())
 	}
+
+	h.cancel()
 }
@@ -0,0 +1,190 @@
+package interfacehttp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/bigstack-oss/plane-go/pkg/base/protocol"
+	"github.com/bigstack-oss/plane-go/pkg/frame/sync/plugin/interact/http/middleware"
+	"github.com/bigstack-oss/plane-go/pkg/frame/sync/plugin/jobstore"
+	"github.com/bigstack-oss/plane-go/pkg/frame/sync/plugin/plug"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/mohae/deepcopy"
+)
+
+func getStoreStatus(err error) int {
+	switch {
+	case errors.Is(err, jobstore.ErrExisted):
+		return http.StatusConflict
+	case errors.Is(err, jobstore.ErrInvalidID):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func copyJob(job *protocol.Job) *protocol.Job {
+	copied := deepcopy.Copy(*job).(protocol.Job)
+	copied.SetRequest(job.GetRequest())
+	return &copied
+}
+
+// finish puts the outcome into the result of the job, the statuses set by
+// the stages or the compensations are kept
+func finish(job *protocol.Job, err error) {
+	if job.Result == nil {
+		job.Result = &protocol.Result{}
+	}
+
+	status := job.Result.Status
+	isCompensated := status == plug.StatusCompensated || status == plug.StatusCompensationFailed
+	if err != nil && !isCompensated {
+		job.Result.Status = jobstore.StatusFailed
+		job.Result.Desc = err.Error()
+		return
+	}
+
+	if err == nil && (status == "" || status == jobstore.StatusRunning) {
+		job.Result.Status = jobstore.StatusSucceeded
+	}
+}
+
+type jobKey struct{}
+
+// jobScope is how long the jobs run in the background for a request
+type jobScope struct {
+	lifetime context.Context
+	timeout  time.Duration
+}
+
+// NewJobContext limits the jobs run in the background for the request to
+// timeout, and cancels them once lifetime is done. the jobs have no deadline
+// if timeout is not positive
+func NewJobContext(ctx context.Context, lifetime context.Context, timeout time.Duration) context.Context {
+	return context.WithValue(ctx, jobKey{}, jobScope{lifetime: lifetime, timeout: timeout})
+}
+
+// detach keeps the values of ctx, but neither its deadline nor its
+// cancellation which come with the request. the job gets its own timeout
+// and lifetime set by NewJobContext instead
+func detach(ctx context.Context) (context.Context, context.CancelFunc) {
+	s, _ := ctx.Value(jobKey{}).(jobScope)
+	detached, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	if s.lifetime != nil {
+		stop := context.AfterFunc(s.lifetime, cancel)
+		cancelDetached := cancel
+		cancel = func() {
+			stop()
+			cancelDetached()
+		}
+	}
+
+	if s.timeout <= 0 {
+		return detached, cancel
+	}
+
+	timed, cancelTimed := context.WithTimeout(detached, s.timeout)
+	return timed, func() {
+		cancelTimed()
+		cancel()
+	}
+}
+
+func save(store jobstore.Store, job *protocol.Job) {
+	err := store.Update(job)
+	if err != nil {
+		executorLoggerf.Errorf("failed to save job(%s). error: %s", job.ID, err.Error())
+	}
+}
+
+// accept keeps the job in the store and answers it with 202, the stages run
+// in the background until the timeout of the job
+func (e *Executor) accept(c *gin.Context, store jobstore.Store, job *protocol.Job, render func(*gin.Context, *protocol.Job, error)) {
+	if job.ID == "" {
+		job.ID = uuid.NewString()
+	}
+
+	job.Result = &protocol.Result{Status: jobstore.StatusAccepted}
+	err := store.Create(job)
+	if err != nil {
+		render(c, &protocol.Job{}, WithStatus(getStoreStatus(err), err))
+		return
+	}
+
+	ctx, cancel := detach(c.Request.Context())
+	go e.runInBackground(ctx, cancel, store, copyJob(job))
+
+	c.Header("Location", JobsPath+"/"+job.ID)
+	c.JSON(http.StatusAccepted, job)
+}
+
+// runInBackground saves the job after every stage, the job saved last is
+// taken once the deadline passed, since the stages given up may still be
+// running with the job
+func (e *Executor) runInBackground(ctx context.Context, cancel context.CancelFunc, store jobstore.Store, job *protocol.Job) {
+	defer cancel()
+
+	job.Result.Status = jobstore.StatusRunning
+	save(store, job)
+
+	last := copyJob(job)
+	err := e.ExecuteWith(ctx, job, func(job *protocol.Job) {
+		last = copyJob(job)
+		save(store, last)
+	})
+	if errors.Is(err, context.DeadlineExceeded) {
+		job = last
+	}
+
+	finish(job, err)
+	save(store, job)
+}
+
+// isOwner tells whether the applicant may read the job of the owner. the
+// jobs are shared within the project of the owner, or kept to the owner if
+// it has no project, and the jobs without an owner are only read by the
+// callers which are not authenticated either
+func isOwner(owner *protocol.Applicant, applicant *protocol.Applicant) bool {
+	if owner == nil || applicant == nil {
+		return owner == nil && applicant == nil
+	}
+
+	if owner.Project != "" {
+		return owner.Project == applicant.Project
+	}
+
+	return owner.ID == applicant.ID
+}
+
+// NewJobHandler answers the jobs run in the background by their ids, the
+// jobs of others are not found. the callers which are not authenticated are
+// rejected with 401 if isAuthRequired
+func NewJobHandler(store jobstore.Store, isAuthRequired bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		applicant, isAuthenticated := middleware.GetApplicant(c)
+		if isAuthRequired && !isAuthenticated {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "no credential was given"})
+			return
+		}
+
+		job, err := store.Get(c.Param("id"))
+		if errors.Is(err, jobstore.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		if !isOwner(job.Applicant, applicant) {
+			c.JSON(http.StatusNotFound, gin.H{"error": jobstore.ErrNotFound.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, job)
+	}
+}
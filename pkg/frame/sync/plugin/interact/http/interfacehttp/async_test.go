@@ -0,0 +1,132 @@
+package interfacehttp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bigstack-oss/plane-go/pkg/base/protocol"
+	"github.com/bigstack-oss/plane-go/pkg/frame/sync/plugin/jobstore"
+	"github.com/bigstack-oss/plane-go/pkg/frame/sync/plugin/plug"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func newAsyncRouter(t *testing.T, stagers ...*testStager) *gin.Engine {
+	store, err := jobstore.New(map[string]interface{}{"name": jobstore.Memory})
+	assert.Equal(t, nil, err, "failed to create the job store")
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Request = c.Request.WithContext(jobstore.NewContext(c.Request.Context(), store))
+	})
+	router.GET(JobsPath+"/:id", NewJobHandler(store, false))
+
+	e := &Executor{}
+	assert.Equal(t, nil, e.RegisterRouter(router, http.MethodPost, "/async"), "failed to register router")
+	for i, stager := range stagers {
+		name := "async-" + string(rune('a'+i))
+		plug.Stagers[name] = stager
+		e.AppendStage(name)
+	}
+
+	return router
+}
+
+func postAsync(router *gin.Engine, body string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/async", strings.NewReader(body)))
+	return recorder
+}
+
+func getJobBody(router *gin.Engine, location string) string {
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, location, nil))
+	return recorder.Body.String()
+}
+
+func TestExecutorAsync(t *testing.T) {
+	router := newAsyncRouter(t, &testStager{isContinued: true})
+
+	recorder := postAsync(router, `{"id": "job-3"}`)
+	assert.Equal(t, http.StatusAccepted, recorder.Code, "failed to accept the job")
+	assert.Contains(t, recorder.Body.String(), jobstore.StatusAccepted, "failed to respond with the accepted job")
+
+	location := recorder.Header().Get("Location")
+	assert.Equal(t, JobsPath+"/job-3", location, "failed to tell where the job is")
+	assert.Eventually(t, func() bool {
+		return strings.Contains(getJobBody(router, location), `"status":"job-3"`)
+	}, time.Second, 10*time.Millisecond, "failed to keep the result of the stages")
+
+	assert.Equal(t, http.StatusConflict, postAsync(router, `{"id": "job-3"}`).Code, "failed to reject the existed job")
+	assert.Equal(t, http.StatusBadRequest, postAsync(router, `{"id": "../job"}`).Code, "failed to reject the invalid id")
+
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, JobsPath+"/job-4", nil))
+	assert.Equal(t, http.StatusNotFound, recorder.Code, "failed to tell the job was not found")
+}
+
+func TestExecutorAsyncFailure(t *testing.T) {
+	router := newAsyncRouter(t, &testStager{err: errors.New("failed")})
+
+	recorder := postAsync(router, "")
+	location := recorder.Header().Get("Location")
+	assert.NotEqual(t, JobsPath+"/", location, "failed to generate the job id")
+	assert.Eventually(t, func() bool {
+		return strings.Contains(getJobBody(router, location), `"status":"`+jobstore.StatusFailed+`"`)
+	}, time.Second, 10*time.Millisecond, "failed to mark the job failed")
+}
+
+func TestDetach(t *testing.T) {
+	request, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	ctx, cancelJob := detach(request)
+	defer cancelJob()
+	_, hasDeadline := ctx.Deadline()
+	assert.Equal(t, false, hasDeadline, "failed to drop the deadline of the request")
+
+	lifetime, stop := context.WithCancel(context.Background())
+	ctx, cancelJob = detach(NewJobContext(request, lifetime, time.Minute))
+	defer cancelJob()
+	deadline, hasDeadline := ctx.Deadline()
+	assert.Equal(t, true, hasDeadline, "failed to limit the job to its timeout")
+	assert.Equal(t, true, time.Until(deadline) > time.Second, "failed to take the job timeout over the request deadline")
+
+	<-request.Done()
+	assert.Equal(t, nil, ctx.Err(), "failed to keep the job running after the request")
+
+	stop()
+	assert.Eventually(t, func() bool {
+		return ctx.Err() != nil
+	}, time.Second, time.Millisecond, "failed to cancel the job once the plugin is stopped")
+}
+
+func TestIsOwner(t *testing.T) {
+	owner := &protocol.Applicant{ID: "user-1", Project: "dummy"}
+	assert.Equal(t, true, isOwner(owner, &protocol.Applicant{ID: "user-2", Project: "dummy"}), "failed to share the job within the project")
+	assert.Equal(t, false, isOwner(owner, &protocol.Applicant{ID: "user-1", Project: "other"}), "failed to hide the job from other projects")
+	assert.Equal(t, false, isOwner(owner, nil), "failed to hide the job from the caller not authenticated")
+	assert.Equal(t, false, isOwner(nil, owner), "failed to hide the job without owner from the authenticated caller")
+	assert.Equal(t, true, isOwner(nil, nil), "failed to answer the job without owner to the caller not authenticated")
+	assert.Equal(t, false, isOwner(&protocol.Applicant{ID: "user-1"}, &protocol.Applicant{ID: "user-2"}), "failed to keep the job without project to its owner")
+}
+
+func TestJobHandlerWithAuth(t *testing.T) {
+	store, err := jobstore.New(map[string]interface{}{"name": jobstore.Memory})
+	assert.Equal(t, nil, err, "failed to create the job store")
+	assert.Equal(t, nil, store.Create(&protocol.Job{ID: "job-5"}), "failed to create the job")
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET(JobsPath+"/:id", NewJobHandler(store, true))
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, JobsPath+"/job-5", nil))
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code, "failed to reject the caller not authenticated")
+}
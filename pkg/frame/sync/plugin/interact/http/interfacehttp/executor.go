@@ -0,0 +1,176 @@
+package interfacehttp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+
+	"github.com/bigstack-oss/plane-go/pkg/base/log"
+	"github.com/bigstack-oss/plane-go/pkg/base/protocol"
+	"github.com/bigstack-oss/plane-go/pkg/frame/sync/plugin/interact/http/middleware"
+	"github.com/bigstack-oss/plane-go/pkg/frame/sync/plugin/interact/http/openapi"
+	"github.com/bigstack-oss/plane-go/pkg/frame/sync/plugin/jobstore"
+	"github.com/bigstack-oss/plane-go/pkg/frame/sync/plugin/plug"
+	"github.com/bigstack-oss/plane-go/pkg/frame/sync/plugin/policy"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	module = "executor"
+
+	// JobsPath is where the jobs run in the background are answered
+	JobsPath = "/jobs"
+)
+
+var (
+	methodPattern = regexp.MustCompile("^[A-Z]+$")
+
+	executorLoggerf = log.GetLogger(module).Sugar()
+)
+
+// Executor is embedded by the interfaces which only run their stages. it
+// binds the request into a job, executes the stages by the chain, and
+// renders the result of the job.
+// Bind and Render can be replaced to customize the request and the response.
+// the applicant of the job is the one authenticated by the auth middleware,
+// the one in the body is never trusted, and the job is authorized by the policy before the stages.
+// the job is not passed to Render once the deadline passed, since the stages
+// given up may still be running with it. if the request comes with a job
+// store, the job is accepted with 202 and its stages run in the background
+type Executor struct {
+	Bind   func(*gin.Context, *protocol.Job) error
+	Render func(*gin.Context, *protocol.Job, error)
+
+	plug.Chain
+}
+
+// StatusError tells the executor which status code to respond with when a
+// stage fails, the failed stages respond with 500 by default, and 504 if the
+// deadline passed. the jobs denied by the policy respond with 403
+type StatusError struct {
+	Code int
+	Err  error
+}
+
+func (s *StatusError) Error() string {
+	return s.Err.Error()
+}
+
+func (s *StatusError) Unwrap() error {
+	return s.Err
+}
+
+// WithStatus wraps the error of a stage with the status code to respond with
+func WithStatus(code int, err error) error {
+	return &StatusError{Code: code, Err: err}
+}
+
+// GetStatus returns the status code to respond with for the error
+func GetStatus(err error) int {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.Code
+	}
+
+	if errors.Is(err, policy.ErrDenied) {
+		return http.StatusForbidden
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return http.StatusGatewayTimeout
+	}
+
+	return http.StatusInternalServerError
+}
+
+// BindJSON decodes the body of the request into the job, requests without a
+// body leave the job empty
+func BindJSON(c *gin.Context, job *protocol.Job) error {
+	if c.Request.ContentLength == 0 {
+		return nil
+	}
+
+	err := c.ShouldBindJSON(job)
+	if err != nil {
+		return WithStatus(http.StatusBadRequest, err)
+	}
+
+	return nil
+}
+
+// RenderJSON responds with the result of the job, or with the error and its
+// status code if the job failed
+func RenderJSON(c *gin.Context, job *protocol.Job, err error) {
+	if err != nil && job.Result != nil {
+		c.JSON(GetStatus(err), gin.H{"error": err.Error(), "result": job.Result})
+		return
+	}
+
+	if err != nil {
+		c.JSON(GetStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	if job.Result == nil {
+		c.Status(http.StatusNoContent)
+		return
+	}
+
+	c.JSON(http.StatusOK, job.Result)
+}
+
+func (e *Executor) RegisterRouter(router *gin.Engine, method string, path string) error {
+	if !methodPattern.MatchString(method) {
+		return fmt.Errorf("invalid method(%s) detected in pattern(%s)", method, path)
+	}
+
+	router.Handle(method, path, e.Handle)
+	return nil
+}
+
+// Schema describes the job bound by BindJSON and the result rendered by
+// RenderJSON, the interfaces replacing them should describe their own
+func (e *Executor) Schema() openapi.Schema {
+	return openapi.Schema{Request: protocol.Job{}, Response: protocol.Result{}}
+}
+
+// Handle is the handler of the routes registered by the executor
+func (e *Executor) Handle(c *gin.Context) {
+	bind, render := e.Bind, e.Render
+	if bind == nil {
+		bind = BindJSON
+	}
+	if render == nil {
+		render = RenderJSON
+	}
+
+	job := &protocol.Job{}
+	err := bind(c, job)
+	job.Applicant = nil
+	if applicant, isAuthenticated := middleware.GetApplicant(c); isAuthenticated {
+		job.Applicant = applicant
+	}
+	if request, isDecoded := plug.RequestFromContext(c.Request.Context()); isDecoded {
+		job.SetRequest(request)
+	}
+	if err == nil {
+		err = policy.Authorize(c.Request.Context(), job)
+		c.Request = c.Request.WithContext(policy.WithAuthorized(c.Request.Context()))
+	}
+	if store, isAsync := jobstore.FromContext(c.Request.Context()); isAsync && err == nil {
+		e.accept(c, store, job, render)
+		return
+	}
+	if err == nil {
+		err = e.Execute(c.Request.Context(), job)
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		render(c, &protocol.Job{}, err)
+		return
+	}
+
+	render(c, job, err)
+}
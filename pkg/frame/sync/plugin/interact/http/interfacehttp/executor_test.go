@@ -0,0 +1,164 @@
+package interfacehttp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bigstack-oss/plane-go/pkg/base/protocol"
+	"github.com/bigstack-oss/plane-go/pkg/frame/sync/plugin/interact/http/middleware"
+	"github.com/bigstack-oss/plane-go/pkg/frame/sync/plugin/plug"
+	"github.com/bigstack-oss/plane-go/pkg/frame/sync/plugin/policy"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type testStager struct {
+	isContinued bool
+	isHung      bool
+	err         error
+	executed    int
+}
+
+func (s *testStager) SetConfig(interface{}) {}
+
+func (s *testStager) CheckConfig() error {
+	return nil
+}
+
+func (s *testStager) Execute(job *protocol.Job) (bool, error) {
+	s.executed++
+	if s.isHung {
+		time.Sleep(time.Second)
+	}
+
+	job.Result = &protocol.Result{Status: job.ID}
+	return s.isContinued, s.err
+}
+
+func newTestRouter(t *testing.T, e *Executor, stagers ...*testStager) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	assert.Equal(t, nil, e.RegisterRouter(router, http.MethodPost, "/jobs"), "failed to register router")
+
+	for i, stager := range stagers {
+		name := "test-" + string(rune('a'+i))
+		plug.Stagers[name] = stager
+		e.AppendStage(name)
+	}
+
+	return router
+}
+
+func postJob(router *gin.Engine, body string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodPost, "/jobs", strings.NewReader(body))
+	router.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestExecutorShortCircuit(t *testing.T) {
+	first, second := &testStager{isContinued: false}, &testStager{isContinued: true}
+	router := newTestRouter(t, &Executor{}, first, second)
+
+	recorder := postJob(router, `{"id": "job-1"}`)
+	assert.Equal(t, http.StatusOK, recorder.Code, "failed to respond with the result")
+	assert.Contains(t, recorder.Body.String(), "job-1", "failed to bind the request into the job")
+	assert.Equal(t, 0, second.executed, "failed to skip the stages after a stage returned false")
+}
+
+func TestExecutorError(t *testing.T) {
+	router := newTestRouter(t, &Executor{}, &testStager{err: WithStatus(http.StatusConflict, errors.New("conflict"))})
+	assert.Equal(t, http.StatusConflict, postJob(router, "").Code, "failed to map the stage error to the status code")
+
+	router = newTestRouter(t, &Executor{}, &testStager{err: fmt.Errorf("%w by rule(test)", policy.ErrDenied)})
+	assert.Equal(t, http.StatusForbidden, postJob(router, "").Code, "failed to respond with 403 for the denied job")
+
+	router = newTestRouter(t, &Executor{}, &testStager{err: errors.New("failed")})
+	assert.Equal(t, http.StatusInternalServerError, postJob(router, "").Code, "failed to respond with 500 by default")
+	assert.Equal(t, http.StatusBadRequest, postJob(router, "{").Code, "failed to reject the invalid body")
+}
+
+func TestExecutorOverride(t *testing.T) {
+	e := &Executor{
+		Bind: func(c *gin.Context, job *protocol.Job) error {
+			job.ID = c.Query("id")
+			return nil
+		},
+		Render: func(c *gin.Context, job *protocol.Job, err error) {
+			c.String(http.StatusAccepted, job.Result.Status)
+		},
+	}
+	router := newTestRouter(t, e, &testStager{isContinued: true})
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/jobs?id=job-2", nil))
+	assert.Equal(t, http.StatusAccepted, recorder.Code, "failed to render by the custom renderer")
+	assert.Equal(t, "job-2", recorder.Body.String(), "failed to bind by the custom binder")
+}
+
+func TestExecutorApplicant(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set(middleware.ApplicantKey, &protocol.Applicant{ID: "user-1"})
+	})
+
+	e := &Executor{Render: func(c *gin.Context, job *protocol.Job, err error) {
+		c.String(http.StatusOK, job.Applicant.ID)
+	}}
+	assert.Equal(t, nil, e.RegisterRouter(router, http.MethodPost, "/jobs"), "failed to register router")
+
+	recorder := postJob(router, `{"applicant": {"id": "spoofed"}}`)
+	assert.Equal(t, "user-1", recorder.Body.String(), "failed to take the applicant from the auth middleware")
+
+	anonymous := gin.New()
+	e = &Executor{Render: func(c *gin.Context, job *protocol.Job, err error) {
+		c.JSON(http.StatusOK, job.Applicant)
+	}}
+	assert.Equal(t, nil, e.RegisterRouter(anonymous, http.MethodPost, "/jobs"), "failed to register router")
+
+	recorder = postJob(anonymous, `{"applicant": {"id": "spoofed"}}`)
+	assert.Equal(t, "null", recorder.Body.String(), "failed to drop the applicant not authenticated")
+}
+
+func TestExecutorDeadline(t *testing.T) {
+	router := newTestRouter(t, &Executor{}, &testStager{isHung: true})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/jobs", nil).WithContext(ctx))
+	assert.Equal(t, http.StatusGatewayTimeout, recorder.Code, "failed to respond with 504 once the deadline passed")
+}
+
+type compensatingStager struct {
+	testStager
+	compensated bool
+}
+
+func (s *compensatingStager) Compensate(ctx context.Context, job *protocol.Job) error {
+	s.compensated = true
+	return nil
+}
+
+func TestExecutorCompensation(t *testing.T) {
+	e := &Executor{}
+	router := newTestRouter(t, e)
+	first := &compensatingStager{testStager: testStager{isContinued: true}}
+	plug.Stagers["test-compensated"] = first
+	e.AppendStage("test-compensated")
+	plug.Stagers["test-failed"] = &testStager{err: errors.New("failed")}
+	e.AppendStage("test-failed")
+
+	recorder := postJob(router, "")
+	assert.Equal(t, true, first.compensated, "failed to compensate the succeeded stage")
+	assert.Equal(t, http.StatusInternalServerError, recorder.Code, "failed to respond with the stage error")
+	assert.Contains(t, recorder.Body.String(), plug.StatusCompensated, "failed to render the outcome of the compensation")
+}
@@ -1,6 +1,7 @@
 package interfacehttp
 
 import (
+	"github.com/bigstack-oss/plane-go/pkg/frame/sync/plugin/interact/http/openapi"
 	"github.com/gin-gonic/gin"
 )
 
@@ -13,3 +14,9 @@ type Interface interface {
 	RegisterRouter(*gin.Engine, string, string) error
 	AppendStage(string)
 }
+
+// Schemer can be implemented by the interfaces to describe their requests
+// and responses in the openapi document
+type Schemer interface {
+	Schema() openapi.Schema
+}
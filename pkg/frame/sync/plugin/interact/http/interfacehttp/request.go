@@ -0,0 +1,295 @@
+package interfacehttp
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/bigstack-oss/plane-go/pkg/frame/sync/plugin/interact/http/openapi"
+	"github.com/bigstack-oss/plane-go/pkg/frame/sync/plugin/plug"
+	"github.com/gin-gonic/gin"
+	"gopkg.in/go-playground/validator.v9"
+	schemaerrors "k8s.io/kube-openapi/pkg/validation/errors"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+	"k8s.io/kube-openapi/pkg/validation/strfmt"
+	"k8s.io/kube-openapi/pkg/validation/validate"
+)
+
+const (
+	invalidRequest = "invalid request body"
+)
+
+var (
+	// Requests are the go structs declared by the interfaces in config, the
+	// body is validated by the validate tags of the struct like the configs of
+	// the plugins
+	Requests = make(map[string]interface{})
+
+	// the keywords of json schema by the codes of the schema errors
+	schemaRules = map[int32]string{
+		schemaerrors.InvalidTypeCode:           "type",
+		schemaerrors.RequiredFailCode:          "required",
+		schemaerrors.TooLongFailCode:           "maxLength",
+		schemaerrors.TooShortFailCode:          "minLength",
+		schemaerrors.PatternFailCode:           "pattern",
+		schemaerrors.EnumFailCode:              "enum",
+		schemaerrors.MultipleOfFailCode:        "multipleOf",
+		schemaerrors.MaxFailCode:               "maximum",
+		schemaerrors.MinFailCode:               "minimum",
+		schemaerrors.UniqueFailCode:            "uniqueItems",
+		schemaerrors.MaxItemsFailCode:          "maxItems",
+		schemaerrors.MinItemsFailCode:          "minItems",
+		schemaerrors.NoAdditionalItemsCode:     "additionalItems",
+		schemaerrors.TooFewPropertiesCode:      "minProperties",
+		schemaerrors.TooManyPropertiesCode:     "maxProperties",
+		schemaerrors.UnallowedPropertyCode:     "additionalProperties",
+		schemaerrors.FailedAllPatternPropsCode: "patternProperties",
+	}
+)
+
+// FieldError tells which field of the body failed which rule, the field is
+// the dotted path of the json names
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// RequestValidator decodes the body of the requests declared by the
+// interface, and returns the failed fields if the body is invalid
+type RequestValidator interface {
+	Validate(body []byte) (interface{}, []FieldError)
+	// Schema returns the request described in the openapi document
+	Schema() interface{}
+}
+
+// structValidator decodes the body into a new struct of the declared type
+type structValidator struct {
+	template  interface{}
+	validator *validator.Validate
+}
+
+// schemaValidator decodes the body into a json value validated by the
+// schema, the schema can not have references
+type schemaValidator struct {
+	raw    openapi.Raw
+	schema *spec.Schema
+}
+
+// getJSONName names the fields by their json tags in the failed fields
+func getJSONName(field reflect.StructField) string {
+	jsonName := strings.Split(field.Tag.Get("json"), ",")[0]
+	if jsonName == "-" {
+		return ""
+	}
+	if jsonName == "" {
+		return field.Name
+	}
+
+	return jsonName
+}
+
+// NewStructValidator returns the validator of the go struct registered in
+// Requests by typeName
+func NewStructValidator(typeName string) (RequestValidator, error) {
+	template, isExisted := Requests[typeName]
+	if !isExisted {
+		return nil, fmt.Errorf("request(%s) was not defined", typeName)
+	}
+
+	t := reflect.TypeOf(template)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("request(%s) is not a struct", typeName)
+	}
+
+	v := validator.New()
+	v.RegisterTagNameFunc(getJSONName)
+	return &structValidator{template: reflect.New(t).Elem().Interface(), validator: v}, nil
+}
+
+// NewSchemaValidator returns the validator of the json schema in path
+func NewSchemaValidator(path string) (RequestValidator, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema. error: %w", err)
+	}
+
+	raw := openapi.Raw{}
+	err = json.Unmarshal(content, &raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse schema(%s). error: %w", path, err)
+	}
+	if hasReference(raw) {
+		return nil, fmt.Errorf("schema(%s) can not have references", path)
+	}
+
+	schema := &spec.Schema{}
+	err = json.Unmarshal(content, schema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse schema(%s). error: %w", path, err)
+	}
+
+	return &schemaValidator{raw: raw, schema: schema}, nil
+}
+
+func hasReference(value interface{}) bool {
+	switch v := value.(type) {
+	case openapi.Raw:
+		return hasReference(map[string]interface{}(v))
+	case map[string]interface{}:
+		for key, child := range v {
+			if key == "$ref" || hasReference(child) {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, child := range v {
+			if hasReference(child) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// getDecodeError converts the error of decoding the body, the syntax errors
+// have no field
+func getDecodeError(err error) FieldError {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return FieldError{
+			Field:   typeErr.Field,
+			Rule:    "type",
+			Message: fmt.Sprintf("%s must be %s rather than %s", typeErr.Field, typeErr.Type.String(), typeErr.Value),
+		}
+	}
+
+	return FieldError{Rule: "json", Message: err.Error()}
+}
+
+func (s *structValidator) Validate(body []byte) (interface{}, []FieldError) {
+	request := reflect.New(reflect.TypeOf(s.template)).Interface()
+	err := json.Unmarshal(body, request)
+	if err != nil {
+		return nil, []FieldError{getDecodeError(err)}
+	}
+
+	err = s.validator.Struct(request)
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		return request, nil
+	}
+
+	fieldErrs := []FieldError{}
+	for _, e := range validationErrs {
+		// the namespace starts with the name of the struct
+		field := e.Namespace()
+		if i := strings.Index(field, "."); i >= 0 {
+			field = field[i+1:]
+		}
+
+		message := fmt.Sprintf("%s failed on the %s rule", field, e.Tag())
+		if e.Param() != "" {
+			message = fmt.Sprintf("%s failed on the %s=%s rule", field, e.Tag(), e.Param())
+		}
+
+		fieldErrs = append(fieldErrs, FieldError{Field: field, Rule: e.Tag(), Message: message})
+	}
+
+	return nil, fieldErrs
+}
+
+func (s *structValidator) Schema() interface{} {
+	return s.template
+}
+
+func (s *schemaValidator) Validate(body []byte) (interface{}, []FieldError) {
+	var request interface{}
+	err := json.Unmarshal(body, &request)
+	if err != nil {
+		return nil, []FieldError{getDecodeError(err)}
+	}
+
+	result := validate.NewSchemaValidator(s.schema, nil, "", strfmt.Default).Validate(request)
+	if result.IsValid() {
+		return request, nil
+	}
+
+	fieldErrs := []FieldError{}
+	for _, err := range result.Errors {
+		fieldErr := FieldError{Rule: "schema", Message: err.Error()}
+
+		var validationErr *schemaerrors.Validation
+		if errors.As(err, &validationErr) {
+			fieldErr.Field = strings.TrimPrefix(validationErr.Name, ".")
+			if rule, isExisted := schemaRules[validationErr.Code()]; isExisted {
+				fieldErr.Rule = rule
+			}
+		}
+
+		fieldErrs = append(fieldErrs, fieldErr)
+	}
+
+	return nil, fieldErrs
+}
+
+func (s *schemaValidator) Schema() interface{} {
+	return s.raw
+}
+
+func readBody(c *gin.Context) ([]byte, error) {
+	if c.Request.Body == nil {
+		return nil, nil
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// ValidateRequest answers the invalid bodies with 400 and the failed fields
+// before the stages run. the decoded request is put into the context for
+// the stages, and the body is kept for Bind. requests without a body are
+// validated as an empty object
+func ValidateRequest(v RequestValidator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		body, err := readBody(c)
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+				return
+			}
+
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if len(bytes.TrimSpace(body)) == 0 {
+			body = []byte("{}")
+		}
+
+		request, fieldErrs := v.Validate(body)
+		if len(fieldErrs) > 0 {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": invalidRequest, "fields": fieldErrs})
+			return
+		}
+
+		c.Request = c.Request.WithContext(plug.NewRequestContext(c.Request.Context(), request))
+		c.Next()
+	}
+}
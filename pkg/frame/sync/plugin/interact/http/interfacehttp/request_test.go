@@ -0,0 +1,158 @@
+package interfacehttp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bigstack-oss/plane-go/pkg/base/protocol"
+	"github.com/bigstack-oss/plane-go/pkg/frame/sync/plugin/plug"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type testRequest struct {
+	Name     string           `json:"name" validate:"required"`
+	Replicas int              `json:"replicas" validate:"min=1,max=3"`
+	Resource *testResourceRef `json:"resource" validate:"required"`
+}
+
+type testResourceRef struct {
+	Type string `json:"type" validate:"oneof=vm volume"`
+}
+
+// requestStager keeps the request decoded from the body
+type requestStager struct {
+	testStager
+	request interface{}
+}
+
+func (s *requestStager) ExecuteContext(ctx context.Context, job *protocol.Job) (bool, error) {
+	s.request, _ = plug.RequestFromContext(ctx)
+	return s.Execute(job)
+}
+
+// jobRequestStager is not aware of context and reads the request from the job
+type jobRequestStager struct {
+	testStager
+	request interface{}
+}
+
+func (s *jobRequestStager) Execute(job *protocol.Job) (bool, error) {
+	s.request = job.GetRequest()
+	return s.testStager.Execute(job)
+}
+
+func newValidatedRouter(t *testing.T, v RequestValidator, stager *requestStager) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(ValidateRequest(v))
+
+	e := &Executor{}
+	assert.Equal(t, nil, e.RegisterRouter(router, http.MethodPost, "/jobs"), "failed to register router")
+	plug.Stagers["test-request"] = stager
+	e.AppendStage("test-request")
+	return router
+}
+
+func getFieldErrors(t *testing.T, body []byte) map[string]string {
+	response := struct {
+		Fields []FieldError `json:"fields"`
+	}{}
+	assert.Equal(t, nil, json.Unmarshal(body, &response), "failed to decode the failed fields")
+
+	rules := make(map[string]string)
+	for _, f := range response.Fields {
+		rules[f.Field] = f.Rule
+	}
+
+	return rules
+}
+
+func TestStructValidator(t *testing.T) {
+	_, err := NewStructValidator("not-existed")
+	assert.NotEqual(t, nil, err, "failed to reject the undefined request")
+
+	Requests["test"] = &testRequest{}
+	v, err := NewStructValidator("test")
+	assert.Equal(t, nil, err, "failed to create the validator")
+
+	stager := &requestStager{testStager: testStager{isContinued: true}}
+	router := newValidatedRouter(t, v, stager)
+
+	recorder := postJob(router, `{"replicas": 5, "resource": {"type": "disk"}}`)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code, "failed to reject the invalid body")
+	assert.Equal(t, map[string]string{"name": "required", "replicas": "max", "resource.type": "oneof"}, getFieldErrors(t, recorder.Body.Bytes()), "failed to report the failed fields")
+	assert.Equal(t, nil, stager.request, "failed to stop the invalid body before the stages")
+
+	recorder = postJob(router, `{"name": "a", "replicas": "two"}`)
+	assert.Equal(t, map[string]string{"replicas": "type"}, getFieldErrors(t, recorder.Body.Bytes()), "failed to report the mistyped field")
+
+	recorder = postJob(router, `{"id": "job-1", "name": "a", "replicas": 2, "resource": {"type": "vm"}}`)
+	assert.Equal(t, http.StatusOK, recorder.Code, "failed to accept the valid body")
+	assert.Contains(t, recorder.Body.String(), "job-1", "failed to keep the body for the job")
+
+	request, isDecoded := stager.request.(*testRequest)
+	assert.Equal(t, true, isDecoded, "failed to pass the decoded request to the stages")
+	assert.Equal(t, "vm", request.Resource.Type, "failed to decode the request")
+}
+
+func TestJobRequest(t *testing.T) {
+	Requests["test"] = &testRequest{}
+	v, err := NewStructValidator("test")
+	assert.Equal(t, nil, err, "failed to create the validator")
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(ValidateRequest(v))
+
+	e := &Executor{}
+	assert.Equal(t, nil, e.RegisterRouter(router, http.MethodPost, "/jobs"), "failed to register router")
+	stager := &jobRequestStager{testStager: testStager{isContinued: true}}
+	plug.Stagers["test-job-request"] = stager
+	e.AppendStage("test-job-request")
+
+	recorder := postJob(router, `{"name": "a", "replicas": 2, "resource": {"type": "vm"}}`)
+	assert.Equal(t, http.StatusOK, recorder.Code, "failed to accept the valid body")
+
+	request, isDecoded := stager.request.(*testRequest)
+	assert.Equal(t, true, isDecoded, "failed to pass the decoded request to the context-less stages")
+	assert.Equal(t, "a", request.Name, "failed to decode the request")
+}
+
+func TestSchemaValidator(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "schema.json")
+	assert.Equal(t, nil, os.WriteFile(file, []byte(`{"properties": {"a": {"$ref": "#/definitions/a"}}}`), 0600), "failed to write schema")
+	_, err := NewSchemaValidator(file)
+	assert.NotEqual(t, nil, err, "failed to reject the schema with references")
+
+	schema := `{
+		"type": "object",
+		"required": ["name"],
+		"properties": {
+			"name": {"type": "string", "minLength": 1},
+			"desired": {"type": "object", "properties": {"operation": {"enum": ["create", "delete"]}}}
+		}
+	}`
+	assert.Equal(t, nil, os.WriteFile(file, []byte(schema), 0600), "failed to write schema")
+	v, err := NewSchemaValidator(file)
+	assert.Equal(t, nil, err, "failed to create the validator")
+
+	stager := &requestStager{testStager: testStager{isContinued: true}}
+	router := newValidatedRouter(t, v, stager)
+
+	recorder := postJob(router, "")
+	assert.Equal(t, map[string]string{"name": "required"}, getFieldErrors(t, recorder.Body.Bytes()), "failed to validate the empty body as an object")
+
+	recorder = postJob(router, `{"name": "", "desired": {"operation": "update"}}`)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code, "failed to reject the invalid body")
+	assert.Equal(t, map[string]string{"name": "minLength", "desired.operation": "enum"}, getFieldErrors(t, recorder.Body.Bytes()), "failed to report the failed fields")
+
+	recorder = postJob(router, `{"name": "a", "desired": {"operation": "create"}}`)
+	assert.Equal(t, http.StatusOK, recorder.Code, "failed to accept the valid body")
+	assert.Equal(t, "a", stager.request.(map[string]interface{})["name"], "failed to pass the decoded request to the stages")
+}
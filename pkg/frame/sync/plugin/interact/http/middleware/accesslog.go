@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/bigstack-oss/plane-go/pkg/base/log"
+	"github.com/gin-gonic/gin"
+	"github.com/goinggo/mapstructure"
+	"go.uber.org/zap"
+	"gopkg.in/go-playground/validator.v9"
+)
+
+const (
+	accessLogModule = "accessLog"
+)
+
+// AccessLog logs every request after it is answered
+type AccessLog struct {
+	config accessLogConfig
+
+	log *zap.Logger
+}
+
+type accessLogConfig struct {
+	Name string `validate:"required"`
+}
+
+func init() {
+	Plugins[accessLogModule] = &AccessLog{}
+}
+
+func (a *AccessLog) SetConfig(conf interface{}) {
+	_ = mapstructure.Decode(conf, &a.config)
+	a.log = log.GetLogger(accessLogModule)
+}
+
+func (a *AccessLog) CheckConfig() error {
+	return validator.New().Struct(a.config)
+}
+
+func (a *AccessLog) Handle(c *gin.Context) {
+	startedAt := time.Now()
+	c.Next()
+
+	a.log.Info("access",
+		zap.String("method", c.Request.Method),
+		zap.String("path", c.Request.URL.Path),
+		zap.Int("status", c.Writer.Status()),
+		zap.Duration("latency", time.Since(startedAt)),
+		zap.String("clientIP", c.ClientIP()),
+		zap.String("requestId", c.GetString(RequestIDKey)),
+	)
+}
@@ -0,0 +1,409 @@
+package middleware
+
+import (
+	"crypto/rsa"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/bigstack-oss/plane-go/pkg/base/protocol"
+	"github.com/gin-gonic/gin"
+	"github.com/goinggo/mapstructure"
+	"github.com/golang-jwt/jwt/v5"
+	"gopkg.in/go-playground/validator.v9"
+)
+
+const (
+	authModule   = "auth"
+	apiKeyHeader = "X-Api-Key"
+	bearerPrefix = "Bearer "
+
+	hs256 = "HS256"
+	rs256 = "RS256"
+
+	// ApplicantKey is the key of the authenticated applicant in the gin context
+	ApplicantKey = "applicant"
+)
+
+var (
+	errNoCredential = errors.New("no credential was given")
+
+	// the applicant fields are filled with the claims of the same names by
+	// default, except that the id comes from the subject
+	defaultClaims = map[string]string{
+		"id":       "sub",
+		"name":     "name",
+		"project":  "project",
+		"email":    "email",
+		"country":  "country",
+		"company":  "company",
+		"industry": "industry",
+		"roles":    "roles",
+	}
+)
+
+// Auth authenticates the requests by a bearer JWT or a static API key, and
+// puts the applicant into the gin context. the requests without a valid
+// credential are rejected with 401, and the applicants out of the allowed
+// projects are rejected with 403. the applicant of a verified client
+// certificate is taken if there is no credential and AllowClientCert is set
+type Auth struct {
+	config authConfig
+
+	keys    map[string]interface{}
+	apiKeys map[string]protocol.Applicant
+	err     error
+}
+
+type authConfig struct {
+	Name            string `validate:"required"`
+	Jwt             jwtConfig
+	ApiKeyHeader    string
+	ApiKeys         []apiKeyConfig
+	Claims          map[string]string
+	AllowedProjects []string
+	AllowClientCert bool
+}
+
+// jwtConfig takes the key from KeyFile, which is the secret of HS256 or the
+// PEM public key of RS256, or the RS256 keys from the local JWKS file. the
+// JWTs are not accepted if Algorithm is not set, or if they never expire
+type jwtConfig struct {
+	Algorithm string `validate:"omitempty,oneof=HS256 RS256"`
+	KeyFile   string
+	JwksFile  string
+	Issuer    string
+	Audience  string
+}
+
+type apiKeyConfig struct {
+	Key       string `validate:"required"`
+	Applicant protocol.Applicant
+}
+
+type jwks struct {
+	Keys []struct {
+		Kid string `json:"kid"`
+		Kty string `json:"kty"`
+		N   string `json:"n"`
+		E   string `json:"e"`
+	} `json:"keys"`
+}
+
+func init() {
+	Plugins[authModule] = &Auth{}
+}
+
+func (a *Auth) SetConfig(conf interface{}) {
+	_ = mapstructure.Decode(conf, &a.config)
+	if a.config.ApiKeyHeader == "" {
+		a.config.ApiKeyHeader = apiKeyHeader
+	}
+	if a.config.Claims == nil {
+		a.config.Claims = defaultClaims
+	}
+
+	a.apiKeys = make(map[string]protocol.Applicant)
+	for _, apiKey := range a.config.ApiKeys {
+		a.apiKeys[apiKey.Key] = apiKey.Applicant
+	}
+
+	a.keys, a.err = loadKeys(a.config.Jwt)
+}
+
+func (a *Auth) CheckConfig() error {
+	if !a.config.Jwt.isEnabled() && len(a.config.ApiKeys) == 0 {
+		return fmt.Errorf("neither jwt nor apiKeys is set for %s middleware", authModule)
+	}
+
+	validate := validator.New()
+	err := validate.Struct(a.config)
+	if err != nil {
+		return err
+	}
+
+	for _, apiKey := range a.config.ApiKeys {
+		err = validate.Struct(apiKey)
+		if err != nil {
+			return err
+		}
+	}
+
+	return a.err
+}
+
+func (j jwtConfig) isEnabled() bool {
+	return j.Algorithm != ""
+}
+
+func readKeyFile(path string) ([]byte, error) {
+	key, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file(%s). error: %s", path, err.Error())
+	}
+
+	return key, nil
+}
+
+func decodeJwks(raw []byte) (map[string]interface{}, error) {
+	set := jwks{}
+	err := json.Unmarshal(raw, &set)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]interface{})
+	for _, key := range set.Keys {
+		if key.Kty != "RSA" {
+			continue
+		}
+
+		n, err := base64.RawURLEncoding.DecodeString(key.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid modulus of key(%s). error: %s", key.Kid, err.Error())
+		}
+
+		e, err := base64.RawURLEncoding.DecodeString(key.E)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exponent of key(%s). error: %s", key.Kid, err.Error())
+		}
+
+		keys[key.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+
+	return keys, nil
+}
+
+// loadKeys reads the keys verifying the JWTs, the key from KeyFile is keyed
+// by an empty kid, so that it verifies the tokens without a kid
+func loadKeys(conf jwtConfig) (map[string]interface{}, error) {
+	keys := make(map[string]interface{})
+	if !conf.isEnabled() {
+		return keys, nil
+	}
+
+	if conf.KeyFile == "" && conf.JwksFile == "" {
+		return nil, errors.New("neither keyFile nor jwksFile is set for jwt")
+	}
+
+	if conf.JwksFile != "" {
+		if conf.Algorithm != rs256 {
+			return nil, fmt.Errorf("jwksFile is only supported by %s", rs256)
+		}
+
+		raw, err := readKeyFile(conf.JwksFile)
+		if err != nil {
+			return nil, err
+		}
+
+		keys, err = decodeJwks(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid jwks file(%s). error: %s", conf.JwksFile, err.Error())
+		}
+	}
+
+	if conf.KeyFile == "" {
+		return keys, nil
+	}
+
+	raw, err := readKeyFile(conf.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	switch conf.Algorithm {
+	case hs256:
+		keys[""] = []byte(strings.TrimSpace(string(raw)))
+	case rs256:
+		keys[""], err = jwt.ParseRSAPublicKeyFromPEM(raw)
+	}
+
+	return keys, err
+}
+
+func (a *Auth) getKey(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, isExisted := a.keys[kid]
+	if !isExisted {
+		key, isExisted = a.keys[""]
+	}
+	if !isExisted {
+		return nil, fmt.Errorf("no key was found for kid(%s)", kid)
+	}
+
+	return key, nil
+}
+
+func getClaim(claims jwt.MapClaims, name string) string {
+	value, isExisted := claims[name]
+	if !isExisted || value == nil {
+		return ""
+	}
+
+	text, isText := value.(string)
+	if isText {
+		return text
+	}
+
+	return fmt.Sprint(value)
+}
+
+// getClaims returns the claim as a list, a single value is taken as a list
+// of one
+func getClaims(claims jwt.MapClaims, name string) []string {
+	values, isList := claims[name].([]interface{})
+	if !isList {
+		value := getClaim(claims, name)
+		if value == "" {
+			return nil
+		}
+
+		return []string{value}
+	}
+
+	list := []string{}
+	for _, value := range values {
+		list = append(list, fmt.Sprint(value))
+	}
+
+	return list
+}
+
+func (a *Auth) toApplicant(claims jwt.MapClaims) protocol.Applicant {
+	applicant := protocol.Applicant{}
+	for field, claim := range a.config.Claims {
+		if field == "roles" {
+			applicant.Roles = getClaims(claims, claim)
+			continue
+		}
+
+		value := getClaim(claims, claim)
+		switch field {
+		case "id":
+			applicant.ID = value
+		case "name":
+			applicant.Name = value
+		case "project":
+			applicant.Project = value
+		case "email":
+			applicant.Email = value
+		case "country":
+			applicant.Country = value
+		case "company":
+			applicant.Company = value
+		case "industry":
+			applicant.Industry = value
+		}
+	}
+
+	return applicant
+}
+
+func (a *Auth) parseJwt(raw string) (protocol.Applicant, error) {
+	options := []jwt.ParserOption{jwt.WithValidMethods([]string{a.config.Jwt.Algorithm}), jwt.WithExpirationRequired()}
+	if a.config.Jwt.Issuer != "" {
+		options = append(options, jwt.WithIssuer(a.config.Jwt.Issuer))
+	}
+	if a.config.Jwt.Audience != "" {
+		options = append(options, jwt.WithAudience(a.config.Jwt.Audience))
+	}
+
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(raw, claims, a.getKey, options...)
+	if err != nil {
+		return protocol.Applicant{}, err
+	}
+
+	return a.toApplicant(claims), nil
+}
+
+func (a *Auth) findApiKey(key string) (protocol.Applicant, error) {
+	for apiKey, applicant := range a.apiKeys {
+		if subtle.ConstantTimeCompare([]byte(apiKey), []byte(key)) == 1 {
+			return applicant, nil
+		}
+	}
+
+	return protocol.Applicant{}, errors.New("invalid api key")
+}
+
+func (a *Auth) authenticate(c *gin.Context) (protocol.Applicant, error) {
+	key := c.GetHeader(a.config.ApiKeyHeader)
+	if key != "" && len(a.apiKeys) > 0 {
+		return a.findApiKey(key)
+	}
+
+	authorization := c.GetHeader("Authorization")
+	if strings.HasPrefix(authorization, bearerPrefix) && a.config.Jwt.isEnabled() {
+		return a.parseJwt(strings.TrimPrefix(authorization, bearerPrefix))
+	}
+
+	peer, isVerified := GetApplicant(c)
+	if isVerified && a.config.AllowClientCert {
+		return *peer, nil
+	}
+
+	return protocol.Applicant{}, errNoCredential
+}
+
+func (a *Auth) isAllowed(applicant protocol.Applicant) bool {
+	if len(a.config.AllowedProjects) == 0 {
+		return true
+	}
+
+	for _, project := range a.config.AllowedProjects {
+		if project == applicant.Project {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (a *Auth) Handle(c *gin.Context) {
+	applicant, err := a.authenticate(c)
+	if err != nil {
+		c.Header("WWW-Authenticate", strings.TrimSpace(bearerPrefix))
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !a.isAllowed(applicant) {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("project(%s) is not allowed", applicant.Project)})
+		return
+	}
+
+	c.Set(ApplicantKey, &applicant)
+	c.Next()
+}
+
+// GetApplicant returns the applicant authenticated by the auth middleware
+func GetApplicant(c *gin.Context) (*protocol.Applicant, bool) {
+	value, isExisted := c.Get(ApplicantKey)
+	if !isExisted {
+		return nil, false
+	}
+
+	applicant, isApplicant := value.(*protocol.Applicant)
+	return applicant, isApplicant
+}
+
+// GetAuthConfig returns the conf of the auth middleware in confs
+func GetAuthConfig(confs []map[string]interface{}) (map[string]interface{}, bool) {
+	for _, conf := range confs {
+		if middlewareName, _ := conf[name].(string); middlewareName == authModule {
+			return conf, true
+		}
+	}
+
+	return nil, false
+}
@@ -0,0 +1,146 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bigstack-oss/plane-go/pkg/base/protocol"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func newAuthRouter(t *testing.T, conf map[string]interface{}) *gin.Engine {
+	chain, err := NewChain([]map[string]interface{}{conf})
+	assert.Equal(t, nil, err, "failed to create the auth middleware")
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(chain...)
+	router.GET("/jobs", func(c *gin.Context) {
+		applicant, _ := GetApplicant(c)
+		c.JSON(http.StatusOK, applicant)
+	})
+
+	return router
+}
+
+func requestWithHeader(router *gin.Engine, name, value string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodGet, "/jobs", nil)
+	if name != "" {
+		request.Header.Set(name, value)
+	}
+
+	router.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestAuthConfig(t *testing.T) {
+	_, err := NewChain([]map[string]interface{}{{"name": authModule}})
+	assert.NotEqual(t, nil, err, "failed to reject the auth without credentials")
+
+	_, err = NewChain([]map[string]interface{}{{
+		"name": authModule,
+		"jwt":  map[string]interface{}{"algorithm": "HS256", "keyFile": "/not/existed"},
+	}})
+	assert.NotEqual(t, nil, err, "failed to reject the missing key file")
+}
+
+func TestAuthByApiKey(t *testing.T) {
+	router := newAuthRouter(t, map[string]interface{}{
+		"name": authModule,
+		"apiKeys": []interface{}{
+			map[string]interface{}{"key": "key-1", "applicant": map[string]interface{}{"id": "user-1", "project": "demo"}},
+			map[string]interface{}{"key": "key-2", "applicant": map[string]interface{}{"id": "user-2", "project": "other"}},
+		},
+		"allowedProjects": []interface{}{"demo"},
+	})
+
+	recorder := requestWithHeader(router, apiKeyHeader, "key-1")
+	assert.Equal(t, http.StatusOK, recorder.Code, "failed to accept the api key")
+	assert.Contains(t, recorder.Body.String(), `"id":"user-1"`, "failed to set the applicant of the api key")
+
+	recorder = requestWithHeader(router, apiKeyHeader, "key-2")
+	assert.Equal(t, http.StatusForbidden, recorder.Code, "failed to reject the project not allowed")
+
+	recorder = requestWithHeader(router, apiKeyHeader, "wrong")
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code, "failed to reject the invalid api key")
+
+	recorder = requestWithHeader(router, "", "")
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code, "failed to reject the request without credentials")
+	assert.Equal(t, "Bearer", recorder.Header().Get("WWW-Authenticate"), "failed to challenge the client")
+}
+
+func TestAuthByJwt(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "secret")
+	err := os.WriteFile(keyFile, []byte("secret\n"), 0600)
+	assert.Equal(t, nil, err, "failed to write the key file")
+
+	router := newAuthRouter(t, map[string]interface{}{
+		"name":   authModule,
+		"jwt":    map[string]interface{}{"algorithm": "HS256", "keyFile": keyFile, "issuer": "plane"},
+		"claims": map[string]interface{}{"id": "sub", "project": "tenant", "roles": "groups"},
+	})
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub": "user-1", "tenant": "demo", "groups": []string{"admin"}, "iss": "plane", "exp": time.Now().Add(time.Hour).Unix(),
+	}).SignedString([]byte("secret"))
+	assert.Equal(t, nil, err, "failed to sign the token")
+
+	recorder := requestWithHeader(router, "Authorization", bearerPrefix+token)
+	assert.Equal(t, http.StatusOK, recorder.Code, "failed to accept the token")
+	assert.Contains(t, recorder.Body.String(), `"project":"demo"`, "failed to map the claims to the applicant")
+	assert.Contains(t, recorder.Body.String(), `"roles":["admin"]`, "failed to map the list claim to the roles")
+
+	forged, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub": "user-1", "iss": "plane",
+	}).SignedString([]byte("forged"))
+	recorder = requestWithHeader(router, "Authorization", bearerPrefix+forged)
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code, "failed to reject the token of a wrong key")
+
+	foreign, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub": "user-1", "iss": "others",
+	}).SignedString([]byte("secret"))
+	recorder = requestWithHeader(router, "Authorization", bearerPrefix+foreign)
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code, "failed to reject the token of another issuer")
+
+	endless, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub": "user-1", "iss": "plane",
+	}).SignedString([]byte("secret"))
+	recorder = requestWithHeader(router, "Authorization", bearerPrefix+endless)
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code, "failed to reject the token without expiration")
+
+	expired, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub": "user-1", "iss": "plane", "exp": time.Now().Add(-time.Hour).Unix(),
+	}).SignedString([]byte("secret"))
+	recorder = requestWithHeader(router, "Authorization", bearerPrefix+expired)
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code, "failed to reject the expired token")
+}
+
+func TestAuthByClientCert(t *testing.T) {
+	chain, err := NewChain([]map[string]interface{}{{
+		"name":            authModule,
+		"apiKeys":         []interface{}{map[string]interface{}{"key": "key-1"}},
+		"allowClientCert": true,
+	}})
+	assert.Equal(t, nil, err, "failed to create the auth middleware")
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set(ApplicantKey, &protocol.Applicant{ID: "svc-a"})
+	})
+	router.Use(chain...)
+	router.GET("/jobs", func(c *gin.Context) {
+		applicant, _ := GetApplicant(c)
+		c.String(http.StatusOK, applicant.ID)
+	})
+
+	recorder := requestWithHeader(router, "", "")
+	assert.Equal(t, "svc-a", recorder.Body.String(), "failed to take the applicant of the client certificate")
+}
@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"fmt"
+
+	"github.com/bigstack-oss/plane-go/pkg/frame/sync/plugin/plug"
+	"github.com/gin-gonic/gin"
+	"github.com/mohae/deepcopy"
+)
+
+const (
+	name = "name"
+
+	// InterfaceKey is the key of the interface name in the gin context
+	InterfaceKey = "interface"
+)
+
+var (
+	Plugins = make(map[string]Middleware)
+)
+
+// Middleware handles the requests before the interfaces, it calls Next of
+// the context to hand the request over, or Abort to answer it by itself
+type Middleware interface {
+	plug.ConfigSetter
+	plug.ConfigChecker
+	Handle(*gin.Context)
+}
+
+// New copies the registered middleware named in conf, and sets the copy
+// with conf
+func New(conf map[string]interface{}) (Middleware, error) {
+	middlewareName, _ := conf[name].(string)
+	template, isExisted := Plugins[middlewareName]
+	if !isExisted {
+		return nil, fmt.Errorf("middleware(%s) was not defined", middlewareName)
+	}
+
+	middleware := deepcopy.Copy(template).(Middleware)
+	middleware.SetConfig(conf)
+	err := middleware.CheckConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to set middleware(%s). error: %s", middlewareName, err.Error())
+	}
+
+	return middleware, nil
+}
+
+// NewChain creates the middlewares of confs in order
+func NewChain(confs []map[string]interface{}) (gin.HandlersChain, error) {
+	chain := gin.HandlersChain{}
+	for _, conf := range confs {
+		middleware, err := New(conf)
+		if err != nil {
+			return nil, err
+		}
+
+		chain = append(chain, middleware.Handle)
+	}
+
+	return chain, nil
+}
@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestRouter(t *testing.T, confs ...map[string]interface{}) *gin.Engine {
+	chain, err := NewChain(confs)
+	assert.Equal(t, nil, err, "failed to create the middlewares")
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(chain...)
+	router.POST("/jobs", func(c *gin.Context) {
+		_, err := c.GetRawData()
+		if err != nil {
+			c.Status(http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		c.String(http.StatusOK, c.GetString(RequestIDKey))
+	})
+
+	return router
+}
+
+func TestNewChain(t *testing.T) {
+	_, err := NewChain([]map[string]interface{}{{"name": "unknown"}})
+	assert.NotEqual(t, nil, err, "failed to reject the undefined middleware")
+
+	_, err = NewChain([]map[string]interface{}{{"name": bodyLimitModule}})
+	assert.NotEqual(t, nil, err, "failed to check the config of the middleware")
+}
+
+func TestRequestIDAndBodyLimit(t *testing.T) {
+	router := newTestRouter(t,
+		map[string]interface{}{"name": requestIDModule},
+		map[string]interface{}{"name": bodyLimitModule, "maxBytes": 4},
+	)
+
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodPost, "/jobs", strings.NewReader("ok"))
+	request.Header.Set(requestIDHeader, "request-1")
+	router.ServeHTTP(recorder, request)
+	assert.Equal(t, "request-1", recorder.Body.String(), "failed to keep the request id of the client")
+	assert.Equal(t, "request-1", recorder.Header().Get(requestIDHeader), "failed to respond with the request id")
+
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/jobs", strings.NewReader("too large")))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, recorder.Code, "failed to reject the large body")
+}
+
+func TestCors(t *testing.T) {
+	router := newTestRouter(t, map[string]interface{}{
+		"name":         corsModule,
+		"allowOrigins": []interface{}{"https://example.com"},
+		"allowHeaders": []interface{}{"Authorization"},
+	})
+
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodOptions, "/jobs", nil)
+	request.Header.Set("Origin", "https://example.com")
+	router.ServeHTTP(recorder, request)
+	assert.Equal(t, http.StatusNoContent, recorder.Code, "failed to answer the preflight request")
+	assert.Equal(t, "Authorization", recorder.Header().Get("Access-Control-Allow-Headers"), "failed to allow the headers")
+
+	recorder = httptest.NewRecorder()
+	request = httptest.NewRequest(http.MethodPost, "/jobs", nil)
+	request.Header.Set("Origin", "https://other.com")
+	router.ServeHTTP(recorder, request)
+	assert.Equal(t, "", recorder.Header().Get("Access-Control-Allow-Origin"), "failed to skip the origin which is not allowed")
+}
@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/goinggo/mapstructure"
+	"gopkg.in/go-playground/validator.v9"
+)
+
+const (
+	bodyLimitModule = "bodyLimit"
+)
+
+// BodyLimit rejects the requests with a body larger than MaxBytes, the
+// bodies without a length fail to be read beyond the limit
+type BodyLimit struct {
+	config bodyLimitConfig
+}
+
+type bodyLimitConfig struct {
+	Name     string `validate:"required"`
+	MaxBytes int64  `validate:"min=1"`
+}
+
+func init() {
+	Plugins[bodyLimitModule] = &BodyLimit{}
+}
+
+func (b *BodyLimit) SetConfig(conf interface{}) {
+	_ = mapstructure.Decode(conf, &b.config)
+}
+
+func (b *BodyLimit) CheckConfig() error {
+	return validator.New().Struct(b.config)
+}
+
+func (b *BodyLimit) Handle(c *gin.Context) {
+	if c.Request.ContentLength > b.config.MaxBytes {
+		c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{"error": "request body too large"})
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, b.config.MaxBytes)
+	c.Next()
+}
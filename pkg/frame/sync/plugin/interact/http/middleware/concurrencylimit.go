@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/bigstack-oss/plane-go/pkg/frame/sync/metric"
+	"github.com/gin-gonic/gin"
+	"github.com/goinggo/mapstructure"
+	"gopkg.in/go-playground/validator.v9"
+)
+
+const (
+	concurrencyLimitModule = "concurrencyLimit"
+)
+
+// ConcurrencyLimit limits the requests served at the same time, the requests
+// over the limit are rejected with 429 right away rather than queued
+type ConcurrencyLimit struct {
+	config concurrencyLimitConfig
+
+	slots chan struct{}
+}
+
+type concurrencyLimitConfig struct {
+	Name string `validate:"required"`
+	Max  int    `validate:"min=1"`
+}
+
+func init() {
+	Plugins[concurrencyLimitModule] = &ConcurrencyLimit{}
+}
+
+func (l *ConcurrencyLimit) SetConfig(conf interface{}) {
+	_ = mapstructure.Decode(conf, &l.config)
+	if l.config.Max > 0 {
+		l.slots = make(chan struct{}, l.config.Max)
+	}
+}
+
+func (l *ConcurrencyLimit) CheckConfig() error {
+	return validator.New().Struct(l.config)
+}
+
+func (l *ConcurrencyLimit) Handle(c *gin.Context) {
+	select {
+	case l.slots <- struct{}{}:
+	default:
+		metric.CountLimited(c.GetString(InterfaceKey), concurrencyLimitModule, "")
+		c.Header("Retry-After", "1")
+		c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": fmt.Sprintf("concurrency limit of %d exceeded", l.config.Max)})
+		return
+	}
+
+	defer func() {
+		<-l.slots
+	}()
+
+	c.Next()
+}
@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/goinggo/mapstructure"
+	"gopkg.in/go-playground/validator.v9"
+)
+
+const (
+	corsModule = "cors"
+	anyOrigin  = "*"
+)
+
+// Cors answers the preflight requests, and allows the listed origins to
+// read the responses. it has to be a global middleware to see the preflight
+// requests, since they match no route of the interfaces
+type Cors struct {
+	config corsConfig
+}
+
+type corsConfig struct {
+	Name         string   `validate:"required"`
+	AllowOrigins []string `validate:"min=1"`
+	AllowMethods []string
+	AllowHeaders []string
+	MaxAge       int `validate:"min=0"`
+}
+
+func init() {
+	Plugins[corsModule] = &Cors{}
+}
+
+func (o *Cors) SetConfig(conf interface{}) {
+	_ = mapstructure.Decode(conf, &o.config)
+	if len(o.config.AllowMethods) == 0 {
+		o.config.AllowMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete}
+	}
+}
+
+func (o *Cors) CheckConfig() error {
+	return validator.New().Struct(o.config)
+}
+
+func (o *Cors) isAllowed(origin string) bool {
+	for _, allowed := range o.config.AllowOrigins {
+		if allowed == anyOrigin || allowed == origin {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (o *Cors) Handle(c *gin.Context) {
+	origin := c.GetHeader("Origin")
+	if origin == "" || !o.isAllowed(origin) {
+		c.Next()
+		return
+	}
+
+	c.Header("Access-Control-Allow-Origin", origin)
+	c.Header("Vary", "Origin")
+	if c.Request.Method != http.MethodOptions {
+		c.Next()
+		return
+	}
+
+	c.Header("Access-Control-Allow-Methods", strings.Join(o.config.AllowMethods, ", "))
+	if len(o.config.AllowHeaders) > 0 {
+		c.Header("Access-Control-Allow-Headers", strings.Join(o.config.AllowHeaders, ", "))
+	}
+	if o.config.MaxAge > 0 {
+		c.Header("Access-Control-Max-Age", strconv.Itoa(o.config.MaxAge))
+	}
+
+	c.AbortWithStatus(http.StatusNoContent)
+}
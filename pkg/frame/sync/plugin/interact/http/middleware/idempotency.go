@@ -0,0 +1,415 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bigstack-oss/plane-go/pkg/frame/sync/plugin/plug"
+	"github.com/gin-gonic/gin"
+	"github.com/goinggo/mapstructure"
+	"github.com/mohae/deepcopy"
+	"gopkg.in/go-playground/validator.v9"
+)
+
+const (
+	idempotencyModule = "idempotency"
+	idempotencyHeader = "Idempotency-Key"
+	replayedHeader    = "Idempotent-Replayed"
+
+	memoryResponses = "memory"
+
+	defaultIdempotencyTtl = 86400
+)
+
+var (
+	// ResponseStores are the stores of the idempotency middleware
+	ResponseStores = make(map[string]ResponseStore)
+
+	// the stores created by their confs, they are shared by the middlewares
+	// and kept across the reloads, so that the retries are still answered
+	responseStores = make(map[string]ResponseStore)
+	responseMutex  = sync.Mutex{}
+
+	ErrInFlight = errors.New("request of the idempotency key is in flight")
+	ErrMismatch = errors.New("idempotency key was used by another request")
+
+	// the headers replayed along with the body
+	replayedHeaders = []string{"Content-Type", "Location"}
+)
+
+// StoredResponse is replayed to the retries of a request
+type StoredResponse struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// ResponseStore keeps the responses by the idempotency keys. Reserve marks
+// the key in flight, or returns the response stored, and the key is released
+// if the request is not completed
+type ResponseStore interface {
+	plug.ConfigSetter
+	plug.ConfigChecker
+	Reserve(key string, fingerprint string, ttl time.Duration) (*StoredResponse, error)
+	Complete(key string, response *StoredResponse) error
+	Release(key string) error
+}
+
+// Idempotency answers the retries of a request with the response of the
+// first attempt, the requests are keyed by the applicant and the key from
+// the header, or from Field of the json body. the failures with 5xx are not
+// stored, so that they can be retried. it has to be placed after the auth
+// middleware, which is checked by CheckOrder
+type Idempotency struct {
+	config idempotencyConfig
+
+	store ResponseStore
+	err   error
+}
+
+type idempotencyConfig struct {
+	Name    string `validate:"required"`
+	Header  string
+	Field   string
+	Methods []string
+	Ttl     int `validate:"min=0"`
+	Store   map[string]interface{}
+}
+
+// MemoryResponses keeps the responses in memory, the expired ones are swept
+// while the keys are reserved
+type MemoryResponses struct {
+	mutex   sync.Mutex
+	entries map[string]*responseEntry
+	sweptAt time.Time
+
+	config memoryResponsesConfig
+}
+
+type memoryResponsesConfig struct {
+	Name string `validate:"required"`
+}
+
+type responseEntry struct {
+	fingerprint string
+	response    *StoredResponse
+	expiredAt   time.Time
+}
+
+// recordingWriter keeps a copy of the body written to the client
+type recordingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func init() {
+	Plugins[idempotencyModule] = &Idempotency{}
+	ResponseStores[memoryResponses] = &MemoryResponses{}
+}
+
+func newResponseStore(conf map[string]interface{}) (ResponseStore, error) {
+	responseMutex.Lock()
+	defer responseMutex.Unlock()
+
+	confKey := fmt.Sprint(conf)
+	if store, isExisted := responseStores[confKey]; isExisted {
+		return store, nil
+	}
+
+	storeName, _ := conf[name].(string)
+	template, isExisted := ResponseStores[storeName]
+	if !isExisted {
+		return nil, fmt.Errorf("response store(%s) was not defined", storeName)
+	}
+
+	store := deepcopy.Copy(template).(ResponseStore)
+	store.SetConfig(conf)
+	err := store.CheckConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to set response store(%s). error: %s", storeName, err.Error())
+	}
+
+	responseStores[confKey] = store
+	return store, nil
+}
+
+// CheckOrder rejects the idempotency middleware placed before the auth
+// middleware in confs, since the responses are keyed by the applicant and
+// would be replayed to anyone otherwise
+func CheckOrder(confs []map[string]interface{}) error {
+	isIdempotent := false
+	for _, conf := range confs {
+		switch middlewareName, _ := conf[name].(string); middlewareName {
+		case idempotencyModule:
+			isIdempotent = true
+		case authModule:
+			if isIdempotent {
+				return fmt.Errorf("middleware(%s) has to be placed after middleware(%s)", idempotencyModule, authModule)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (i *Idempotency) SetConfig(conf interface{}) {
+	_ = mapstructure.Decode(conf, &i.config)
+	if i.config.Header == "" {
+		i.config.Header = idempotencyHeader
+	}
+	if i.config.Methods == nil {
+		i.config.Methods = []string{http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+	}
+	if i.config.Ttl == 0 {
+		i.config.Ttl = defaultIdempotencyTtl
+	}
+	if i.config.Store == nil {
+		i.config.Store = map[string]interface{}{name: memoryResponses}
+	}
+
+	i.store, i.err = newResponseStore(i.config.Store)
+}
+
+func (i *Idempotency) CheckConfig() error {
+	err := validator.New().Struct(i.config)
+	if err != nil {
+		return err
+	}
+
+	return i.err
+}
+
+func (i *Idempotency) isMutating(method string) bool {
+	for _, m := range i.config.Methods {
+		if strings.EqualFold(m, method) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// getField returns the value of the dotted field of the json body
+func getField(body []byte, field string) string {
+	var value interface{}
+	if json.Unmarshal(body, &value) != nil {
+		return ""
+	}
+
+	for _, key := range strings.Split(field, ".") {
+		object, isObject := value.(map[string]interface{})
+		if !isObject {
+			return ""
+		}
+
+		value = object[key]
+	}
+
+	if value == nil {
+		return ""
+	}
+
+	return fmt.Sprint(value)
+}
+
+func (i *Idempotency) getKey(c *gin.Context, body []byte) string {
+	key := c.GetHeader(i.config.Header)
+	if key == "" && i.config.Field != "" {
+		key = getField(body, i.config.Field)
+	}
+	if key == "" {
+		return ""
+	}
+
+	applicantID := ""
+	if applicant, isAuthenticated := GetApplicant(c); isAuthenticated {
+		applicantID = applicant.Project + "/" + applicant.ID
+	}
+
+	return strings.Join([]string{c.Request.Method, c.FullPath(), applicantID, key}, "|")
+}
+
+func readBody(c *gin.Context) ([]byte, error) {
+	if c.Request.Body == nil {
+		return nil, nil
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+func replay(c *gin.Context, response *StoredResponse) {
+	for key, values := range response.Header {
+		for _, value := range values {
+			c.Writer.Header().Add(key, value)
+		}
+	}
+
+	c.Header(replayedHeader, "true")
+	c.Data(response.Status, response.Header.Get("Content-Type"), response.Body)
+	c.Abort()
+}
+
+func record(writer *recordingWriter) *StoredResponse {
+	header := http.Header{}
+	for _, key := range replayedHeaders {
+		if value := writer.Header().Get(key); value != "" {
+			header.Set(key, value)
+		}
+	}
+
+	return &StoredResponse{Status: writer.Status(), Header: header, Body: writer.body.Bytes()}
+}
+
+func (i *Idempotency) Handle(c *gin.Context) {
+	if !i.isMutating(c.Request.Method) {
+		c.Next()
+		return
+	}
+
+	body, err := readBody(c)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	key := i.getKey(c, body)
+	if key == "" {
+		c.Next()
+		return
+	}
+
+	digest := sha256.Sum256(body)
+	stored, err := i.store.Reserve(key, hex.EncodeToString(digest[:]), time.Duration(i.config.Ttl)*time.Second)
+	switch {
+	case errors.Is(err, ErrInFlight):
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, ErrMismatch):
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	case stored != nil:
+		replay(c, stored)
+		return
+	}
+
+	isCompleted := false
+	defer func() {
+		if !isCompleted {
+			_ = i.store.Release(key)
+		}
+	}()
+
+	writer := &recordingWriter{ResponseWriter: c.Writer}
+	c.Writer = writer
+	c.Next()
+	c.Writer = writer.ResponseWriter
+
+	if writer.Status() >= http.StatusInternalServerError {
+		return
+	}
+
+	err = i.store.Complete(key, record(writer))
+	isCompleted = err == nil
+}
+
+func (w *recordingWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *recordingWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (m *MemoryResponses) SetConfig(conf interface{}) {
+	_ = mapstructure.Decode(conf, &m.config)
+	m.entries = make(map[string]*responseEntry)
+}
+
+func (m *MemoryResponses) CheckConfig() error {
+	return validator.New().Struct(m.config)
+}
+
+// sweep removes the expired entries once in a tenth of the ttl
+func (m *MemoryResponses) sweep(ttl time.Duration) {
+	if time.Since(m.sweptAt) < ttl/10 {
+		return
+	}
+
+	now := time.Now()
+	for key, entry := range m.entries {
+		if now.After(entry.expiredAt) {
+			delete(m.entries, key)
+		}
+	}
+
+	m.sweptAt = now
+}
+
+func (m *MemoryResponses) Reserve(key string, fingerprint string, ttl time.Duration) (*StoredResponse, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.sweep(ttl)
+	entry, isExisted := m.entries[key]
+	if !isExisted || time.Now().After(entry.expiredAt) {
+		m.entries[key] = &responseEntry{fingerprint: fingerprint, expiredAt: time.Now().Add(ttl)}
+		return nil, nil
+	}
+
+	if entry.fingerprint != fingerprint {
+		return nil, ErrMismatch
+	}
+
+	if entry.response == nil {
+		return nil, ErrInFlight
+	}
+
+	return entry.response, nil
+}
+
+func (m *MemoryResponses) Complete(key string, response *StoredResponse) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	entry, isExisted := m.entries[key]
+	if !isExisted {
+		return fmt.Errorf("idempotency key(%s) was not reserved", key)
+	}
+
+	entry.response = response
+	return nil
+}
+
+func (m *MemoryResponses) Release(key string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	delete(m.entries, key)
+	return nil
+}
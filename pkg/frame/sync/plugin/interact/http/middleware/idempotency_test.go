@@ -0,0 +1,99 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func newIdempotencyRouter(t *testing.T, conf map[string]interface{}, handler gin.HandlerFunc) *gin.Engine {
+	responseStores = make(map[string]ResponseStore)
+	return newRouter(t, conf, handler)
+}
+
+func newRouter(t *testing.T, conf map[string]interface{}, handler gin.HandlerFunc) *gin.Engine {
+	chain, err := NewChain([]map[string]interface{}{conf})
+	assert.Equal(t, nil, err, "failed to create the idempotency middleware")
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(chain...)
+	router.POST("/jobs", handler)
+	return router
+}
+
+func postWithKey(router *gin.Engine, key string, body string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodPost, "/jobs", strings.NewReader(body))
+	if key != "" {
+		request.Header.Set(idempotencyHeader, key)
+	}
+
+	router.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestIdempotencyReplay(t *testing.T) {
+	executed := 0
+	router := newIdempotencyRouter(t, map[string]interface{}{"name": idempotencyModule, "field": "id"}, func(c *gin.Context) {
+		executed++
+		c.JSON(http.StatusCreated, gin.H{"executed": executed})
+	})
+
+	first := postWithKey(router, "key-1", `{"id": "job-1"}`)
+	retried := postWithKey(router, "key-1", `{"id": "job-1"}`)
+	assert.Equal(t, 1, executed, "failed to skip the retried request")
+	assert.Equal(t, http.StatusCreated, retried.Code, "failed to replay the status code")
+	assert.Equal(t, first.Body.String(), retried.Body.String(), "failed to replay the body")
+	assert.Equal(t, "true", retried.Header().Get(replayedHeader), "failed to mark the replayed response")
+
+	assert.Equal(t, http.StatusUnprocessableEntity, postWithKey(router, "key-1", `{"id": "job-2"}`).Code, "failed to reject the key of another request")
+
+	postWithKey(router, "", `{"id": "job-3"}`)
+	postWithKey(router, "", `{"id": "job-3"}`)
+	assert.Equal(t, 2, executed, "failed to take the key from the field of the body")
+
+	postWithKey(router, "", `{}`)
+	postWithKey(router, "", `{}`)
+	assert.Equal(t, 4, executed, "failed to pass the requests without key through")
+}
+
+func TestIdempotencyInFlight(t *testing.T) {
+	var router *gin.Engine
+	inFlight := 0
+	router = newIdempotencyRouter(t, map[string]interface{}{"name": idempotencyModule}, func(c *gin.Context) {
+		inFlight = postWithKey(router, "key-1", "").Code
+		c.Status(http.StatusInternalServerError)
+	})
+
+	postWithKey(router, "key-1", "")
+	assert.Equal(t, http.StatusConflict, inFlight, "failed to reject the request in flight")
+
+	inFlight = 0
+	postWithKey(router, "key-1", "")
+	assert.Equal(t, http.StatusConflict, inFlight, "failed to release the key of the failed request")
+}
+
+func TestIdempotencyReload(t *testing.T) {
+	executed := 0
+	handler := func(c *gin.Context) {
+		executed++
+		c.Status(http.StatusCreated)
+	}
+
+	postWithKey(newIdempotencyRouter(t, map[string]interface{}{"name": idempotencyModule}, handler), "key-1", "")
+	postWithKey(newRouter(t, map[string]interface{}{"name": idempotencyModule}, handler), "key-1", "")
+	assert.Equal(t, 1, executed, "failed to keep the responses across reloads")
+}
+
+func TestCheckOrder(t *testing.T) {
+	auth := map[string]interface{}{"name": authModule}
+	idempotency := map[string]interface{}{"name": idempotencyModule}
+	assert.Equal(t, nil, CheckOrder([]map[string]interface{}{auth, idempotency}), "failed to allow the idempotency after auth")
+	assert.Equal(t, nil, CheckOrder([]map[string]interface{}{idempotency}), "failed to allow the idempotency without auth")
+	assert.NotEqual(t, nil, CheckOrder([]map[string]interface{}{idempotency, auth}), "failed to reject the idempotency before auth")
+}
@@ -0,0 +1,153 @@
+package middleware
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bigstack-oss/plane-go/pkg/frame/sync/metric"
+	"github.com/gin-gonic/gin"
+	"github.com/goinggo/mapstructure"
+	"golang.org/x/time/rate"
+	"gopkg.in/go-playground/validator.v9"
+)
+
+const (
+	rateLimitModule = "rateLimit"
+
+	keyByApplicant = "applicant"
+	keyByProject   = "project"
+	keyByIP        = "ip"
+	keyByHeader    = "header"
+
+	// the buckets which are not taken for so long are full again, so that
+	// they can be dropped
+	idleBucket = 10 * time.Minute
+)
+
+// RateLimit limits the requests by the token buckets of their keys, which
+// are the applicants, the projects, the client ips or a header. the requests
+// without applicant or without the header are keyed by the client ip, so they
+// don't share a bucket. every route has buckets of its own, even if the
+// middleware is shared by all interfaces. the rejected requests are
+// answered with 429 and when to retry
+type RateLimit struct {
+	config rateLimitConfig
+
+	mutex   sync.Mutex
+	buckets map[string]*bucket
+	sweptAt time.Time
+}
+
+type rateLimitConfig struct {
+	Name   string  `validate:"required"`
+	Rate   float64 `validate:"gt=0"`
+	Burst  int     `validate:"min=0"`
+	KeyBy  string  `validate:"omitempty,oneof=applicant project ip header"`
+	Header string
+}
+
+type bucket struct {
+	limiter *rate.Limiter
+	takenAt time.Time
+}
+
+func init() {
+	Plugins[rateLimitModule] = &RateLimit{}
+}
+
+func (r *RateLimit) SetConfig(conf interface{}) {
+	_ = mapstructure.Decode(conf, &r.config)
+	if r.config.Burst == 0 {
+		r.config.Burst = int(math.Ceil(r.config.Rate))
+	}
+	if r.config.KeyBy == "" {
+		r.config.KeyBy = keyByIP
+	}
+
+	r.buckets = make(map[string]*bucket)
+}
+
+func (r *RateLimit) CheckConfig() error {
+	if r.config.KeyBy == keyByHeader && r.config.Header == "" {
+		return fmt.Errorf("header is required for %s keyed by %s", rateLimitModule, keyByHeader)
+	}
+
+	return validator.New().Struct(r.config)
+}
+
+// getKey prefixes the key with the route and where the key comes from, so
+// that the keys of different sources never share a bucket
+func (r *RateLimit) getKey(c *gin.Context) string {
+	source, key := r.getSource(c)
+	return strings.Join([]string{c.Request.Method, c.FullPath(), source, key}, "|")
+}
+
+func (r *RateLimit) getSource(c *gin.Context) (string, string) {
+	applicant, isAuthenticated := GetApplicant(c)
+	switch {
+	case r.config.KeyBy == keyByApplicant && isAuthenticated && applicant.ID != "":
+		return keyByApplicant, applicant.ID
+	case r.config.KeyBy == keyByProject && isAuthenticated && applicant.Project != "":
+		return keyByProject, applicant.Project
+	case r.config.KeyBy == keyByHeader && c.GetHeader(r.config.Header) != "":
+		return keyByHeader, c.GetHeader(r.config.Header)
+	default:
+		return keyByIP, c.ClientIP()
+	}
+}
+
+// sweep drops the idle buckets once in a while
+func (r *RateLimit) sweep(now time.Time) {
+	if now.Sub(r.sweptAt) < idleBucket {
+		return
+	}
+
+	for key, b := range r.buckets {
+		if now.Sub(b.takenAt) > idleBucket {
+			delete(r.buckets, key)
+		}
+	}
+
+	r.sweptAt = now
+}
+
+// take takes a token of the key, and tells how long to wait if there is none
+func (r *RateLimit) take(key string) (bool, time.Duration) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	now := time.Now()
+	r.sweep(now)
+	b, isExisted := r.buckets[key]
+	if !isExisted {
+		b = &bucket{limiter: rate.NewLimiter(rate.Limit(r.config.Rate), r.config.Burst)}
+		r.buckets[key] = b
+	}
+
+	b.takenAt = now
+	reservation := b.limiter.ReserveN(now, 1)
+	delay := reservation.DelayFrom(now)
+	if delay == 0 {
+		return true, 0
+	}
+
+	reservation.CancelAt(now)
+	return false, delay
+}
+
+func (r *RateLimit) Handle(c *gin.Context) {
+	isTaken, delay := r.take(r.getKey(c))
+	if isTaken {
+		c.Next()
+		return
+	}
+
+	metric.CountLimited(c.GetString(InterfaceKey), rateLimitModule, r.config.KeyBy)
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(delay.Seconds()))))
+	c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": fmt.Sprintf("rate limit of %g per second exceeded", r.config.Rate)})
+}
@@ -0,0 +1,121 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bigstack-oss/plane-go/pkg/base/protocol"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func newLimitRouter(t *testing.T, conf map[string]interface{}, handler gin.HandlerFunc) *gin.Engine {
+	chain, err := NewChain([]map[string]interface{}{conf})
+	assert.Equal(t, nil, err, "failed to create the limiter")
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(chain...)
+	router.GET("/jobs", handler)
+	router.GET("/jobs/:id", handler)
+	return router
+}
+
+func getWithTenant(router *gin.Engine, tenant string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodGet, "/jobs", nil)
+	request.Header.Set("X-Tenant", tenant)
+	router.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestRateLimitConfig(t *testing.T) {
+	_, err := NewChain([]map[string]interface{}{{"name": rateLimitModule}})
+	assert.NotEqual(t, nil, err, "failed to reject the rate limit without rate")
+
+	_, err = NewChain([]map[string]interface{}{{"name": rateLimitModule, "rate": 1, "keyBy": keyByHeader}})
+	assert.NotEqual(t, nil, err, "failed to reject the header key without header")
+}
+
+func TestRateLimit(t *testing.T) {
+	router := newLimitRouter(t, map[string]interface{}{
+		"name":   rateLimitModule,
+		"rate":   0.1,
+		"burst":  2,
+		"keyBy":  keyByHeader,
+		"header": "X-Tenant",
+	}, func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	assert.Equal(t, http.StatusOK, getWithTenant(router, "a").Code, "failed to take the burst")
+	assert.Equal(t, http.StatusOK, getWithTenant(router, "a").Code, "failed to take the burst")
+
+	recorder := getWithTenant(router, "a")
+	assert.Equal(t, http.StatusTooManyRequests, recorder.Code, "failed to reject the request over the limit")
+	assert.Equal(t, "10", recorder.Header().Get("Retry-After"), "failed to tell when to retry")
+
+	assert.Equal(t, http.StatusOK, getWithTenant(router, "b").Code, "failed to limit the keys separately")
+
+	recorder = httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodGet, "/jobs/job-1", nil)
+	request.Header.Set("X-Tenant", "a")
+	router.ServeHTTP(recorder, request)
+	assert.Equal(t, http.StatusOK, recorder.Code, "failed to limit the routes separately")
+}
+
+func TestRateLimitWithoutHeader(t *testing.T) {
+	router := newLimitRouter(t, map[string]interface{}{
+		"name":   rateLimitModule,
+		"rate":   0.1,
+		"burst":  1,
+		"keyBy":  keyByHeader,
+		"header": "X-Tenant",
+	}, func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	get := func(remoteAddr string) int {
+		recorder := httptest.NewRecorder()
+		request := httptest.NewRequest(http.MethodGet, "/jobs", nil)
+		request.RemoteAddr = remoteAddr
+		router.ServeHTTP(recorder, request)
+		return recorder.Code
+	}
+
+	assert.Equal(t, http.StatusOK, get("10.0.0.1:1234"), "failed to take the burst")
+	assert.Equal(t, http.StatusTooManyRequests, get("10.0.0.1:1234"), "failed to limit the requests without header by client ip")
+	assert.Equal(t, http.StatusOK, get("10.0.0.2:1234"), "failed to key the requests without header by client ip")
+}
+
+func TestRateLimitKey(t *testing.T) {
+	r := &RateLimit{}
+	r.SetConfig(map[string]interface{}{"name": rateLimitModule, "rate": 1, "keyBy": keyByApplicant})
+
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/jobs", nil)
+	c.Request.RemoteAddr = "10.0.0.1:1234"
+	c.Set(ApplicantKey, &protocol.Applicant{Project: "demo"})
+	assert.Equal(t, "GET||ip|10.0.0.1", r.getKey(c), "failed to key the applicant without id by client ip")
+
+	c.Set(ApplicantKey, &protocol.Applicant{ID: "10.0.0.1"})
+	assert.Equal(t, "GET||applicant|10.0.0.1", r.getKey(c), "failed to keep the applicant apart from the client ip")
+}
+
+func TestConcurrencyLimit(t *testing.T) {
+	var router *gin.Engine
+	nested := 0
+	router = newLimitRouter(t, map[string]interface{}{"name": concurrencyLimitModule, "max": 1}, func(c *gin.Context) {
+		if c.GetHeader("X-Tenant") == "outer" {
+			nested = getWithTenant(router, "inner").Code
+		}
+
+		c.Status(http.StatusOK)
+	})
+
+	assert.Equal(t, http.StatusOK, getWithTenant(router, "outer").Code, "failed to serve the request under the limit")
+	assert.Equal(t, http.StatusTooManyRequests, nested, "failed to reject the request over the limit")
+	assert.Equal(t, http.StatusOK, getWithTenant(router, "inner").Code, "failed to release the slot")
+}
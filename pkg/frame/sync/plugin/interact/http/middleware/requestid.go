@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/goinggo/mapstructure"
+	"github.com/google/uuid"
+	"gopkg.in/go-playground/validator.v9"
+)
+
+const (
+	requestIDModule = "requestId"
+	requestIDHeader = "X-Request-Id"
+
+	// RequestIDKey is the key of the request id in the gin context
+	RequestIDKey = "requestId"
+)
+
+// RequestID keeps the request id given by the client, or generates one, and
+// responds with it in the header
+type RequestID struct {
+	config requestIDConfig
+}
+
+type requestIDConfig struct {
+	Name   string `validate:"required"`
+	Header string
+}
+
+func init() {
+	Plugins[requestIDModule] = &RequestID{}
+}
+
+func (r *RequestID) SetConfig(conf interface{}) {
+	_ = mapstructure.Decode(conf, &r.config)
+	if r.config.Header == "" {
+		r.config.Header = requestIDHeader
+	}
+}
+
+func (r *RequestID) CheckConfig() error {
+	return validator.New().Struct(r.config)
+}
+
+func (r *RequestID) Handle(c *gin.Context) {
+	requestID := c.GetHeader(r.config.Header)
+	if requestID == "" {
+		requestID = uuid.NewString()
+	}
+
+	c.Set(RequestIDKey, requestID)
+	c.Header(r.config.Header, requestID)
+	c.Next()
+}
@@ -0,0 +1,259 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	version = "3.0.3"
+)
+
+var (
+	// the params of gin, like :id and *path
+	paramPattern = regexp.MustCompile(`[:*]([^/]+)`)
+
+	timeType  = reflect.TypeOf(time.Time{})
+	bytesType = reflect.TypeOf([]byte{})
+)
+
+// Raw is a schema written by hand, it is taken as it is rather than
+// reflected from a go value
+type Raw map[string]interface{}
+
+// Schema describes the request and the response of an interface. Request
+// and Response are go values whose types are reflected into schemas, or Raw
+// schemas. the operation has no body of the request or the response if it
+// is nil
+type Schema struct {
+	Summary     string
+	Description string
+	Request     interface{}
+	Response    interface{}
+	// the status code of the response, it is 200 by default
+	Status int
+}
+
+// Operation is an operation of the document
+type Operation struct {
+	Method     string
+	Path       string
+	Name       string
+	Schema     Schema
+	Extensions map[string]interface{}
+}
+
+// Document is built from the interfaces, the go types of the schemas are
+// put into the components by their names
+type Document struct {
+	Title   string
+	Version string
+
+	paths      map[string]map[string]interface{}
+	components map[string]interface{}
+	names      map[reflect.Type]string
+}
+
+func NewDocument(title string, version string) *Document {
+	return &Document{
+		Title:      title,
+		Version:    version,
+		paths:      make(map[string]map[string]interface{}),
+		components: make(map[string]interface{}),
+		names:      make(map[reflect.Type]string),
+	}
+}
+
+// ConvertPath converts the path of gin into the one of openapi, and returns
+// the names of the params in it
+func ConvertPath(ginPath string) (string, []string) {
+	params := []string{}
+	converted := paramPattern.ReplaceAllStringFunc(ginPath, func(param string) string {
+		params = append(params, param[1:])
+		return "{" + param[1:] + "}"
+	})
+
+	return converted, params
+}
+
+// getComponentName names the struct by its type name, the package name is
+// prefixed if the name was taken by another type
+func (d *Document) getComponentName(t reflect.Type) string {
+	componentName := t.Name()
+	for _, taken := range d.names {
+		if taken == componentName {
+			componentName = path.Base(t.PkgPath()) + "." + t.Name()
+			break
+		}
+	}
+
+	return componentName
+}
+
+func (d *Document) structSchema(t reflect.Type) map[string]interface{} {
+	properties := make(map[string]interface{})
+	d.addProperties(t, properties)
+	return map[string]interface{}{"type": "object", "properties": properties}
+}
+
+// addProperties puts the fields of the struct into properties like
+// encoding/json, the embedded structs without a json name are flattened
+func (d *Document) addProperties(t reflect.Type, properties map[string]interface{}) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" || (!field.IsExported() && !field.Anonymous) {
+			continue
+		}
+
+		fieldName := strings.Split(tag, ",")[0]
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+
+		if field.Anonymous && fieldName == "" && fieldType.Kind() == reflect.Struct {
+			d.addProperties(fieldType, properties)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+
+		if fieldName == "" {
+			fieldName = field.Name
+		}
+
+		properties[fieldName] = d.schemaOf(field.Type)
+	}
+}
+
+func (d *Document) schemaOf(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case t == bytesType:
+		return map[string]interface{}{"type": "string", "format": "byte"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]interface{}{"type": "integer", "format": "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": d.schemaOf(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": d.schemaOf(t.Elem())}
+	case reflect.Struct:
+		return d.refOf(t)
+	default:
+		return map[string]interface{}{}
+	}
+}
+
+// refOf puts the struct into the components, the anonymous structs are
+// inlined
+func (d *Document) refOf(t reflect.Type) map[string]interface{} {
+	if t.Name() == "" {
+		return d.structSchema(t)
+	}
+
+	componentName, isExisted := d.names[t]
+	if !isExisted {
+		componentName = d.getComponentName(t)
+		d.names[t] = componentName
+		d.components[componentName] = d.structSchema(t)
+	}
+
+	return map[string]interface{}{"$ref": "#/components/schemas/" + componentName}
+}
+
+// SchemaOf returns the schema of the go value, or the Raw schema as it is
+func (d *Document) SchemaOf(v interface{}) map[string]interface{} {
+	raw, isRaw := v.(Raw)
+	if isRaw {
+		return raw
+	}
+
+	return d.schemaOf(reflect.TypeOf(v))
+}
+
+func (d *Document) content(v interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"application/json": map[string]interface{}{"schema": d.SchemaOf(v)},
+	}
+}
+
+// AddOperation puts the operation into the paths of the document
+func (d *Document) AddOperation(o Operation) {
+	openapiPath, params := ConvertPath(o.Path)
+	operation := map[string]interface{}{"operationId": o.Name}
+	if o.Schema.Summary != "" {
+		operation["summary"] = o.Schema.Summary
+	}
+	if o.Schema.Description != "" {
+		operation["description"] = o.Schema.Description
+	}
+
+	parameters := []interface{}{}
+	for _, param := range params {
+		parameters = append(parameters, map[string]interface{}{
+			"name": param, "in": "path", "required": true, "schema": map[string]interface{}{"type": "string"},
+		})
+	}
+	if len(parameters) > 0 {
+		operation["parameters"] = parameters
+	}
+
+	if o.Schema.Request != nil {
+		operation["requestBody"] = map[string]interface{}{"content": d.content(o.Schema.Request)}
+	}
+
+	status := o.Schema.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	response := map[string]interface{}{"description": http.StatusText(status)}
+	if o.Schema.Response != nil {
+		response["content"] = d.content(o.Schema.Response)
+	}
+	operation["responses"] = map[string]interface{}{fmt.Sprint(status): response}
+
+	for key, value := range o.Extensions {
+		operation["x-"+key] = value
+	}
+
+	if d.paths[openapiPath] == nil {
+		d.paths[openapiPath] = make(map[string]interface{})
+	}
+	d.paths[openapiPath][strings.ToLower(o.Method)] = operation
+}
+
+func (d *Document) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]interface{}{
+		"openapi": version,
+		"info":    map[string]interface{}{"title": d.Title, "version": d.Version},
+		"paths":   d.paths,
+		"components": map[string]interface{}{
+			"schemas": d.components,
+		},
+	})
+}
@@ -0,0 +1,69 @@
+package openapi
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testEmbedded struct {
+	Kind string `json:"kind"`
+}
+
+type testNode struct {
+	testEmbedded
+	Name      string            `json:"name"`
+	Children  []*testNode       `json:"children,omitempty"`
+	Labels    map[string]string `json:"labels"`
+	Data      []byte            `json:"data"`
+	CreatedAt time.Time         `json:"createdAt"`
+	Ignored   string            `json:"-"`
+	hidden    string
+}
+
+func TestConvertPath(t *testing.T) {
+	converted, params := ConvertPath("/apis/v1/:project/jobs/*path")
+	assert.Equal(t, "/apis/v1/{project}/jobs/{path}", converted, "failed to convert the params")
+	assert.Equal(t, []string{"project", "path"}, params, "failed to return the params")
+}
+
+func TestSchemaOf(t *testing.T) {
+	d := NewDocument("test", "1.0.0")
+	schema := d.SchemaOf(testNode{})
+	assert.Equal(t, "#/components/schemas/testNode", schema["$ref"], "failed to refer to the struct")
+
+	properties := d.components["testNode"].(map[string]interface{})["properties"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"type": "string"}, properties["kind"], "failed to flatten the embedded struct")
+	assert.Equal(t, map[string]interface{}{"type": "array", "items": schema}, properties["children"], "failed to refer to the recursive struct")
+	assert.Equal(t, map[string]interface{}{"type": "string", "format": "byte"}, properties["data"], "failed to take bytes as a string")
+	assert.Equal(t, map[string]interface{}{"type": "string", "format": "date-time"}, properties["createdAt"], "failed to take time as a string")
+	assert.NotContains(t, properties, "Ignored", "failed to skip the ignored field")
+	assert.NotContains(t, properties, "hidden", "failed to skip the unexported field")
+
+	assert.Equal(t, map[string]interface{}{"type": "string"}, d.SchemaOf(Raw{"type": "string"}), "failed to take the raw schema")
+}
+
+func TestAddOperation(t *testing.T) {
+	d := NewDocument("test", "1.0.0")
+	d.AddOperation(Operation{
+		Method:     "POST",
+		Path:       "/jobs/:id",
+		Name:       "post",
+		Schema:     Schema{Request: testNode{}, Status: 202},
+		Extensions: map[string]interface{}{"stages": []string{"transit"}},
+	})
+
+	b, err := json.Marshal(d)
+	assert.Equal(t, nil, err, "failed to marshal the document")
+
+	document := map[string]interface{}{}
+	_ = json.Unmarshal(b, &document)
+	operation := document["paths"].(map[string]interface{})["/jobs/{id}"].(map[string]interface{})["post"].(map[string]interface{})
+	assert.Equal(t, "post", operation["operationId"], "failed to name the operation")
+	assert.Contains(t, operation["responses"], "202", "failed to respond with the status")
+	assert.Contains(t, operation, "requestBody", "failed to describe the request")
+	assert.Contains(t, operation, "parameters", "failed to describe the path params")
+	assert.Contains(t, operation, "x-stages", "failed to put the extensions")
+}
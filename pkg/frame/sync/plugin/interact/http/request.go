@@ -0,0 +1,28 @@
+package http
+
+import (
+	"errors"
+
+	"github.com/bigstack-oss/plane-go/pkg/frame/sync/plugin/interact/http/interfacehttp"
+)
+
+// requestConfig declares the body of the interface, by the name of a go
+// struct registered in interfacehttp.Requests, or by a json schema file
+type requestConfig struct {
+	Type       string
+	SchemaFile string
+}
+
+// newRequestValidator returns nil if the interface declares no request
+func newRequestValidator(conf requestConfig) (interfacehttp.RequestValidator, error) {
+	switch {
+	case conf.Type != "" && conf.SchemaFile != "":
+		return nil, errors.New("request can not declare both type and schemaFile")
+	case conf.Type != "":
+		return interfacehttp.NewStructValidator(conf.Type)
+	case conf.SchemaFile != "":
+		return interfacehttp.NewSchemaValidator(conf.SchemaFile)
+	default:
+		return nil, nil
+	}
+}
@@ -0,0 +1,38 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/bigstack-oss/plane-go/pkg/frame/sync/plugin/interact/certs"
+	"github.com/bigstack-oss/plane-go/pkg/frame/sync/plugin/interact/http/middleware"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// tlsServer serves https with the certificates of the reloader
+type tlsServer struct {
+	*http.Server
+	reloader *certs.Reloader
+	logf     *zap.SugaredLogger
+}
+
+func (t *tlsServer) ListenAndServe() error {
+	err := t.reloader.Watch()
+	if err != nil {
+		t.logf.Errorf("failed to watch tls files, they are not reloaded. error: %s", err.Error())
+	}
+
+	defer t.reloader.Close()
+	return t.Server.ListenAndServeTLS("", "")
+}
+
+// setPeer exposes the verified client certificate as the applicant of the
+// request, the auth middleware replaces it if it authenticates the request
+func (h *Http) setPeer(c *gin.Context) {
+	applicant, isVerified := certs.GetApplicant(c.Request.TLS)
+	if isVerified {
+		c.Set(middleware.ApplicantKey, applicant)
+	}
+
+	c.Next()
+}
@@ -0,0 +1,154 @@
+package jobstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/bigstack-oss/plane-go/pkg/base/log"
+	"github.com/bigstack-oss/plane-go/pkg/base/protocol"
+	"github.com/bigstack-oss/plane-go/pkg/frame/sync/plugin/plug"
+	"github.com/mohae/deepcopy"
+)
+
+const (
+	module = "jobStore"
+	name   = "name"
+
+	// Memory is the store taken if the store is not configured
+	Memory = "memory"
+
+	StatusAccepted  = "accepted"
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+
+	defaultTtl = 3600
+)
+
+var (
+	Plugins = make(map[string]Store)
+
+	ErrNotFound  = errors.New("job was not found")
+	ErrExisted   = errors.New("job already existed")
+	ErrInvalidID = errors.New("invalid job id")
+
+	idPattern = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+	storeLoggerf = log.GetLogger(module).Sugar()
+)
+
+// Store keeps the jobs run in the background, the jobs are removed once
+// they have not been updated for the ttl of the store
+type Store interface {
+	plug.ConfigSetter
+	plug.ConfigChecker
+	Create(*protocol.Job) error
+	Update(*protocol.Job) error
+	Get(id string) (*protocol.Job, error)
+	GetTtl() time.Duration
+	Clean() error
+}
+
+type storeKey struct{}
+
+// Cleaner removes the expired jobs of the store periodically until it is
+// stopped, it never starts once it is stopped
+type Cleaner struct {
+	store Store
+	done  chan struct{}
+	once  sync.Once
+}
+
+// New copies the registered store named in conf, and sets the copy with
+// conf
+func New(conf map[string]interface{}) (Store, error) {
+	storeName, _ := conf[name].(string)
+	template, isExisted := Plugins[storeName]
+	if !isExisted {
+		return nil, fmt.Errorf("job store(%s) was not defined", storeName)
+	}
+
+	store := deepcopy.Copy(template).(Store)
+	store.SetConfig(conf)
+	err := store.CheckConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to set job store(%s). error: %s", storeName, err.Error())
+	}
+
+	return store, nil
+}
+
+// CheckID rejects the ids which are not safe to be keys or file names
+func CheckID(id string) error {
+	if !idPattern.MatchString(id) {
+		return fmt.Errorf("%w(%s)", ErrInvalidID, id)
+	}
+
+	return nil
+}
+
+func getTtl(ttl int) time.Duration {
+	if ttl <= 0 {
+		ttl = defaultTtl
+	}
+
+	return time.Duration(ttl) * time.Second
+}
+
+// getCleanInterval checks the expired jobs ten times in a ttl, but no more
+// often than once a second
+func getCleanInterval(ttl time.Duration) time.Duration {
+	interval := ttl / 10
+	if interval < time.Second {
+		return time.Second
+	}
+
+	return interval
+}
+
+func NewCleaner(store Store) *Cleaner {
+	return &Cleaner{store: store, done: make(chan struct{})}
+}
+
+// Start cleans the store ten times in its ttl
+func (c *Cleaner) Start() {
+	ticker := time.NewTicker(getCleanInterval(c.store.GetTtl()))
+	go func() {
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-c.done:
+				return
+			case <-ticker.C:
+				err := c.store.Clean()
+				if err != nil {
+					storeLoggerf.Errorf("failed to clean expired jobs. error: %s", err.Error())
+				}
+			}
+		}
+	}()
+}
+
+func (c *Cleaner) Stop() {
+	c.once.Do(func() {
+		close(c.done)
+	})
+}
+
+// NewContext puts the store into ctx, so that the job of the request runs in
+// the background and is kept in the store
+func NewContext(ctx context.Context, store Store) context.Context {
+	return context.WithValue(ctx, storeKey{}, store)
+}
+
+// FromContext returns the store of the request, if the job of the request
+// runs in the background
+func FromContext(ctx context.Context) (Store, bool) {
+	store, isExisted := ctx.Value(storeKey{}).(Store)
+	return store, isExisted && store != nil
+}
@@ -0,0 +1,67 @@
+package jobstore
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bigstack-oss/plane-go/pkg/base/protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+func testStore(t *testing.T, store Store) {
+	job := &protocol.Job{ID: "job-1", Result: &protocol.Result{Status: StatusAccepted}}
+	assert.Equal(t, nil, store.Create(job), "failed to create the job")
+	assert.Equal(t, true, errors.Is(store.Create(job), ErrExisted), "failed to reject the existed job")
+	assert.Equal(t, true, errors.Is(store.Create(&protocol.Job{ID: "../job"}), ErrInvalidID), "failed to reject the invalid id")
+
+	job.Result.Status = StatusSucceeded
+	assert.Equal(t, nil, store.Update(job), "failed to update the job")
+	assert.Equal(t, true, errors.Is(store.Update(&protocol.Job{ID: "job-2"}), ErrNotFound), "failed to reject the job not created")
+
+	got, err := store.Get("job-1")
+	assert.Equal(t, nil, err, "failed to get the job")
+	assert.Equal(t, StatusSucceeded, got.Result.Status, "failed to keep the updated job")
+
+	got.Result.Status = StatusFailed
+	got, _ = store.Get("job-1")
+	assert.Equal(t, StatusSucceeded, got.Result.Status, "failed to keep the job from the change of the caller")
+
+	_, err = store.Get("job-2")
+	assert.Equal(t, true, errors.Is(err, ErrNotFound), "failed to tell the job was not found")
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(map[string]interface{}{"name": "unknown"})
+	assert.NotEqual(t, nil, err, "failed to reject the undefined store")
+
+	_, err = New(map[string]interface{}{"name": fileStore})
+	assert.NotEqual(t, nil, err, "failed to check the config of the store")
+}
+
+func TestMemoryStore(t *testing.T) {
+	store, err := New(map[string]interface{}{"name": Memory})
+	assert.Equal(t, nil, err, "failed to create the store")
+	assert.Equal(t, time.Duration(defaultTtl)*time.Second, store.GetTtl(), "failed to take the default ttl")
+
+	testStore(t, store)
+}
+
+func TestFileStore(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "jobs")
+	store, err := New(map[string]interface{}{"name": fileStore, "dir": dir, "ttl": 60})
+	assert.Equal(t, nil, err, "failed to create the store")
+
+	testStore(t, store)
+
+	expired := time.Now().Add(-time.Hour)
+	assert.Equal(t, nil, os.Chtimes(filepath.Join(dir, "job-1.json"), expired, expired), "failed to age the job")
+	_, err = store.Get("job-1")
+	assert.Equal(t, true, errors.Is(err, ErrNotFound), "failed to expire the job")
+
+	assert.Equal(t, nil, store.Clean(), "failed to clean the store")
+	entries, _ := os.ReadDir(dir)
+	assert.Equal(t, 0, len(entries), "failed to remove the expired job")
+}
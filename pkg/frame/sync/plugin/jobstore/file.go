@@ -0,0 +1,172 @@
+package jobstore
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bigstack-oss/plane-go/pkg/base/protocol"
+	"github.com/goinggo/mapstructure"
+	"gopkg.in/go-playground/validator.v9"
+)
+
+const (
+	fileStore = "file"
+	fileExt   = ".json"
+)
+
+// FileStore keeps every job as a json file in Dir, so that the jobs survive
+// reloads and restarts. the modification time of a file is when the job was
+// updated
+type FileStore struct {
+	mutex sync.Mutex
+
+	config fileConfig
+}
+
+type fileConfig struct {
+	Name string `validate:"required"`
+	Ttl  int    `validate:"min=0"`
+	Dir  string `validate:"required"`
+}
+
+func init() {
+	Plugins[fileStore] = &FileStore{}
+}
+
+func (f *FileStore) SetConfig(conf interface{}) {
+	_ = mapstructure.Decode(conf, &f.config)
+}
+
+func (f *FileStore) CheckConfig() error {
+	err := validator.New().Struct(f.config)
+	if err != nil {
+		return err
+	}
+
+	return os.MkdirAll(f.config.Dir, 0755)
+}
+
+func (f *FileStore) GetTtl() time.Duration {
+	return getTtl(f.config.Ttl)
+}
+
+func (f *FileStore) getPath(id string) string {
+	return filepath.Join(f.config.Dir, id+fileExt)
+}
+
+func (f *FileStore) isExpired(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && time.Since(info.ModTime()) > f.GetTtl()
+}
+
+// write replaces the file of the job, the readers never see a partial one
+func (f *FileStore) write(job *protocol.Job) error {
+	b, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+
+	temp, err := os.CreateTemp(f.config.Dir, "."+job.ID+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+
+	_, err = temp.Write(b)
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(temp.Name(), f.getPath(job.ID))
+}
+
+func (f *FileStore) Create(job *protocol.Job) error {
+	err := CheckID(job.ID)
+	if err != nil {
+		return err
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	path := f.getPath(job.ID)
+	_, err = os.Stat(path)
+	if err == nil && !f.isExpired(path) {
+		return ErrExisted
+	}
+
+	return f.write(job)
+}
+
+func (f *FileStore) Update(job *protocol.Job) error {
+	err := CheckID(job.ID)
+	if err != nil {
+		return err
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	_, err = os.Stat(f.getPath(job.ID))
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrNotFound
+	}
+
+	return f.write(job)
+}
+
+func (f *FileStore) Get(id string) (*protocol.Job, error) {
+	if CheckID(id) != nil {
+		return nil, ErrNotFound
+	}
+
+	path := f.getPath(id)
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) || f.isExpired(path) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	job := &protocol.Job{}
+	err = json.Unmarshal(b, job)
+	if err != nil {
+		return nil, fmt.Errorf("malformed job file(%s). error: %s", path, err.Error())
+	}
+
+	return job, nil
+}
+
+func (f *FileStore) Clean() error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	entries, err := os.ReadDir(f.config.Dir)
+	if err != nil {
+		return err
+	}
+
+	errs := []error{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), fileExt) {
+			continue
+		}
+
+		path := filepath.Join(f.config.Dir, entry.Name())
+		if f.isExpired(path) {
+			errs = append(errs, os.Remove(path))
+		}
+	}
+
+	return errors.Join(errs...)
+}
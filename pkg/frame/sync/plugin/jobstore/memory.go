@@ -0,0 +1,113 @@
+package jobstore
+
+import (
+	"sync"
+	"time"
+
+	"github.com/bigstack-oss/plane-go/pkg/base/protocol"
+	"github.com/goinggo/mapstructure"
+	"github.com/mohae/deepcopy"
+	"gopkg.in/go-playground/validator.v9"
+)
+
+// MemoryStore keeps copies of the jobs in memory, they are lost once the
+// interact plugin is reloaded or the service restarts
+type MemoryStore struct {
+	mutex   sync.RWMutex
+	entries map[string]memoryEntry
+
+	config memoryConfig
+}
+
+type memoryConfig struct {
+	Name string `validate:"required"`
+	Ttl  int    `validate:"min=0"`
+}
+
+type memoryEntry struct {
+	job       *protocol.Job
+	updatedAt time.Time
+}
+
+func init() {
+	Plugins[Memory] = &MemoryStore{}
+}
+
+func (m *MemoryStore) SetConfig(conf interface{}) {
+	_ = mapstructure.Decode(conf, &m.config)
+	m.entries = make(map[string]memoryEntry)
+}
+
+func (m *MemoryStore) CheckConfig() error {
+	return validator.New().Struct(m.config)
+}
+
+func (m *MemoryStore) isExpired(entry memoryEntry) bool {
+	return time.Since(entry.updatedAt) > m.GetTtl()
+}
+
+func (m *MemoryStore) put(job *protocol.Job) {
+	copied := deepcopy.Copy(*job).(protocol.Job)
+	m.entries[job.ID] = memoryEntry{job: &copied, updatedAt: time.Now()}
+}
+
+func (m *MemoryStore) Create(job *protocol.Job) error {
+	err := CheckID(job.ID)
+	if err != nil {
+		return err
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	entry, isExisted := m.entries[job.ID]
+	if isExisted && !m.isExpired(entry) {
+		return ErrExisted
+	}
+
+	m.put(job)
+	return nil
+}
+
+func (m *MemoryStore) Update(job *protocol.Job) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	_, isExisted := m.entries[job.ID]
+	if !isExisted {
+		return ErrNotFound
+	}
+
+	m.put(job)
+	return nil
+}
+
+func (m *MemoryStore) Get(id string) (*protocol.Job, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	entry, isExisted := m.entries[id]
+	if !isExisted || m.isExpired(entry) {
+		return nil, ErrNotFound
+	}
+
+	copied := deepcopy.Copy(*entry.job).(protocol.Job)
+	return &copied, nil
+}
+
+func (m *MemoryStore) Clean() error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for id, entry := range m.entries {
+		if m.isExpired(entry) {
+			delete(m.entries, id)
+		}
+	}
+
+	return nil
+}
+
+func (m *MemoryStore) GetTtl() time.Duration {
+	return getTtl(m.config.Ttl)
+}
@@ -0,0 +1,32 @@
+package plug
+
+import (
+	"context"
+
+	"github.com/bigstack-oss/plane-go/pkg/base/protocol"
+)
+
+// Authorizer authorizes the jobs before the chain runs their stages, it is
+// put into the context of the requests by the interact plugins
+type Authorizer interface {
+	Authorize(*protocol.Job) error
+}
+
+type authorizerKey struct{}
+
+// NewAuthorizerContext puts the authorizer into ctx, the jobs are no longer
+// authorized with ctx if nil is given
+func NewAuthorizerContext(ctx context.Context, authorizer Authorizer) context.Context {
+	return context.WithValue(ctx, authorizerKey{}, authorizer)
+}
+
+// Authorize authorizes the job with the authorizer in ctx, the jobs are
+// allowed if there is no authorizer
+func Authorize(ctx context.Context, job *protocol.Job) error {
+	authorizer, _ := ctx.Value(authorizerKey{}).(Authorizer)
+	if authorizer == nil {
+		return nil
+	}
+
+	return authorizer.Authorize(job)
+}
@@ -1,5 +1,7 @@
 package plug
 
+import "github.com/bigstack-oss/plane-go/pkg/base/config"
+
 type ConfigChecker interface {
 	CheckConfig() error
 }
@@ -15,3 +17,7 @@ type SuccessCounter interface {
 type ErrorCounter interface {
 	AddError()
 }
+
+type Reloader interface {
+	Reload(config.Configer) error
+}
@@ -0,0 +1,72 @@
+package plug
+
+import (
+	"context"
+
+	"github.com/bigstack-oss/plane-go/pkg/base/log"
+	"github.com/bigstack-oss/plane-go/pkg/base/protocol"
+)
+
+var (
+	chainLoggerf = log.GetLogger("chain").Sugar()
+)
+
+// Chain runs the stages of an interface in order until one of them returns
+// false or an error, the succeeded stages are compensated in reverse order
+// when a stage fails. it is shared by the interact plugins
+type Chain struct {
+	stages []ContextStager
+}
+
+func (c *Chain) AppendStage(stage string) {
+	c.stages = append(c.stages, WithContext(Stagers[stage]))
+}
+
+// compensate undoes the succeeded stages even if ctx is done, since the
+// failure of the request may be caused by its deadline
+func compensate(ctx context.Context, job *protocol.Job, succeeded []Stager, cause error) {
+	compensated, err := Compensate(context.WithoutCancel(ctx), job, succeeded, cause)
+	if err != nil {
+		chainLoggerf.Errorf("failed to compensate stages of job(%s). error: %s", job.ID, err.Error())
+		return
+	}
+
+	if compensated > 0 {
+		chainLoggerf.Infof("compensated %d stages of job(%s) after error: %s", compensated, job.ID, cause.Error())
+	}
+}
+
+// Execute runs the stages in order with the job until ctx is done, the
+// stages after the one returning false are skipped. the job is authorized
+// by the authorizer in ctx before the stages
+func (c *Chain) Execute(ctx context.Context, job *protocol.Job) error {
+	return c.ExecuteWith(ctx, job, nil)
+}
+
+// ExecuteWith calls onStage with the job after every stage succeeded
+func (c *Chain) ExecuteWith(ctx context.Context, job *protocol.Job, onStage func(*protocol.Job)) error {
+	err := Authorize(ctx, job)
+	if err != nil {
+		return err
+	}
+
+	ctx = NewExecutionContext(ctx)
+	succeeded := []Stager{}
+	for _, stage := range c.stages {
+		isContinued, err := stage.ExecuteContext(ctx, job)
+		if err != nil {
+			compensate(ctx, job, succeeded, err)
+			return err
+		}
+
+		succeeded = append(succeeded, stage)
+		if onStage != nil {
+			onStage(job)
+		}
+		if !isContinued {
+			return nil
+		}
+	}
+
+	return nil
+}
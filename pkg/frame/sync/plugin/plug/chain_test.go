@@ -0,0 +1,40 @@
+package plug
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/bigstack-oss/plane-go/pkg/base/protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+type failingStager struct {
+	sleepingStager
+}
+
+func (s *failingStager) Execute(job *protocol.Job) (bool, error) {
+	return false, errors.New("failed")
+}
+
+func TestChain(t *testing.T) {
+	compensated := []string{}
+	Stagers["chain-compensated"] = &compensatingStager{name: "compensated", compensated: &compensated}
+	Stagers["chain-failed"] = &failingStager{}
+	defer delete(Stagers, "chain-compensated")
+	defer delete(Stagers, "chain-failed")
+
+	chain := &Chain{}
+	chain.AppendStage("chain-compensated")
+	chain.AppendStage("chain-failed")
+
+	executed := 0
+	job := &protocol.Job{}
+	err := chain.ExecuteWith(context.Background(), job, func(*protocol.Job) {
+		executed++
+	})
+	assert.NotEqual(t, nil, err, "failed to return the error of the stage")
+	assert.Equal(t, 1, executed, "failed to call back after the succeeded stages only")
+	assert.Equal(t, []string{"compensated"}, compensated, "failed to compensate the succeeded stages")
+	assert.Equal(t, StatusCompensated, job.Result.Status, "failed to put the outcome into the result")
+}
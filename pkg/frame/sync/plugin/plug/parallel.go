@@ -0,0 +1,305 @@
+package plug
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/bigstack-oss/plane-go/pkg/base/log"
+	"github.com/bigstack-oss/plane-go/pkg/base/protocol"
+	json "github.com/json-iterator/go"
+)
+
+const (
+	Parallel = "parallel"
+
+	// the job becomes the copy of the first stage succeeded
+	MergeFirst = "first"
+	// every stage has to succeed, and the results are collected into the
+	// data of the job result. the other changes of the stages to their
+	// copies are dropped
+	MergeAll = "all"
+	// the results of every stage are collected, failed or not, and the other
+	// changes are dropped as with MergeAll
+	MergeCollect = "collect"
+)
+
+var (
+	parallelLoggerf = log.GetLogger(Parallel).Sugar()
+)
+
+// parallelStager runs the stages concurrently, each of them on a copy of the
+// job, and merges the copies back into the job by the merge strategy. the
+// stages which succeed after the group was decided are compensated once they
+// finish, since their copies are never merged
+type parallelStager struct {
+	names   []string
+	stagers []ContextStager
+	merge   string
+}
+
+// ParallelResult is the result of a stage in a parallel group, the results
+// are collected into the data of the job result as a json array
+type ParallelResult struct {
+	Stage            string `json:"stage"`
+	*protocol.Result `json:"result,omitempty"`
+	Error            string `json:"error,omitempty"`
+}
+
+// compensatingParallel is a parallel group with compensators in it
+type compensatingParallel struct {
+	*parallelStager
+}
+
+type parallelDone struct {
+	index       int
+	job         *protocol.Job
+	isContinued bool
+	err         error
+}
+
+type executionKey struct{}
+
+// execution keeps the stages of every parallel group which succeeded in one
+// execution of the stages, along with the copies of the job they ran with
+type execution struct {
+	sync.Mutex
+	succeeded map[*parallelStager][]parallelDone
+}
+
+// NewExecutionContext lets the parallel groups executed with ctx record
+// which of their stages succeeded, so that only those are compensated
+func NewExecutionContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, executionKey{}, &execution{succeeded: make(map[*parallelStager][]parallelDone)})
+}
+
+// NewParallel groups the stagers named by names, so that they run
+// concurrently. the siblings are cancelled once the group is decided, by the
+// first success with MergeFirst, or by the first failure with MergeAll
+func NewParallel(names []string, stagers []Stager, merge string) (ContextStager, error) {
+	switch merge {
+	case MergeFirst, MergeAll, MergeCollect:
+	default:
+		return nil, fmt.Errorf("unsupported merge strategy(%s) of %s stages", merge, Parallel)
+	}
+
+	group := &parallelStager{names: names, merge: merge}
+	hasCompensator := false
+	for _, stager := range stagers {
+		group.stagers = append(group.stagers, WithContext(stager))
+		_, isCompensator := GetCompensator(stager)
+		hasCompensator = hasCompensator || isCompensator
+	}
+
+	if hasCompensator {
+		return &compensatingParallel{parallelStager: group}, nil
+	}
+
+	return group, nil
+}
+
+func (p *parallelStager) SetConfig(interface{}) {}
+
+func (p *parallelStager) CheckConfig() error {
+	return nil
+}
+
+func (p *parallelStager) Execute(job *protocol.Job) (bool, error) {
+	return p.ExecuteContext(context.Background(), job)
+}
+
+// isDecided tells whether the rest of the stages can be cancelled
+func (p *parallelStager) isDecided(done parallelDone) bool {
+	switch p.merge {
+	case MergeFirst:
+		return done.err == nil
+	case MergeAll:
+		return done.err != nil
+	default:
+		return false
+	}
+}
+
+func (p *parallelStager) ExecuteContext(ctx context.Context, job *protocol.Job) (bool, error) {
+	groupCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	dones := make(chan parallelDone, len(p.stagers))
+	for i, stager := range p.stagers {
+		copied := copyJob(job)
+		stagerCtx := context.WithValue(groupCtx, lateKey{}, func(late *protocol.Job) {
+			p.compensateLate(i, late)
+		})
+		go func(i int, stager ContextStager, copied *protocol.Job) {
+			isContinued, err := stager.ExecuteContext(stagerCtx, copied)
+			dones <- parallelDone{index: i, job: copied, isContinued: isContinued, err: err}
+		}(i, stager, &copied)
+	}
+
+	results := make([]parallelDone, len(p.stagers))
+	isDecided := false
+	received := 0
+	for ; received < len(p.stagers) && !isDecided; received++ {
+		done := <-dones
+		results[done.index] = done
+		isDecided = p.isDecided(done)
+	}
+	cancel()
+
+	go p.drain(dones, len(p.stagers)-received)
+
+	var isContinued bool
+	var err error
+	if isDecided {
+		isContinued, err = p.decide(job, results)
+	} else {
+		isContinued, err = p.mergeResults(job, results)
+	}
+
+	if err == nil {
+		p.record(ctx, results)
+	}
+
+	return isContinued, err
+}
+
+// record keeps the stages which succeeded for the compensation, the ones not
+// finished or failed are left out
+func (p *parallelStager) record(ctx context.Context, results []parallelDone) {
+	e, isRecorded := ctx.Value(executionKey{}).(*execution)
+	if !isRecorded {
+		return
+	}
+
+	succeeded := []parallelDone{}
+	for _, done := range results {
+		if done.job != nil && done.err == nil {
+			succeeded = append(succeeded, done)
+		}
+	}
+
+	e.Lock()
+	defer e.Unlock()
+	e.succeeded[p] = succeeded
+}
+
+// decide takes the stage which decided the group before the others finished,
+// the jobs of the others must not be read since they may still be running
+func (p *parallelStager) decide(job *protocol.Job, results []parallelDone) (bool, error) {
+	for i, done := range results {
+		if done.job == nil {
+			continue
+		}
+
+		if p.merge == MergeFirst && done.err == nil {
+			*job = *done.job
+			return done.isContinued, nil
+		}
+		if p.merge == MergeAll && done.err != nil {
+			p.compensate(results)
+			return false, fmt.Errorf("%s stage(%s) failed. error: %w", Parallel, p.names[i], done.err)
+		}
+	}
+
+	return false, nil
+}
+
+// drain waits for the stages still running after the group was decided, and
+// compensates the ones which succeeded anyway
+func (p *parallelStager) drain(dones chan parallelDone, running int) {
+	for ; running > 0; running-- {
+		done := <-dones
+		if done.err == nil {
+			p.compensateLate(done.index, done.job)
+		}
+	}
+}
+
+// compensateLate undoes the stage which succeeded after the group was
+// decided, with the copy of the job it ran with
+func (p *parallelStager) compensateLate(index int, job *protocol.Job) {
+	_, err := undo(context.Background(), job, []Stager{p.stagers[index]})
+	if err != nil {
+		parallelLoggerf.Errorf("failed to compensate %s stage(%s) succeeded after the group was decided. error: %s", Parallel, p.names[index], err.Error())
+	}
+}
+
+// compensate undoes the stages of a failed group which succeeded before the
+// group was decided, each of them with its own copy of the job
+func (p *parallelStager) compensate(results []parallelDone) {
+	for i := len(results) - 1; i >= 0; i-- {
+		if results[i].job == nil || results[i].err != nil {
+			continue
+		}
+
+		_, _ = undo(context.Background(), results[i].job, []Stager{p.stagers[i]})
+	}
+}
+
+func (p *parallelStager) mergeResults(job *protocol.Job, results []parallelDone) (bool, error) {
+	isContinued := true
+	errs := []error{}
+	collected := []ParallelResult{}
+	for i, done := range results {
+		result := ParallelResult{Stage: p.names[i], Result: done.job.Result}
+		if done.err != nil {
+			result.Error = done.err.Error()
+			errs = append(errs, fmt.Errorf("%s stage(%s) failed. error: %w", Parallel, p.names[i], done.err))
+		} else {
+			isContinued = isContinued && done.isContinued
+		}
+
+		collected = append(collected, result)
+	}
+
+	if len(errs) == len(results) {
+		return false, errors.Join(errs...)
+	}
+
+	if p.merge == MergeAll && len(errs) > 0 {
+		p.compensate(results)
+		return false, errors.Join(errs...)
+	}
+
+	if p.merge == MergeFirst {
+		return p.decide(job, results)
+	}
+
+	data, err := json.Marshal(collected)
+	if err != nil {
+		return false, err
+	}
+
+	job.Result = &protocol.Result{Data: data}
+	return isContinued, nil
+}
+
+// Compensate undoes the stages of the group which succeeded, each of them
+// with its own copy of the job. every stage which is a compensator is undone
+// with the job if the group was not executed with an execution context
+func (p *compensatingParallel) Compensate(ctx context.Context, job *protocol.Job) error {
+	e, isRecorded := ctx.Value(executionKey{}).(*execution)
+	if !isRecorded {
+		stagers := make([]Stager, len(p.stagers))
+		for i, stager := range p.stagers {
+			stagers[i] = stager
+		}
+
+		_, err := undo(ctx, job, stagers)
+		return err
+	}
+
+	e.Lock()
+	succeeded := e.succeeded[p.parallelStager]
+	delete(e.succeeded, p.parallelStager)
+	e.Unlock()
+
+	errs := []error{}
+	for i := len(succeeded) - 1; i >= 0; i-- {
+		_, err := undo(ctx, succeeded[i].job, []Stager{p.stagers[succeeded[i].index]})
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,176 @@
+package plug
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bigstack-oss/plane-go/pkg/base/protocol"
+	json "github.com/json-iterator/go"
+	"github.com/stretchr/testify/assert"
+)
+
+type backendStager struct {
+	sleepingStager
+	status string
+	err    error
+}
+
+func (b *backendStager) ExecuteContext(ctx context.Context, job *protocol.Job) (bool, error) {
+	select {
+	case <-ctx.Done():
+		return false, ctx.Err()
+	case <-time.After(b.sleep):
+	}
+
+	job.Result = &protocol.Result{Status: b.status}
+	return true, b.err
+}
+
+func newBackends(backends ...*backendStager) ([]string, []Stager) {
+	names, stagers := []string{}, []Stager{}
+	for _, backend := range backends {
+		names = append(names, backend.status)
+		stagers = append(stagers, backend)
+	}
+
+	return names, stagers
+}
+
+func TestParallelFirst(t *testing.T) {
+	names, stagers := newBackends(
+		&backendStager{sleepingStager: sleepingStager{sleep: time.Second}, status: "slow"},
+		&backendStager{status: "fast"},
+	)
+	group, err := NewParallel(names, stagers, MergeFirst)
+	assert.Equal(t, nil, err, "failed to create the parallel group")
+
+	job := &protocol.Job{}
+	startedAt := time.Now()
+	_, err = group.ExecuteContext(context.Background(), job)
+	assert.Equal(t, nil, err, "failed to take the first success")
+	assert.Equal(t, "fast", job.Result.Status, "failed to merge the job of the first success")
+	assert.Less(t, time.Since(startedAt), time.Second, "failed to cancel the slow stage")
+}
+
+func TestParallelAll(t *testing.T) {
+	names, stagers := newBackends(
+		&backendStager{sleepingStager: sleepingStager{sleep: time.Second}, status: "slow"},
+		&backendStager{status: "failed", err: errors.New("unavailable")},
+	)
+	group, _ := NewParallel(names, stagers, MergeAll)
+
+	startedAt := time.Now()
+	_, err := group.ExecuteContext(context.Background(), &protocol.Job{})
+	assert.NotEqual(t, nil, err, "failed to fail the group once a stage failed")
+	assert.Less(t, time.Since(startedAt), time.Second, "failed to cancel the siblings of the failed stage")
+}
+
+func TestParallelCollect(t *testing.T) {
+	names, stagers := newBackends(
+		&backendStager{status: "a"},
+		&backendStager{status: "b", err: errors.New("unavailable")},
+	)
+	group, _ := NewParallel(names, stagers, MergeCollect)
+
+	job := &protocol.Job{}
+	_, err := group.ExecuteContext(context.Background(), job)
+	assert.Equal(t, nil, err, "failed to collect the results")
+
+	collected := []ParallelResult{}
+	assert.Equal(t, nil, json.Unmarshal(job.Result.Data, &collected), "failed to collect the results as json")
+	assert.Equal(t, 2, len(collected), "failed to collect the result of every stage")
+	assert.Equal(t, "a", collected[0].Status, "failed to keep the order of the stages")
+	assert.Equal(t, "unavailable", collected[1].Error, "failed to collect the error of the failed stage")
+
+	_, err = NewParallel(names, stagers, "unknown")
+	assert.NotEqual(t, nil, err, "failed to reject the unsupported merge strategy")
+}
+
+func TestParallelAllLastFailure(t *testing.T) {
+	names, stagers := newBackends(
+		&backendStager{status: "fast"},
+		&backendStager{sleepingStager: sleepingStager{sleep: 20 * time.Millisecond}, status: "failed", err: errors.New("unavailable")},
+	)
+	group, _ := NewParallel(names, stagers, MergeAll)
+
+	isContinued, err := group.ExecuteContext(context.Background(), &protocol.Job{})
+	assert.NotEqual(t, nil, err, "failed to fail the group when the last stage failed")
+	assert.Equal(t, false, isContinued, "failed to stop the stages after the failed group")
+}
+
+type compensatingBackend struct {
+	backendStager
+	compensated *[]string
+}
+
+func (b *compensatingBackend) Compensate(ctx context.Context, job *protocol.Job) error {
+	*b.compensated = append(*b.compensated, job.Result.Status)
+	return nil
+}
+
+func TestParallelCompensate(t *testing.T) {
+	compensated := []string{}
+	group, _ := NewParallel([]string{"a", "b"}, []Stager{
+		&compensatingBackend{backendStager: backendStager{status: "a"}, compensated: &compensated},
+		&compensatingBackend{backendStager: backendStager{status: "b", err: errors.New("unavailable")}, compensated: &compensated},
+	}, MergeCollect)
+
+	ctx := NewExecutionContext(context.Background())
+	job := &protocol.Job{}
+	_, err := group.ExecuteContext(ctx, job)
+	assert.Equal(t, nil, err, "failed to collect the results")
+
+	compensator, isCompensator := GetCompensator(group)
+	assert.Equal(t, true, isCompensator, "failed to compensate the group with compensators")
+	assert.Equal(t, nil, compensator.Compensate(ctx, job), "failed to compensate the group")
+	assert.Equal(t, []string{"a"}, compensated, "failed to compensate only the succeeded stages with their copies")
+}
+
+// lateStager succeeds after its sleep and tells when it is compensated
+type lateStager struct {
+	sleepingStager
+	name        string
+	compensated chan string
+}
+
+func (s *lateStager) Compensate(ctx context.Context, job *protocol.Job) error {
+	s.compensated <- s.name + "/" + job.Result.Status
+	return nil
+}
+
+// stubbornStager ignores the context and succeeds after its sleep
+type stubbornStager struct {
+	lateStager
+}
+
+func (s *stubbornStager) ExecuteContext(ctx context.Context, job *protocol.Job) (bool, error) {
+	return s.Execute(job)
+}
+
+func TestParallelFirstCompensateLate(t *testing.T) {
+	compensated := make(chan string, 2)
+	group, _ := NewParallel([]string{"fast", "stubborn", "context-less"}, []Stager{
+		&backendStager{status: "fast"},
+		&stubbornStager{lateStager{sleepingStager: sleepingStager{sleep: 50 * time.Millisecond}, name: "stubborn", compensated: compensated}},
+		&lateStager{sleepingStager: sleepingStager{sleep: 50 * time.Millisecond}, name: "context-less", compensated: compensated},
+	}, MergeFirst)
+
+	job := &protocol.Job{}
+	_, err := group.ExecuteContext(context.Background(), job)
+	assert.Equal(t, nil, err, "failed to take the first success")
+	assert.Equal(t, "fast", job.Result.Status, "failed to merge the job of the first success")
+
+	late := []string{}
+	for len(late) < 2 {
+		select {
+		case name := <-compensated:
+			late = append(late, name)
+		case <-time.After(time.Second):
+			t.Fatalf("failed to compensate the stages succeeded after the group was decided, compensated: %v", late)
+		}
+	}
+
+	assert.ElementsMatch(t, []string{"stubborn/slept", "context-less/slept"}, late, "failed to compensate the late stages with their copies")
+}
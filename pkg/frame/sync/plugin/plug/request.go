@@ -0,0 +1,22 @@
+package plug
+
+import (
+	"context"
+)
+
+type requestKey struct{}
+
+// NewRequestContext puts the request decoded from the body into ctx, so that
+// the context-aware stages read it alongside the job
+func NewRequestContext(ctx context.Context, request interface{}) context.Context {
+	return context.WithValue(ctx, requestKey{}, request)
+}
+
+// RequestFromContext returns the request decoded from the body, it is the
+// pointer to the struct declared by the interface, or the json value
+// validated by the schema of the interface. the context-less stages read
+// the same request from job.GetRequest
+func RequestFromContext(ctx context.Context) (interface{}, bool) {
+	request := ctx.Value(requestKey{})
+	return request, request != nil
+}
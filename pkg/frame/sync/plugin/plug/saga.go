@@ -0,0 +1,58 @@
+package plug
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/bigstack-oss/plane-go/pkg/base/protocol"
+)
+
+const (
+	StatusCompensated        = "compensated"
+	StatusCompensationFailed = "compensationFailed"
+)
+
+// undo compensates the stages in reverse order, the stages which are not
+// compensators are skipped. it keeps going when a compensation fails
+func undo(ctx context.Context, job *protocol.Job, stagers []Stager) (int, error) {
+	compensated := 0
+	errs := []error{}
+	for i := len(stagers) - 1; i >= 0; i-- {
+		compensator, isCompensator := GetCompensator(stagers[i])
+		if !isCompensator {
+			continue
+		}
+
+		compensated++
+		err := compensator.Compensate(ctx, job)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return compensated, errors.Join(errs...)
+}
+
+// Compensate undoes the succeeded stages in reverse order after a later stage
+// failed with cause, and the outcome is put into the result of the job if
+// any stage was compensated
+func Compensate(ctx context.Context, job *protocol.Job, succeeded []Stager, cause error) (int, error) {
+	compensated, err := undo(ctx, job, succeeded)
+	if compensated == 0 {
+		return 0, nil
+	}
+
+	if job.Result == nil {
+		job.Result = &protocol.Result{}
+	}
+
+	job.Result.Status = StatusCompensated
+	job.Result.Desc = cause.Error()
+	if err != nil {
+		job.Result.Status = StatusCompensationFailed
+		job.Result.Desc = fmt.Sprintf("%s. compensation error: %s", cause.Error(), err.Error())
+	}
+
+	return compensated, err
+}
@@ -0,0 +1,57 @@
+package plug
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bigstack-oss/plane-go/pkg/base/protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+type compensatingStager struct {
+	sleepingStager
+	name        string
+	compensated *[]string
+	err         error
+}
+
+func (s *compensatingStager) Compensate(ctx context.Context, job *protocol.Job) error {
+	*s.compensated = append(*s.compensated, s.name)
+	return s.err
+}
+
+func TestCompensate(t *testing.T) {
+	compensated := []string{}
+	succeeded := []Stager{
+		WithTimeout(&compensatingStager{name: "transit", compensated: &compensated}, 0),
+		&sleepingStager{},
+		WithTimeout(&compensatingStager{name: "process", compensated: &compensated}, time.Second),
+	}
+
+	job := &protocol.Job{}
+	number, err := Compensate(context.Background(), job, succeeded, errors.New("request failed"))
+	assert.Equal(t, nil, err, "failed to compensate stages")
+	assert.Equal(t, 2, number, "failed to count the compensated stages")
+	assert.Equal(t, []string{"process", "transit"}, compensated, "failed to compensate the wrapped stages in reverse order")
+	assert.Equal(t, StatusCompensated, job.Result.Status, "failed to put the outcome into the result")
+}
+
+func TestCompensateWithError(t *testing.T) {
+	compensated := []string{}
+	succeeded := []Stager{
+		&compensatingStager{name: "transit", compensated: &compensated},
+		&compensatingStager{name: "process", compensated: &compensated, err: errors.New("failed")},
+	}
+
+	job := &protocol.Job{}
+	_, err := Compensate(context.Background(), job, succeeded, errors.New("request failed"))
+	assert.NotEqual(t, nil, err, "failed to return the compensation error")
+	assert.Equal(t, []string{"process", "transit"}, compensated, "failed to keep compensating after an error")
+	assert.Equal(t, StatusCompensationFailed, job.Result.Status, "failed to put the failure into the result")
+
+	number, err := Compensate(context.Background(), &protocol.Job{}, []Stager{&sleepingStager{}}, errors.New("request failed"))
+	assert.Equal(t, 0, number, "failed to skip the stages which are not compensators")
+	assert.Equal(t, nil, err, "failed to skip the stages which are not compensators")
+}
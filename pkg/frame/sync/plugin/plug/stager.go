@@ -1,6 +1,12 @@
 package plug
 
-import "github.com/bigstack-oss/plane-go/pkg/base/protocol"
+import (
+	"context"
+	"time"
+
+	"github.com/bigstack-oss/plane-go/pkg/base/protocol"
+	"github.com/mohae/deepcopy"
+)
 
 var Stagers = make(map[string]Stager)
 
@@ -13,3 +19,143 @@ type Stager interface {
 	ConfigChecker
 	Execute(*protocol.Job) (bool, error)
 }
+
+// ContextStager is a stager which gives up once the context is done. its
+// Execute is only called by the interfaces which are not aware of context
+type ContextStager interface {
+	Stager
+	ExecuteContext(context.Context, *protocol.Job) (bool, error)
+}
+
+// Compensator can be implemented by the stagers with side effects, so that
+// the effects are undone when a later stage of the interface fails
+type Compensator interface {
+	Compensate(context.Context, *protocol.Job) error
+}
+
+// Unwrapper is implemented by the stagers wrapping another stager
+type Unwrapper interface {
+	Unwrap() Stager
+}
+
+// contextAdapter runs a context-less stager in a goroutine, so that the
+// caller is released once the context is done. the stager runs on a copy of
+// the job, which is copied back only if the stager returns in time, so that
+// the stager given up never touches the job of the caller
+type contextAdapter struct {
+	Stager
+}
+
+// lateKey is the key of the callback which takes the copy of the job once a
+// stage given up by the adapter succeeds after all
+type lateKey struct{}
+
+type stageResult struct {
+	isContinued bool
+	err         error
+}
+
+// timedStager executes the stager with a deadline of its own
+type timedStager struct {
+	ContextStager
+	timeout time.Duration
+}
+
+// WithContext returns the stager as a ContextStager, the context-less
+// stagers are wrapped by an adapter
+func WithContext(stager Stager) ContextStager {
+	contextStager, isContextAware := stager.(ContextStager)
+	if isContextAware {
+		return contextStager
+	}
+
+	return &contextAdapter{Stager: stager}
+}
+
+// WithTimeout limits every execution of the stager to timeout, the stager
+// is returned as it is if timeout is not positive
+func WithTimeout(stager Stager, timeout time.Duration) ContextStager {
+	if timeout <= 0 {
+		return WithContext(stager)
+	}
+
+	return &timedStager{ContextStager: WithContext(stager), timeout: timeout}
+}
+
+// GetCompensator returns the compensator of the stager, the wrapped stagers
+// are looked into until one of them is a compensator
+func GetCompensator(stager Stager) (Compensator, bool) {
+	for stager != nil {
+		compensator, isCompensator := stager.(Compensator)
+		if isCompensator {
+			return compensator, true
+		}
+
+		unwrapper, isWrapped := stager.(Unwrapper)
+		if !isWrapped {
+			return nil, false
+		}
+
+		stager = unwrapper.Unwrap()
+	}
+
+	return nil, false
+}
+
+// copyJob copies the job along with the fields which are not serialized
+func copyJob(job *protocol.Job) protocol.Job {
+	copied := deepcopy.Copy(*job).(protocol.Job)
+	copied.SetEnqueuedAt(job.GetEnqueuedAt())
+	copied.SetRequest(job.GetRequest())
+	return copied
+}
+
+func (a *contextAdapter) Unwrap() Stager {
+	return a.Stager
+}
+
+func (a *contextAdapter) ExecuteContext(ctx context.Context, job *protocol.Job) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	copied := copyJob(job)
+
+	done := make(chan stageResult, 1)
+	go func() {
+		isContinued, err := a.Stager.Execute(&copied)
+		done <- stageResult{isContinued: isContinued, err: err}
+	}()
+
+	select {
+	case <-ctx.Done():
+		onLate, isWatched := ctx.Value(lateKey{}).(func(*protocol.Job))
+		if isWatched {
+			go func() {
+				if result := <-done; result.err == nil {
+					onLate(&copied)
+				}
+			}()
+		}
+
+		return false, ctx.Err()
+	case result := <-done:
+		*job = copied
+		return result.isContinued, result.err
+	}
+}
+
+func (t *timedStager) Unwrap() Stager {
+	return t.ContextStager
+}
+
+func (t *timedStager) Execute(job *protocol.Job) (bool, error) {
+	return t.ExecuteContext(context.Background(), job)
+}
+
+func (t *timedStager) ExecuteContext(ctx context.Context, job *protocol.Job) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+
+	return t.ContextStager.ExecuteContext(ctx, job)
+}
@@ -0,0 +1,62 @@
+package plug
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bigstack-oss/plane-go/pkg/base/protocol"
+	"github.com/stretchr/testify/assert"
+)
+
+type sleepingStager struct {
+	sleep time.Duration
+}
+
+func (s *sleepingStager) SetConfig(interface{}) {}
+
+func (s *sleepingStager) CheckConfig() error {
+	return nil
+}
+
+func (s *sleepingStager) Execute(job *protocol.Job) (bool, error) {
+	time.Sleep(s.sleep)
+	job.Result = &protocol.Result{Status: "slept"}
+	return true, nil
+}
+
+func TestWithTimeout(t *testing.T) {
+	stager := WithTimeout(&sleepingStager{sleep: time.Second}, 10*time.Millisecond)
+	_, err := stager.ExecuteContext(context.Background(), &protocol.Job{})
+	assert.Equal(t, true, errors.Is(err, context.DeadlineExceeded), "failed to give up the stage after its timeout")
+
+	_, err = stager.Execute(&protocol.Job{})
+	assert.Equal(t, true, errors.Is(err, context.DeadlineExceeded), "failed to limit the context-less execution")
+
+	isContinued, err := WithTimeout(&sleepingStager{}, time.Second).ExecuteContext(context.Background(), &protocol.Job{})
+	assert.Equal(t, true, isContinued, "failed to pass the result of the stage through")
+	assert.Equal(t, nil, err, "failed to execute the stage within its timeout")
+}
+
+func TestWithTimeoutCopy(t *testing.T) {
+	job := &protocol.Job{ID: "job-1"}
+	_, err := WithTimeout(&sleepingStager{sleep: 50 * time.Millisecond}, 10*time.Millisecond).ExecuteContext(context.Background(), job)
+	assert.Equal(t, true, errors.Is(err, context.DeadlineExceeded), "failed to give up the stage after its timeout")
+
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, (*protocol.Result)(nil), job.Result, "failed to keep the stage given up away from the job")
+
+	_, err = WithTimeout(&sleepingStager{}, time.Second).ExecuteContext(context.Background(), job)
+	assert.Equal(t, nil, err, "failed to execute the stage within its timeout")
+	assert.Equal(t, "job-1", job.ID, "failed to keep the job")
+	assert.Equal(t, "slept", job.Result.Status, "failed to copy the result of the stage back")
+}
+
+func TestWithContextRequest(t *testing.T) {
+	job := &protocol.Job{}
+	job.SetRequest("request")
+	_, err := WithContext(&sleepingStager{}).ExecuteContext(context.Background(), job)
+	assert.Equal(t, nil, err, "failed to execute the stage")
+	assert.Equal(t, "request", job.GetRequest(), "failed to keep the request for the context-less stage")
+}
@@ -0,0 +1,243 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+
+	"github.com/bigstack-oss/plane-go/pkg/base/log"
+	"github.com/bigstack-oss/plane-go/pkg/base/protocol"
+	"github.com/bigstack-oss/plane-go/pkg/frame/sync/metric"
+	"github.com/bigstack-oss/plane-go/pkg/frame/sync/plugin/plug"
+	"github.com/goinggo/mapstructure"
+	"go.uber.org/zap"
+	"gopkg.in/go-playground/validator.v9"
+)
+
+const (
+	module = "policy"
+
+	Allow = "allow"
+	Deny  = "deny"
+
+	// the rule label of the requests decided by the default effect
+	defaultRule = "default"
+
+	// the rule label of the requests denied for having no applicant
+	unauthenticatedRule = "unauthenticated"
+)
+
+var (
+	// ErrDenied is wrapped by the errors of the denied requests
+	ErrDenied = errors.New("request was denied")
+)
+
+// Engine decides whether a request is allowed by the first rule matching
+// it, or by the default effect if no rule matches. the decisions are only
+// logged in dry run, and every request is allowed
+type Engine struct {
+	config
+
+	log *zap.Logger
+}
+
+type config struct {
+	DryRun  bool
+	Default string `validate:"oneof=allow deny"`
+	Rules   []Rule `validate:"dive"`
+}
+
+// Rule matches the requests by each of its non-empty fields, the patterns
+// are in the syntax of path.Match, so "*" matches anything
+type Rule struct {
+	Name          string
+	Effect        string `validate:"oneof=allow deny"`
+	Projects      []string
+	Roles         []string
+	Operations    []string
+	ResourceTypes []string
+	Methods       []string
+	Paths         []string
+}
+
+// Request is what the rules are evaluated against
+type Request struct {
+	Interface string
+	Method    string
+	Path      string
+	*protocol.Job
+}
+
+// Decision tells the effect of a request and the rule deciding it
+type Decision struct {
+	Effect string
+	Rule   string
+}
+
+// scope authorizes the jobs of a request to the interface
+type scope struct {
+	engine        *Engine
+	interfaceName string
+	method        string
+	path          string
+}
+
+func (s scope) Authorize(job *protocol.Job) error {
+	return s.engine.Authorize(Request{Interface: s.interfaceName, Method: s.method, Path: s.path, Job: job})
+}
+
+// New creates the engine from the conf, the default effect is allow
+func New(conf interface{}) (*Engine, error) {
+	e := &Engine{log: log.GetLogger(module)}
+	_ = mapstructure.Decode(conf, &e.config)
+	if e.Default == "" {
+		e.Default = Allow
+	}
+
+	for i := range e.Rules {
+		if e.Rules[i].Name == "" {
+			e.Rules[i].Name = fmt.Sprintf("rule-%d", i)
+		}
+	}
+
+	err := validator.New().Struct(e.config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set %s. error: %s", module, err.Error())
+	}
+
+	return e, nil
+}
+
+func matchAny(patterns []string, value string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+
+	for _, pattern := range patterns {
+		isMatched, _ := path.Match(pattern, value)
+		if isMatched {
+			return true
+		}
+	}
+
+	return false
+}
+
+func matchAnyOf(patterns []string, values []string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+
+	for _, value := range values {
+		if matchAny(patterns, value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func getSubject(job *protocol.Job) (string, []string) {
+	if job == nil || job.Applicant == nil {
+		return "", nil
+	}
+
+	return job.Applicant.Project, job.Applicant.Roles
+}
+
+func getDesired(job *protocol.Job) (string, string) {
+	if job == nil || job.Desired == nil {
+		return "", ""
+	}
+
+	if job.Desired.Resource == nil {
+		return job.Desired.Operation, ""
+	}
+
+	return job.Desired.Operation, job.Desired.Resource.Type
+}
+
+func (r Rule) match(request Request) bool {
+	project, roles := getSubject(request.Job)
+	operation, resourceType := getDesired(request.Job)
+	return matchAny(r.Projects, project) &&
+		matchAnyOf(r.Roles, roles) &&
+		matchAny(r.Operations, operation) &&
+		matchAny(r.ResourceTypes, resourceType) &&
+		matchAny(r.Methods, request.Method) &&
+		matchAny(r.Paths, request.Path)
+}
+
+// Evaluate returns the decision of the request, dry run is not considered
+func (e *Engine) Evaluate(request Request) Decision {
+	for _, rule := range e.Rules {
+		if rule.match(request) {
+			return Decision{Effect: rule.Effect, Rule: rule.Name}
+		}
+	}
+
+	return Decision{Effect: e.Default, Rule: defaultRule}
+}
+
+func (e *Engine) audit(request Request, decision Decision) {
+	project, roles := getSubject(request.Job)
+	operation, resourceType := getDesired(request.Job)
+	applicantID := ""
+	if request.Job != nil && request.Job.Applicant != nil {
+		applicantID = request.Job.Applicant.ID
+	}
+
+	e.log.Warn("denied",
+		zap.String("interface", request.Interface),
+		zap.String("rule", decision.Rule),
+		zap.Bool("dryRun", e.DryRun),
+		zap.String("applicant", applicantID),
+		zap.String("project", project),
+		zap.Strings("roles", roles),
+		zap.String("operation", operation),
+		zap.String("resourceType", resourceType),
+		zap.String("method", request.Method),
+		zap.String("path", request.Path),
+	)
+}
+
+// Authorize returns an error wrapping ErrDenied if the request is denied,
+// the denials are audited and counted even in dry run. the requests without
+// an authenticated applicant are denied before the rules
+func (e *Engine) Authorize(request Request) error {
+	decision := Decision{Effect: Deny, Rule: unauthenticatedRule}
+	if request.Job != nil && request.Job.Applicant != nil {
+		decision = e.Evaluate(request)
+	}
+	if decision.Effect == Allow {
+		return nil
+	}
+
+	e.audit(request, decision)
+	metric.CountDenial(request.Interface, decision.Rule, e.DryRun)
+	if e.DryRun {
+		return nil
+	}
+
+	return fmt.Errorf("%w by policy rule(%s)", ErrDenied, decision.Rule)
+}
+
+// NewContext puts the engine into ctx for the request of the interface, so
+// that the jobs run by the chain with ctx are authorized before the stages
+func NewContext(ctx context.Context, engine *Engine, interfaceName string, method string, path string) context.Context {
+	return plug.NewAuthorizerContext(ctx, scope{engine: engine, interfaceName: interfaceName, method: method, path: path})
+}
+
+// Authorize authorizes the job with the engine in ctx, the jobs are allowed
+// if there is no engine
+func Authorize(ctx context.Context, job *protocol.Job) error {
+	return plug.Authorize(ctx, job)
+}
+
+// WithAuthorized returns ctx in which the job authorized already is not
+// authorized again by the chain, so that the denials in dry run are counted
+// once
+func WithAuthorized(ctx context.Context) context.Context {
+	return plug.NewAuthorizerContext(ctx, nil)
+}
@@ -0,0 +1,83 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/bigstack-oss/plane-go/pkg/base/protocol"
+	"github.com/bigstack-oss/plane-go/pkg/frame/sync/plugin/plug"
+	"github.com/stretchr/testify/assert"
+)
+
+func newJob(project string, roles []string, operation string, resourceType string) *protocol.Job {
+	return &protocol.Job{
+		Applicant: &protocol.Applicant{ID: "user-1", Project: project, Roles: roles},
+		Desired:   &protocol.Desired{Operation: operation, Resource: &protocol.Resource{Type: resourceType}},
+	}
+}
+
+func newTestEngine(t *testing.T, isDryRun bool) *Engine {
+	engine, err := New(map[string]interface{}{
+		"dryRun":  isDryRun,
+		"default": Deny,
+		"rules": []interface{}{
+			map[string]interface{}{"name": "no-delete", "effect": Deny, "operations": []interface{}{"delete"}, "roles": []interface{}{"viewer"}},
+			map[string]interface{}{"effect": Allow, "projects": []interface{}{"demo"}, "resourceTypes": []interface{}{"vm*"}},
+			map[string]interface{}{"effect": Allow, "methods": []interface{}{"GET"}, "paths": []interface{}{"/apis/v1/*"}},
+		},
+	})
+	assert.Equal(t, nil, err, "failed to create the engine")
+
+	return engine
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(map[string]interface{}{"rules": []interface{}{map[string]interface{}{"effect": "maybe"}}})
+	assert.NotEqual(t, nil, err, "failed to reject the unknown effect")
+
+	engine, err := New(map[string]interface{}{})
+	assert.Equal(t, nil, err, "failed to create the engine without rules")
+	assert.Equal(t, Allow, engine.Evaluate(Request{}).Effect, "failed to allow by default")
+}
+
+func TestEvaluate(t *testing.T) {
+	engine := newTestEngine(t, false)
+
+	decision := engine.Evaluate(Request{Method: "POST", Job: newJob("demo", []string{"viewer"}, "delete", "vm")})
+	assert.Equal(t, Decision{Effect: Deny, Rule: "no-delete"}, decision, "failed to take the first rule matched")
+
+	decision = engine.Evaluate(Request{Method: "POST", Job: newJob("demo", []string{"admin"}, "delete", "vmGroup")})
+	assert.Equal(t, Decision{Effect: Allow, Rule: "rule-1"}, decision, "failed to match the patterns")
+
+	decision = engine.Evaluate(Request{Method: "GET", Path: "/apis/v1/dummy"})
+	assert.Equal(t, Decision{Effect: Allow, Rule: "rule-2"}, decision, "failed to match the method and the path")
+
+	decision = engine.Evaluate(Request{Method: "POST", Path: "/apis/v1/dummy", Job: newJob("other", nil, "create", "vm")})
+	assert.Equal(t, Decision{Effect: Deny, Rule: defaultRule}, decision, "failed to take the default effect")
+}
+
+func TestAuthorize(t *testing.T) {
+	job := newJob("other", nil, "create", "vm")
+	assert.Equal(t, nil, Authorize(context.Background(), job), "failed to allow the request without engine")
+
+	ctx := NewContext(context.Background(), newTestEngine(t, false), "post", "POST", "/jobs")
+	err := Authorize(ctx, job)
+	assert.Equal(t, true, errors.Is(err, ErrDenied), "failed to deny the request")
+	assert.Equal(t, nil, Authorize(WithAuthorized(ctx), job), "failed to skip the job authorized already")
+
+	chain := &plug.Chain{}
+	err = chain.Execute(ctx, job)
+	assert.Equal(t, true, errors.Is(err, ErrDenied), "failed to deny the request executed by the chain")
+
+	ctx = NewContext(context.Background(), newTestEngine(t, true), "post", "POST", "/jobs")
+	assert.Equal(t, nil, Authorize(ctx, job), "failed to allow the denied request in dry run")
+
+	engine, err := New(map[string]interface{}{})
+	assert.Equal(t, nil, err, "failed to create the engine")
+	ctx = NewContext(context.Background(), engine, "post", "POST", "/jobs")
+	assert.Equal(t, nil, Authorize(ctx, job), "failed to allow the authenticated request by default")
+
+	err = Authorize(ctx, &protocol.Job{Desired: job.Desired})
+	assert.Equal(t, true, errors.Is(err, ErrDenied), "failed to deny the request without applicant")
+}
@@ -0,0 +1,161 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync/atomic"
+	"time"
+
+	"github.com/bigstack-oss/plane-go/pkg/base/protocol"
+	"github.com/bigstack-oss/plane-go/pkg/frame/sync/plugin"
+	"github.com/bigstack-oss/plane-go/pkg/frame/sync/plugin/plug"
+)
+
+type attemptKey struct{}
+
+// Policy is decoded from the `retry` block of a stage config, a number is
+// taken as Max. backoffs are in milliseconds and jitter is a ratio between
+// 0 and 1. the stage is retried when it fails with a retryable error, or its
+// job ends up with one of Statuses as the result
+type Policy struct {
+	Max            int     `validate:"min=0"`
+	InitialBackoff int     `validate:"min=0"`
+	MaxBackoff     int     `validate:"gtefield=InitialBackoff"`
+	Jitter         float64 `validate:"min=0,max=1"`
+	Statuses       []string
+}
+
+// Retryabler can be implemented by the errors returned from the stages to
+// decide whether the failed job deserves another attempt
+type Retryabler interface {
+	Retryable() bool
+}
+
+type permanentError struct {
+	err error
+}
+
+// retryStager executes the stager again under the policy
+type retryStager struct {
+	plug.ContextStager
+	policy  Policy
+	onRetry func()
+}
+
+func (p *permanentError) Error() string {
+	return p.err.Error()
+}
+
+func (p *permanentError) Unwrap() error {
+	return p.err
+}
+
+func (p *permanentError) Retryable() bool {
+	return false
+}
+
+// Permanent marks the error as not retryable, so the stage fails right away
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &permanentError{err: err}
+}
+
+func IsRetryable(err error) bool {
+	var retryabler Retryabler
+	if errors.As(err, &retryabler) {
+		return retryabler.Retryable()
+	}
+
+	return true
+}
+
+// GetAttempt returns how many times the stage was retried, it is 0 for the
+// first execution
+func GetAttempt(ctx context.Context) int {
+	attempt, _ := ctx.Value(attemptKey{}).(int)
+	return attempt
+}
+
+func (p Policy) backoff(attempt int) time.Duration {
+	backoff := float64(p.InitialBackoff)
+	for i := 1; i < attempt && backoff < float64(p.MaxBackoff); i++ {
+		backoff *= 2
+	}
+
+	if backoff > float64(p.MaxBackoff) {
+		backoff = float64(p.MaxBackoff)
+	}
+
+	backoff += backoff * p.Jitter * (2*rand.Float64() - 1)
+	return time.Duration(backoff) * time.Millisecond
+}
+
+func (p Policy) shouldRetry(job *protocol.Job, err error) bool {
+	if err != nil {
+		return IsRetryable(err)
+	}
+
+	if job.Result == nil {
+		return false
+	}
+
+	for _, status := range p.Statuses {
+		if job.Result.Status == status {
+			return true
+		}
+	}
+
+	return false
+}
+
+// WithPolicy retries the stager under the policy, onRetry is called before
+// every retry. the stager is returned as it is if the policy never retries
+func WithPolicy(stager plug.Stager, policy Policy, onRetry func()) plug.ContextStager {
+	if policy.Max == 0 {
+		return plug.WithContext(stager)
+	}
+
+	return &retryStager{
+		ContextStager: plug.WithContext(stager),
+		policy:        policy,
+		onRetry:       onRetry,
+	}
+}
+
+func (r *retryStager) Unwrap() plug.Stager {
+	return r.ContextStager
+}
+
+func (r *retryStager) Execute(job *protocol.Job) (bool, error) {
+	return r.ExecuteContext(context.Background(), job)
+}
+
+// ExecuteContext stops retrying when the error is permanent, the attempts
+// are used up or the context is done, and returns the last result. an
+// attempt of a context-less stager given up by its timeout runs on its own
+// copy of the job, so it never races with the retries
+func (r *retryStager) ExecuteContext(ctx context.Context, job *protocol.Job) (bool, error) {
+	isContinued, err := r.ContextStager.ExecuteContext(ctx, job)
+	for attempt := 1; attempt <= r.policy.Max && r.policy.shouldRetry(job, err); attempt++ {
+		timer := time.NewTimer(r.policy.backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return isContinued, err
+		case <-timer.C:
+		}
+
+		atomic.AddUint64(&plugin.Metrics.StageRetry, 1)
+		if r.onRetry != nil {
+			r.onRetry()
+		}
+
+		isContinued, err = r.ContextStager.ExecuteContext(context.WithValue(ctx, attemptKey{}, attempt), job)
+	}
+
+	return isContinued, err
+}
@@ -0,0 +1,126 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bigstack-oss/plane-go/pkg/base/protocol"
+	"github.com/bigstack-oss/plane-go/pkg/frame/sync/plugin"
+	"github.com/bigstack-oss/plane-go/pkg/frame/sync/plugin/plug"
+	"github.com/stretchr/testify/assert"
+)
+
+type testStager struct {
+	attempts []int
+	execute  func(attempt int, job *protocol.Job) error
+}
+
+func (s *testStager) SetConfig(interface{}) {}
+
+func (s *testStager) CheckConfig() error {
+	return nil
+}
+
+func (s *testStager) Execute(job *protocol.Job) (bool, error) {
+	return s.ExecuteContext(context.Background(), job)
+}
+
+func (s *testStager) ExecuteContext(ctx context.Context, job *protocol.Job) (bool, error) {
+	attempt := GetAttempt(ctx)
+	s.attempts = append(s.attempts, attempt)
+	return true, s.execute(attempt, job)
+}
+
+// slowStager is context-less, it writes the job late in its first execution
+type slowStager struct {
+	executions int32
+}
+
+func (s *slowStager) SetConfig(interface{}) {}
+
+func (s *slowStager) CheckConfig() error {
+	return nil
+}
+
+func (s *slowStager) Execute(job *protocol.Job) (bool, error) {
+	execution := atomic.AddInt32(&s.executions, 1)
+	if execution == 1 {
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	job.Result = &protocol.Result{Status: fmt.Sprintf("execution-%d", execution)}
+	return true, nil
+}
+
+func TestRetryUntilSuccess(t *testing.T) {
+	plugin.Metrics = &plugin.Metric{}
+	retries := 0
+	stager := &testStager{execute: func(attempt int, job *protocol.Job) error {
+		if attempt < 2 {
+			return errors.New("failed")
+		}
+
+		return nil
+	}}
+
+	_, err := WithPolicy(stager, Policy{Max: 5, InitialBackoff: 1, MaxBackoff: 4}, func() { retries++ }).Execute(&protocol.Job{})
+	assert.Equal(t, nil, err, "failed to succeed after retries")
+	assert.Equal(t, []int{0, 1, 2}, stager.attempts, "failed to expose the attempt to the stage")
+	assert.Equal(t, 2, retries, "failed to call onRetry before every retry")
+	assert.Equal(t, uint64(2), plugin.Metrics.StageRetry, "failed to count retries")
+}
+
+func TestRetryOnStatus(t *testing.T) {
+	stager := &testStager{execute: func(attempt int, job *protocol.Job) error {
+		job.Result = &protocol.Result{Status: "busy"}
+		if attempt > 0 {
+			job.Result.Status = "done"
+		}
+
+		return nil
+	}}
+
+	job := &protocol.Job{}
+	_, err := WithPolicy(stager, Policy{Max: 3, Statuses: []string{"busy"}}, nil).Execute(job)
+	assert.Equal(t, nil, err, "failed to execute the stage")
+	assert.Equal(t, "done", job.Result.Status, "failed to retry the stage by the status of the result")
+	assert.Equal(t, 2, len(stager.attempts), "failed to stop retrying once the status changed")
+}
+
+func TestRetryWithPermanentError(t *testing.T) {
+	stager := &testStager{execute: func(attempt int, job *protocol.Job) error {
+		return Permanent(errors.New("bad request"))
+	}}
+
+	_, err := WithPolicy(stager, Policy{Max: 3}, nil).Execute(&protocol.Job{})
+	assert.NotEqual(t, nil, err, "failed to return the permanent error")
+	assert.Equal(t, 1, len(stager.attempts), "failed to skip retrying the permanent error")
+}
+
+func TestRetryUntilDeadline(t *testing.T) {
+	stager := &testStager{execute: func(attempt int, job *protocol.Job) error {
+		return errors.New("failed")
+	}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := WithPolicy(stager, Policy{Max: 3, InitialBackoff: 1000, MaxBackoff: 1000}, nil).ExecuteContext(ctx, &protocol.Job{})
+	assert.NotEqual(t, nil, err, "failed to return the last error")
+	assert.Equal(t, 1, len(stager.attempts), "failed to stop retrying once the context is done")
+}
+
+func TestRetryTimeout(t *testing.T) {
+	stager := &slowStager{}
+	job := &protocol.Job{}
+	_, err := WithPolicy(plug.WithTimeout(stager, 10*time.Millisecond), Policy{Max: 1, InitialBackoff: 1}, nil).Execute(job)
+	assert.Equal(t, nil, err, "failed to retry the stage timed out")
+	assert.Equal(t, "execution-2", job.Result.Status, "failed to take the result of the retry")
+
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, "execution-2", job.Result.Status, "failed to keep the execution timed out away from the job")
+}
@@ -0,0 +1,137 @@
+package worker
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/bigstack-oss/plane-go/pkg/base/config"
+	"github.com/bigstack-oss/plane-go/pkg/frame/sync/plugin"
+	"github.com/bigstack-oss/plane-go/pkg/frame/sync/plugin/cronjob"
+	"github.com/mohae/deepcopy"
+)
+
+func getSortedKeys[T any](items map[string]T) []string {
+	keys := make([]string, 0, len(items))
+	for key := range items {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+	return keys
+}
+
+// getChangedKeys returns the keys which are added, removed or changed
+func getChangedKeys[T any](running map[string]T, loaded map[string]T) map[string]bool {
+	changed := make(map[string]bool)
+	for key, value := range loaded {
+		runningValue, isExisted := running[key]
+		if !isExisted || !reflect.DeepEqual(runningValue, value) {
+			changed[key] = true
+		}
+	}
+
+	for key := range running {
+		if _, isExisted := loaded[key]; !isExisted {
+			changed[key] = true
+		}
+	}
+
+	return changed
+}
+
+// checkCronner sets a copy of the cronjob plugin with the config, so that
+// the running one is not touched when the config is invalid
+func checkCronner(cronName string, cronConfig confType) error {
+	cronner, isExisted := cronjob.Plugin[cronName]
+	if !isExisted {
+		return fmt.Errorf("cronjob plugin(%s) was not defined", cronName)
+	}
+
+	copied := deepcopy.Copy(cronner).(cronjob.Cronjob)
+	copied.SetConfig(cronConfig)
+	err := copied.CheckConfig()
+	if err != nil {
+		return fmt.Errorf("failed to set cronjob plugin(%s). error: %s", cronName, err.Error())
+	}
+
+	return nil
+}
+
+// reloadInteractor replaces the running interact plugin with a new one set
+// with the conf. the running one keeps serving if the conf is invalid
+func (r *Syncer) reloadInteractor(interactConf confType) error {
+	pluginName, _ := interactConf[name].(string)
+	stagers, err := newStagers(interactConf)
+	if err != nil {
+		return err
+	}
+
+	previous := r.stagers
+	r.setStagers(stagers)
+	interactor, err := newInteractor(pluginName, interactConf)
+	if err != nil {
+		r.setStagers(previous)
+		return err
+	}
+
+	r.StopInteractor()
+	r.setInteractor(plugin.Interact, pluginName, interactor, interactConf)
+	r.StartInteractor()
+	return nil
+}
+
+func (r *Syncer) reloadCronners(crons map[string]confType, changed map[string]bool) error {
+	errs := []error{}
+	for cronName := range changed {
+		if _, isRunning := r.crons[cronName]; isRunning {
+			r.stopCronner(cronName)
+		}
+
+		cronConfig, isExisted := crons[cronName]
+		if !isExisted {
+			continue
+		}
+
+		err := setCronner(plugin.CronJob, cronName, cronConfig)
+		if err != nil {
+			errs = append(errs, err)
+			delete(crons, cronName)
+			continue
+		}
+
+		r.startCronner(cronName)
+	}
+
+	r.crons = crons
+	r.CronJobs = getSortedKeys(crons)
+	return errors.Join(errs...)
+}
+
+// Reload applies the conf to the running plugins, the interact plugin is
+// replaced only if its conf changed, and only the changed cronjobs are
+// restarted. nothing is touched if the conf is invalid
+func (r *Syncer) Reload(cfg config.Configer) error {
+	crons := loadCronnerConfigs(cfg, plugin.CronJob)
+	changedCrons := getChangedKeys(r.crons, crons)
+	for cronName := range changedCrons {
+		if cronConfig, isExisted := crons[cronName]; isExisted {
+			err := checkCronner(cronName, cronConfig)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	interactConf, _ := cfg.Get(plugin.Interact).(confType)
+	if !reflect.DeepEqual(r.interactConf, interactConf) {
+		r.logf.Infof("reloading interact plugin(%s)", r.Interact)
+		err := r.reloadInteractor(interactConf)
+		if err != nil {
+			return err
+		}
+	}
+
+	return r.reloadCronners(crons, changedCrons)
+}
@@ -4,15 +4,20 @@ import (
 	"fmt"
 	"os"
 	"reflect"
+	"time"
 
 	"github.com/bigstack-oss/plane-go/pkg/base/config"
 	"github.com/bigstack-oss/plane-go/pkg/base/log"
+	"github.com/bigstack-oss/plane-go/pkg/frame/sync/metric"
 	"github.com/bigstack-oss/plane-go/pkg/frame/sync/plugin/cronjob"
 	"github.com/bigstack-oss/plane-go/pkg/frame/sync/plugin/interact"
 	"github.com/bigstack-oss/plane-go/pkg/frame/sync/plugin/interact/stage"
 	"github.com/bigstack-oss/plane-go/pkg/frame/sync/plugin/plug"
+	"github.com/bigstack-oss/plane-go/pkg/frame/sync/plugin/retry"
+	"github.com/goinggo/mapstructure"
 	"github.com/mohae/deepcopy"
 	"go.uber.org/zap"
+	"gopkg.in/go-playground/validator.v9"
 )
 
 const (
@@ -20,6 +25,7 @@ const (
 	stages     = "stages"
 	module     = "interactor"
 	interfaces = "interfaces"
+	policy     = "retry"
 )
 
 var (
@@ -31,10 +37,24 @@ type (
 	confType = map[string]interface{}
 )
 
+// stageOptions are the options of a stage handled by the framework, the
+// rest of the conf is up to the stage plugin
+type stageOptions struct {
+	Name     string
+	Timeout  int `validate:"min=0"`
+	Parallel []interface{}
+	Merge    string
+}
+
 type Syncer struct {
 	Interact string
 	CronJobs []string
 
+	interactor   interact.Interactor
+	interactConf confType
+	stagers      map[string]plug.Stager
+	crons        map[string]confType
+
 	log  *zap.Logger
 	logf *zap.SugaredLogger
 }
@@ -48,127 +68,282 @@ func InitWorker() Worker {
 	}
 }
 
-func (r *Syncer) SetStage(interfaceName string, stageIndex int, stageConfig interface{}) {
-	stageName := stageConfig.(confType)[name].(string)
-	stager := fmt.Sprintf("%s-%s-%d", interfaceName, stageName, stageIndex)
-	defer func() {
-		panic := recover()
-		if panic != nil {
-			r.logf.Errorf("stage plugin init failed: %s was not found", stageName)
-			osExit(1)
-		}
-	}()
+func getStagerName(interfaceName string, stageIndex int, stageConfig interface{}) string {
+	stageName, _ := stageConfig.(confType)[name].(string)
+	if _, isParallel := stageConfig.(confType)[plug.Parallel]; isParallel {
+		stageName = plug.Parallel
+	}
+
+	return fmt.Sprintf("%s-%s-%d", interfaceName, stageName, stageIndex)
+}
 
-	plug.Stagers[stager] = deepcopy.Copy(stage.Plugins[stageName]).(plug.Stager)
-	plug.Stagers[stager].SetConfig(stageConfig)
-	err := plug.Stagers[stager].CheckConfig()
+// getRetryPolicy decodes the retry policy of the stage, a number is taken as
+// the max attempts of retry
+func getRetryPolicy(stageConfig confType) (retry.Policy, error) {
+	retryPolicy := retry.Policy{}
+	switch rawPolicy := stageConfig[policy].(type) {
+	case nil:
+		return retryPolicy, nil
+	case int:
+		retryPolicy.Max = rawPolicy
+	default:
+		_ = mapstructure.Decode(rawPolicy, &retryPolicy)
+	}
+
+	err := validator.New().Struct(retryPolicy)
 	if err != nil {
-		r.logf.Errorf("error details of set stager(%s): %s", stager, err.Error())
-		osExit(1)
+		return retryPolicy, fmt.Errorf("invalid retry policy: %s", err.Error())
 	}
+
+	return retryPolicy, nil
 }
 
-func (r *Syncer) setInteractorConfig(interactor string, pluginConf interface{}) {
-	plug.InteractPluggers[interactor].SetConfig(pluginConf)
-	err := plug.InteractPluggers[interactor].CheckConfig()
-	if err != nil {
-		r.logf.Error(err)
-		osExit(1)
+func newStagePlugin(stageName string, stageConfig interface{}) (plug.Stager, error) {
+	template, isExisted := stage.Plugins[stageName]
+	if !isExisted {
+		return nil, fmt.Errorf("stage plugin init failed: %s was not found", stageName)
 	}
+
+	stager := deepcopy.Copy(template).(plug.Stager)
+	stager.SetConfig(stageConfig)
+	return stager, stager.CheckConfig()
 }
 
-func (r *Syncer) getInterfaceConfs(interactConf interface{}) []interface{} {
-	return interactConf.(confType)[interfaces].([]interface{})
+// newParallel creates the stages of a parallel block, they are merged by
+// all-must-succeed if the merge strategy is not given
+func newParallel(interfaceName string, stageIndex int, options stageOptions) (plug.Stager, error) {
+	if len(options.Parallel) == 0 {
+		return nil, fmt.Errorf("no stage was found in %s block", plug.Parallel)
+	}
+
+	names := []string{}
+	stagers := []plug.Stager{}
+	for _, stageConfig := range options.Parallel {
+		stager, err := newStage(interfaceName, stageIndex, stageConfig)
+		if err != nil {
+			return nil, err
+		}
+
+		stageName, _ := stageConfig.(confType)[name].(string)
+		names = append(names, stageName)
+		stagers = append(stagers, stager)
+	}
+
+	if options.Merge == "" {
+		options.Merge = plug.MergeAll
+	}
+
+	return plug.NewParallel(names, stagers, options.Merge)
 }
 
-func (r *Syncer) getInteractorConfig(pluginType string) (string, interface{}) {
-	rawConf := configer.Get(pluginType)
-	pluginName := rawConf.(confType)[name].(string)
-	r.Interact = pluginName
-	interactor := fmt.Sprintf("%s-%s", pluginType, pluginName)
+// newStage copies the registered stage plugin and sets the copy with
+// stageConfig, or groups the stages of a parallel block. every attempt of
+// the stage is limited by its timeout, the stage is retried under its retry
+// policy and measured per interface
+func newStage(interfaceName string, stageIndex int, stageConfig interface{}) (plug.Stager, error) {
+	options := stageOptions{}
+	_ = mapstructure.Decode(stageConfig, &options)
+	err := validator.New().Struct(options)
+	if err != nil {
+		return nil, err
+	}
+
+	var stager plug.Stager
+	switch options.Parallel {
+	case nil:
+		stager, err = newStagePlugin(options.Name, stageConfig)
+	default:
+		options.Name = plug.Parallel
+		stager, err = newParallel(interfaceName, stageIndex, options)
+	}
+	if err != nil {
+		return nil, err
+	}
 
-	return interactor, rawConf
+	policy, err := getRetryPolicy(stageConfig.(confType))
+	if err != nil {
+		return nil, err
+	}
+
+	timed := plug.WithTimeout(stager, time.Duration(options.Timeout)*time.Second)
+	retried := retry.WithPolicy(timed, policy, metric.CountRetry(interfaceName, stageIndex, options.Name))
+	return metric.WrapStager(interfaceName, stageIndex, options.Name, retried), nil
 }
 
-func (r *Syncer) SetInteractor(pluginType string) {
-	interactor, conf := r.getInteractorConfig(pluginType)
-	plug.InteractPluggers[interactor] = interact.Plugins[r.Interact]
+func (r *Syncer) SetStage(interfaceName string, stageIndex int, stageConfig interface{}) {
+	stager := getStagerName(interfaceName, stageIndex, stageConfig)
+	stagePlugin, err := newStage(interfaceName, stageIndex, stageConfig)
+	if err != nil {
+		r.logf.Errorf("error details of set stager(%s): %s", stager, err.Error())
+		osExit(1)
+		return
+	}
 
-	interfaceConfs := r.getInterfaceConfs(conf)
+	plug.Stagers[stager] = stagePlugin
+}
+
+// newStagers creates the stages of all the interfaces in the conf of the
+// interact plugin
+func newStagers(interactConf confType) (map[string]plug.Stager, error) {
+	stagers := make(map[string]plug.Stager)
+	interfaceConfs, _ := interactConf[interfaces].([]interface{})
 	for _, interfaceConf := range interfaceConfs {
-		interfaceName := interfaceConf.(confType)[name].(string)
+		interfaceName, _ := interfaceConf.(confType)[name].(string)
 		interfaceStages, hasStages := interfaceConf.(confType)[stages].([]interface{})
 		if !hasStages {
 			continue
 		}
 
 		for stageIndex, stageConf := range interfaceStages {
-			r.SetStage(interfaceName, stageIndex, stageConf)
+			stager := getStagerName(interfaceName, stageIndex, stageConf)
+			stagePlugin, err := newStage(interfaceName, stageIndex, stageConf)
+			if err != nil {
+				return nil, fmt.Errorf("error details of set stager(%s): %s", stager, err.Error())
+			}
+
+			stagers[stager] = stagePlugin
 		}
 	}
 
-	r.setInteractorConfig(interactor, conf)
+	return stagers, nil
+}
+
+// setStagers replaces the stages of the running interfaces, the interfaces
+// keep the stages they were set with until they are replaced as well
+func (r *Syncer) setStagers(stagers map[string]plug.Stager) {
+	for stager := range r.stagers {
+		delete(plug.Stagers, stager)
+	}
+
+	for stager, stagePlugin := range stagers {
+		plug.Stagers[stager] = stagePlugin
+	}
+
+	r.stagers = stagers
+}
+
+// newInteractor copies the registered interact plugin and sets the copy with
+// the conf, the stages have to be set before
+func newInteractor(pluginName string, interactConf confType) (interact.Interactor, error) {
+	template, isExisted := interact.Plugins[pluginName]
+	if !isExisted {
+		return nil, fmt.Errorf("interact plugin(%s) was not defined", pluginName)
+	}
+
+	interactor := deepcopy.Copy(template).(interact.Interactor)
+	interactor.SetConfig(interactConf)
+	return interactor, interactor.CheckConfig()
+}
+
+func getInteractorName(pluginType string, pluginName string) string {
+	return fmt.Sprintf("%s-%s", pluginType, pluginName)
+}
+
+func (r *Syncer) setInteractor(pluginType string, pluginName string, interactor interact.Interactor, interactConf confType) {
+	delete(plug.InteractPluggers, getInteractorName(pluginType, r.Interact))
+	plug.InteractPluggers[getInteractorName(pluginType, pluginName)] = interactor
+
+	r.Interact = pluginName
+	r.interactor = interactor
+	r.interactConf = interactConf
+}
+
+func (r *Syncer) SetInteractor(pluginType string) {
+	interactConf, _ := configer.Get(pluginType).(confType)
+	pluginName, _ := interactConf[name].(string)
+
+	stagers, err := newStagers(interactConf)
+	if err != nil {
+		r.logf.Error(err)
+		osExit(1)
+		return
+	}
+
+	r.setStagers(stagers)
+	interactor, err := newInteractor(pluginName, interactConf)
+	if err != nil {
+		r.logf.Error(err)
+		osExit(1)
+		return
+	}
+
+	r.setInteractor(pluginType, pluginName, interactor, interactConf)
 }
 
 func (r *Syncer) StartInteractor() {
 	r.logf.Infof("start interact plugin(%s)", r.Interact)
-	go interact.Plugins[r.Interact].DoInteract()
+	go r.interactor.DoInteract()
 }
 
 func (r *Syncer) StopInteractor() {
-	interact.Plugins[r.Interact].Stop()
+	r.interactor.Stop()
 	r.logf.Infof("stop interact plugin(%s)", r.Interact)
 }
 
-func (r *Syncer) getCronnerConfig(pluginType string) map[string]confType {
+func loadCronnerConfigs(cfg config.Configer, pluginType string) map[string]confType {
 	cronConfigs := make(map[string]confType)
-	rawConfig := reflect.ValueOf(configer.Get(pluginType))
+	rawConfig := reflect.ValueOf(cfg.Get(pluginType))
 	if !rawConfig.IsValid() {
-		return nil
+		return cronConfigs
 	}
 
 	for i := 0; i < rawConfig.Len(); i++ {
 		pluginConfig := rawConfig.Index(i).Interface().(confType)
-		pluginName := pluginConfig[name].(string)
-		cronName := fmt.Sprintf("%s-%s", pluginType, pluginName)
-
-		cronConfigs[pluginName] = make(confType)
+		pluginName, _ := pluginConfig[name].(string)
 		cronConfigs[pluginName] = pluginConfig
-		plug.Cronners[cronName] = cronjob.Plugin[pluginName]
 	}
 
 	return cronConfigs
 }
 
-func (r *Syncer) setCronnerConfig(pluginType string, cronConfigs map[string]confType) {
-	for cronName, cronConfig := range cronConfigs {
-		cronner := fmt.Sprintf("%s-%s", pluginType, cronName)
-		plug.Cronners[cronner].SetConfig(cronConfig)
-		err := plug.Cronners[cronner].CheckConfig()
+func setCronner(pluginType string, cronName string, cronConfig confType) error {
+	cronner, isExisted := cronjob.Plugin[cronName]
+	if !isExisted {
+		return fmt.Errorf("cronjob plugin(%s) was not defined", cronName)
+	}
+
+	cronner.SetConfig(cronConfig)
+	err := cronner.CheckConfig()
+	if err != nil {
+		return fmt.Errorf("failed to set cronjob plugin(%s). error: %s", cronName, err.Error())
+	}
+
+	plug.Cronners[fmt.Sprintf("%s-%s", pluginType, cronName)] = cronner
+	return nil
+}
+
+func (r *Syncer) SetCronner(pluginType string) {
+	r.crons = loadCronnerConfigs(configer, pluginType)
+	r.CronJobs = getSortedKeys(r.crons)
+
+	for _, cronName := range r.CronJobs {
+		err := setCronner(pluginType, cronName, r.crons[cronName])
 		if err != nil {
-			r.logf.Errorf("failed to set cronjob plugin(%s). error: %s", cronName, err.Error())
+			r.logf.Error(err)
 			osExit(1)
+			return
 		}
-
-		r.CronJobs = append(r.CronJobs, cronName)
 	}
 }
 
-func (r *Syncer) SetCronner(pluginType string) {
-	cronConfigs := r.getCronnerConfig(pluginType)
-	r.setCronnerConfig(pluginType, cronConfigs)
+func (r *Syncer) startCronner(cronName string) {
+	r.logf.Infof("start cronjob plugin(%s)", cronName)
+	go cronjob.Plugin[cronName].DoSchedule()
+}
+
+func (r *Syncer) stopCronner(cronName string) {
+	cronjob.Plugin[cronName].Stop()
+	r.logf.Infof("stop cronjob plugin(%s)", cronName)
 }
 
 func (r *Syncer) StartCronners() {
 	for _, cronName := range r.CronJobs {
-		r.logf.Infof("start cronjob plugin(%s)", cronName)
-		go cronjob.Plugin[cronName].DoSchedule()
+		r.startCronner(cronName)
 	}
 }
 
 func (r *Syncer) StopCronners() {
 	for _, cronName := range r.CronJobs {
-		cronjob.Plugin[cronName].Stop()
-		r.logf.Infof("stop cronjob plugin(%s)", cronName)
+		r.stopCronner(cronName)
 	}
 }
@@ -77,7 +77,16 @@ func (h *Helper) genHeadlessSockets(sockets []string) []string {
 }
 
 func (h *Helper) SetNatsJetStreamClient() {
-	var err error
+	err := h.ConnectJetStream()
+	if err != nil {
+		logf.Errorf("error details of set nats jetstream client: %s", err.Error())
+		os.Exit(1)
+	}
+}
+
+// ConnectJetStream sets the jetstream client like SetNatsJetStreamClient,
+// but returns the error to the caller instead of exiting
+func (h *Helper) ConnectJetStream() error {
 	scksStr := ""
 	if h.IsHeadlessSvc {
 		scksStr = strings.Join(h.genHeadlessSockets(h.Sockets), ",")
@@ -87,15 +96,11 @@ func (h *Helper) SetNatsJetStreamClient() {
 
 	cli, err := nats.Connect(scksStr)
 	if err != nil {
-		logf.Errorf("error details of set nats connection: %s", err.Error())
-		os.Exit(1)
+		return err
 	}
 
 	h.JsClient, err = cli.JetStream()
-	if err != nil {
-		logf.Errorf("error details of set nats jetstream client: %s", err.Error())
-		os.Exit(1)
-	}
+	return err
 }
 
 func (h *Helper) SetJetStreamSubscriber(subject string, durable string) {